@@ -0,0 +1,114 @@
+// Package achievement describes achievements as declarative rules over a
+// member's progress, so guilds can add their own next to the built-in ones
+package achievement
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Metrics a rule can test
+const (
+	MetricSolves      = "solves"       // distinct problems solved, optionally of one tier
+	MetricCleanSolves = "clean_solves" // problems solved without asking for a hint
+	MetricStreak      = "streak"       // current daily streak in days
+	MetricRating      = "rating"       // Glicko-2 rating
+)
+
+// Metrics lists every metric, in the order staff see them
+var Metrics = []string{MetricSolves, MetricCleanSolves, MetricStreak, MetricRating}
+
+// Rule unlocks an achievement once a metric reaches a threshold
+type Rule struct {
+	Key         string // unique in a guild, e.g. "hard-100"
+	Name        string
+	Description string
+	Metric      string
+	Threshold   int
+	Tier        string // only for solves: count this difficulty only
+}
+
+// Builtin are the achievements every guild has
+var Builtin = []Rule{
+	{Key: "first-solve", Name: "First stone", Description: "Solve your first problem", Metric: MetricSolves, Threshold: 1},
+	{Key: "unaided", Name: "Unaided", Description: "Solve 10 problems without a hint", Metric: MetricCleanSolves, Threshold: 10},
+	{Key: "streak-7", Name: "On a roll", Description: "Keep a 7-day daily streak", Metric: MetricStreak, Threshold: 7},
+	{Key: "streak-30", Name: "Thirty days", Description: "Keep a 30-day daily streak", Metric: MetricStreak, Threshold: 30},
+	{Key: "hard-100", Name: "Hard as stone", Description: "Solve 100 hard problems", Metric: MetricSolves, Threshold: 100, Tier: "hard"},
+	{Key: "rating-1800", Name: "Dan strength", Description: "Reach a rating of 1800", Metric: MetricRating, Threshold: 1800},
+}
+
+// Progress is what rules are tested against
+type Progress struct {
+	Solves      map[string]int // per tier; "" counts every problem
+	CleanSolves int
+	Streak      int
+	Rating      float64
+}
+
+var keyRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// Check reports what's wrong with a rule, if anything
+func (r Rule) Check() error {
+	switch {
+	case !keyRe.MatchString(r.Key):
+		return fmt.Errorf("key %q should be up to 32 lowercase letters, digits and dashes", r.Key)
+	case strings.TrimSpace(r.Name) == "":
+		return fmt.Errorf("the achievement needs a name")
+	case !slices.Contains(Metrics, r.Metric):
+		return fmt.Errorf("unknown metric %q (use %s)", r.Metric, strings.Join(Metrics, ", "))
+	case r.Threshold < 1:
+		return fmt.Errorf("the threshold must be at least 1")
+	case r.Tier != "" && r.Metric != MetricSolves:
+		return fmt.Errorf("only %s can be limited to a tier", MetricSolves)
+	}
+	return nil
+}
+
+// Met reports whether p satisfies the rule
+func (r Rule) Met(p Progress) bool {
+	var value float64
+	switch r.Metric {
+	case MetricSolves:
+		value = float64(p.Solves[r.Tier])
+	case MetricCleanSolves:
+		value = float64(p.CleanSolves)
+	case MetricStreak:
+		value = float64(p.Streak)
+	case MetricRating:
+		value = p.Rating
+	default:
+		return false
+	}
+	return value >= float64(r.Threshold)
+}
+
+// Describe restates a rule's condition, e.g. "100 hard solves"
+func (r Rule) Describe() string {
+	switch r.Metric {
+	case MetricSolves:
+		if r.Tier != "" {
+			return fmt.Sprintf("%d %s solves", r.Threshold, r.Tier)
+		}
+		return fmt.Sprintf("%d solves", r.Threshold)
+	case MetricCleanSolves:
+		return fmt.Sprintf("%d solves without a hint", r.Threshold)
+	case MetricStreak:
+		return fmt.Sprintf("a %d-day streak", r.Threshold)
+	default:
+		return fmt.Sprintf("a %s of %d", r.Metric, r.Threshold)
+	}
+}
+
+// Newly returns the rules p meets that aren't in have yet
+func Newly(rules []Rule, p Progress, have map[string]bool) []Rule {
+	var unlocked []Rule
+	for _, r := range rules {
+		if !have[r.Key] && r.Met(p) {
+			unlocked = append(unlocked, r)
+		}
+	}
+	return unlocked
+}
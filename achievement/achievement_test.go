@@ -0,0 +1,52 @@
+package achievement
+
+import "testing"
+
+func TestBuiltinRulesAreValid(t *testing.T) {
+	seen := map[string]bool{}
+	for _, r := range Builtin {
+		if err := r.Check(); err != nil {
+			t.Errorf("%s: %v", r.Key, err)
+		}
+		if seen[r.Key] {
+			t.Errorf("duplicate key %s", r.Key)
+		}
+		seen[r.Key] = true
+	}
+}
+
+func TestCheckRejectsBadRules(t *testing.T) {
+	good := Rule{Key: "ko-master", Name: "Ko master", Metric: MetricSolves, Threshold: 5, Tier: "medium"}
+	if err := good.Check(); err != nil {
+		t.Fatalf("expected a valid rule, got %v", err)
+	}
+	for _, bad := range []Rule{
+		{Key: "Has Spaces", Name: "x", Metric: MetricSolves, Threshold: 1},
+		{Key: "nameless", Metric: MetricSolves, Threshold: 1},
+		{Key: "karma", Name: "x", Metric: "karma", Threshold: 1},
+		{Key: "zero", Name: "x", Metric: MetricStreak},
+		{Key: "tiered-streak", Name: "x", Metric: MetricStreak, Threshold: 3, Tier: "hard"},
+	} {
+		if bad.Check() == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestNewly(t *testing.T) {
+	p := Progress{Solves: map[string]int{"": 120, "hard": 99}, CleanSolves: 10, Streak: 8, Rating: 1650}
+	got := Newly(Builtin, p, map[string]bool{"first-solve": true})
+	var keys []string
+	for _, r := range got {
+		keys = append(keys, r.Key)
+	}
+	want := []string{"unaided", "streak-7"}
+	if len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+
+	p.Solves["hard"] = 100
+	if got := Newly(Builtin, p, map[string]bool{"first-solve": true, "unaided": true, "streak-7": true}); len(got) != 1 || got[0].Key != "hard-100" {
+		t.Errorf("expected hard-100 at 100 hard solves, got %+v", got)
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/achievement"
+	"github.com/novnod/barista-bot/daily"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/rating"
+	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/scheduler"
+)
+
+// achievementsCommand describes /achievements, which lists a member's achievements
+func achievementsCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "achievements",
+		Description: "Show the server's achievements and which you've unlocked",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "member", Description: "Whose achievements to show"},
+		},
+	}
+}
+
+// achievementsAdminCommand describes /achievements_admin, which lets staff
+// add the guild's own achievements and attach roles to any of them
+func achievementsAdminCommand() *discordgo.ApplicationCommand {
+	metrics := make([]*discordgo.ApplicationCommandOptionChoice, len(achievement.Metrics))
+	for n, m := range achievement.Metrics {
+		metrics[n] = &discordgo.ApplicationCommandOptionChoice{Name: m, Value: m}
+	}
+	tiers := make([]*discordgo.ApplicationCommandOptionChoice, len(daily.Tiers))
+	for n, t := range daily.Tiers {
+		tiers[n] = &discordgo.ApplicationCommandOptionChoice{Name: t, Value: t}
+	}
+	minOne := 1.0
+	keyOption := &discordgo.ApplicationCommandOption{
+		Type: discordgo.ApplicationCommandOptionString, Name: "key", Description: "Achievement key, e.g. hard-100", Required: true,
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        "achievements_admin",
+		Description: "Add achievements and role rewards",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Add an achievement, or change one added before",
+				Options: []*discordgo.ApplicationCommandOption{
+					keyOption,
+					{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "Shown when it's unlocked", Required: true, MaxLength: 60},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "metric", Description: "What it counts", Required: true, Choices: metrics},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "threshold", Description: "Unlocks when the metric reaches this", Required: true, MinValue: &minOne},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "tier", Description: "Only count solves of this difficulty", Choices: tiers},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "description", Description: "e.g. \"Solve 50 medium problems\"", MaxLength: 200},
+					{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Description: "Role to grant when it's unlocked"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove an achievement this server added; members keep it",
+				Options:     []*discordgo.ApplicationCommandOption{keyOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "role",
+				Description: "Grant a role with any achievement, built-in ones too",
+				Options: []*discordgo.ApplicationCommandOption{
+					keyOption,
+					{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Description: "Role to grant (none to stop granting one)"},
+				},
+			},
+		},
+	}
+}
+
+// handleAchievements lists the guild's achievements, marking the member's
+func handleAchievements(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" {
+		respondEphemeral(s, i, "Achievements are kept per server; use /achievements in one.")
+		return
+	}
+	userID := interactionUserID(i)
+	if opts := i.ApplicationCommandData().Options; len(opts) > 0 {
+		userID = opts[0].UserValue(nil).ID
+	}
+	rules, err := achievementRepo.Rules(i.GuildID)
+	if err != nil {
+		respondError(s, i, "could not load achievements: "+err.Error())
+		return
+	}
+	unlocked, err := achievementRepo.Unlocked(i.GuildID, userID)
+	if err != nil {
+		respondError(s, i, "could not load achievements: "+err.Error())
+		return
+	}
+	roles, err := achievementRepo.Roles(i.GuildID)
+	if err != nil {
+		respondError(s, i, "could not load achievements: "+err.Error())
+		return
+	}
+
+	lines := []string{fmt.Sprintf("<@%s> has %d of %d achievements.", userID, len(unlocked), len(rules))}
+	length := len(lines[0])
+	for n, r := range rules {
+		line := "▫️ "
+		if at, ok := unlocked[r.Key]; ok {
+			line = fmt.Sprintf("🏅 <t:%d:d> ", at.Unix())
+		}
+		line += fmt.Sprintf("**%s**: %s", r.Name, ruleText(r))
+		if role := roles[r.Key]; role != "" {
+			line += fmt.Sprintf(" · <@&%s>", role)
+		}
+		line += fmt.Sprintf(" `%s`", r.Key)
+		// stay inside Discord's message limit
+		if length += len(line) + 1; length > 1900 {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(rules)-n))
+			break
+		}
+		lines = append(lines, line)
+	}
+	respondEphemeral(s, i, strings.Join(lines, "\n"))
+}
+
+// handleAchievementsAdmin runs an /achievements_admin subcommand for staff
+func handleAchievementsAdmin(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !requireStaff(s, i) {
+		return
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+	key := strings.ToLower(strings.TrimSpace(opts["key"].StringValue()))
+
+	switch sub.Name {
+	case "add":
+		if builtinRule(key) {
+			respondEphemeral(s, i, fmt.Sprintf("%q is a built-in achievement; pick another key.", key))
+			return
+		}
+		rule := achievement.Rule{
+			Key:       key,
+			Name:      strings.TrimSpace(opts["name"].StringValue()),
+			Metric:    opts["metric"].StringValue(),
+			Threshold: int(opts["threshold"].IntValue()),
+		}
+		if o, ok := opts["tier"]; ok {
+			rule.Tier = o.StringValue()
+		}
+		if o, ok := opts["description"]; ok {
+			rule.Description = strings.TrimSpace(o.StringValue())
+		}
+		if err := rule.Check(); err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("Couldn't save: %v.", err))
+			return
+		}
+		if err := achievementRepo.SaveRule(i.GuildID, rule); err != nil {
+			respondError(s, i, "could not save achievement: "+err.Error())
+			return
+		}
+		msg := fmt.Sprintf("Saved **%s**: %s. Members who already qualify get it with their next graded answer.", rule.Name, ruleText(rule))
+		if o, ok := opts["role"]; ok {
+			role := o.RoleValue(s, i.GuildID)
+			if err := achievementRepo.SetRole(i.GuildID, key, role.ID); err != nil {
+				respondError(s, i, "could not set achievement role: "+err.Error())
+				return
+			}
+			msg += fmt.Sprintf(" It grants <@&%s>.", role.ID)
+		}
+		respondEphemeral(s, i, msg)
+
+	case "remove":
+		if builtinRule(key) {
+			respondEphemeral(s, i, fmt.Sprintf("%q is built in and can't be removed.", key))
+			return
+		}
+		err := achievementRepo.RemoveRule(i.GuildID, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondEphemeral(s, i, fmt.Sprintf("There's no achievement %q.", key))
+			return
+		}
+		if err != nil {
+			respondError(s, i, "could not remove achievement: "+err.Error())
+			return
+		}
+		respondEphemeral(s, i, fmt.Sprintf("Removed %q. Members who unlocked it keep it, and any role they got.", key))
+
+	case "role":
+		rules, err := achievementRepo.Rules(i.GuildID)
+		if err != nil {
+			respondError(s, i, "could not load achievements: "+err.Error())
+			return
+		}
+		if findRule(rules, key) == nil {
+			respondEphemeral(s, i, fmt.Sprintf("There's no achievement %q.", key))
+			return
+		}
+		roleID := ""
+		if o, ok := opts["role"]; ok {
+			roleID = o.RoleValue(s, i.GuildID).ID
+		}
+		if err := achievementRepo.SetRole(i.GuildID, key, roleID); err != nil {
+			respondError(s, i, "could not set achievement role: "+err.Error())
+			return
+		}
+		if roleID == "" {
+			respondEphemeral(s, i, fmt.Sprintf("%q no longer grants a role.", key))
+		} else {
+			respondEphemeral(s, i, fmt.Sprintf("%q now grants <@&%s>. The bot's role must be above it to hand it out.", key, roleID))
+		}
+	}
+}
+
+// checkAchievements tests the guild's rules after a graded attempt, then
+// records, announces and rewards whatever the member newly unlocked
+func checkAchievements(s *discordgo.Session, pg *parser.GoParser, a repo.Attempt) {
+	if a.GuildID == "" || a.Result == repo.ResultUngraded {
+		return
+	}
+	rules, err := achievementRepo.Rules(a.GuildID)
+	if err != nil {
+		log.Printf("failed to check achievements: %v", err)
+		return
+	}
+	unlocked, err := achievementRepo.Unlocked(a.GuildID, a.UserID)
+	if err != nil {
+		log.Printf("failed to check achievements: %v", err)
+		return
+	}
+	have := map[string]bool{}
+	for key := range unlocked {
+		have[key] = true
+	}
+	// members keep achievements whose rules were removed, so only current
+	// rules tell whether there's anything left to unlock
+	locked := false
+	for _, r := range rules {
+		if !have[r.Key] {
+			locked = true
+			break
+		}
+	}
+	if !locked {
+		return
+	}
+	p, err := achievementProgress(a.GuildID, a.UserID, pg)
+	if err != nil {
+		log.Printf("failed to check achievements: %v", err)
+		return
+	}
+	newly := achievement.Newly(rules, *p, have)
+	if len(newly) == 0 {
+		return
+	}
+
+	roles, err := achievementRepo.Roles(a.GuildID)
+	if err != nil {
+		log.Printf("failed to load achievement roles: %v", err)
+	}
+	for _, r := range newly {
+		if ok, err := achievementRepo.Unlock(a.GuildID, a.UserID, r.Key, a.CreatedAt); err != nil || !ok {
+			if err != nil {
+				log.Printf("failed to unlock %s: %v", r.Key, err)
+			}
+			continue
+		}
+		msg := fmt.Sprintf("🏅 <@%s> unlocked **%s**: %s", a.UserID, r.Name, ruleText(r))
+		if role := roles[r.Key]; role != "" {
+			if err := s.GuildMemberRoleAdd(a.GuildID, a.UserID, role); err != nil {
+				log.Printf("failed to grant role %s for %s: %v", role, r.Key, err)
+			} else {
+				msg += fmt.Sprintf(", and the <@&%s> role", role)
+			}
+		}
+		_, err := s.ChannelMessageSendComplex(a.ThreadID, &discordgo.MessageSend{
+			Content:         msg + "!",
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{a.UserID}},
+		})
+		if err != nil {
+			log.Printf("failed to announce %s: %v", r.Key, err)
+		}
+	}
+}
+
+// achievementProgress gathers what achievement rules are tested against
+func achievementProgress(guildID, userID string, pg *parser.GoParser) (*achievement.Progress, error) {
+	p := &achievement.Progress{Solves: map[string]int{}}
+	tallies, err := attemptRepo.Tallies(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tallies {
+		if t.Correct == 0 {
+			continue
+		}
+		p.Solves[""]++
+		if prob := pg.Problem(t.ProblemID); prob != nil && prob.Difficulty != "" {
+			p.Solves[prob.Difficulty]++
+		}
+	}
+	if p.CleanSolves, err = attemptRepo.CleanSolves(guildID, userID); err != nil {
+		return nil, err
+	}
+	st, err := streakRepo.GetStreak(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	p.Streak = daily.CurrentStreak(*st, scheduler.DayNumber(time.Now().In(guildLocation(guildID))))
+	user, err := loadRating(guildID, repo.SubjectUser, userID, rating.Default)
+	if err != nil {
+		return nil, err
+	}
+	p.Rating = user.Glicko.Rating
+	return p, nil
+}
+
+// ruleText is a rule's description, or its condition if it has none
+func ruleText(r achievement.Rule) string {
+	if r.Description != "" {
+		return r.Description
+	}
+	return "reach " + r.Describe()
+}
+
+func builtinRule(key string) bool {
+	return findRule(achievement.Builtin, key) != nil
+}
+
+func findRule(rules []achievement.Rule, key string) *achievement.Rule {
+	for n := range rules {
+		if rules[n].Key == key {
+			return &rules[n]
+		}
+	}
+	return nil
+}
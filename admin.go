@@ -0,0 +1,454 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/daily"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+)
+
+// dailyAdminCommand describes /daily_admin, the staff controls for the daily progression
+func dailyAdminCommand(pg *parser.GoParser) *discordgo.ApplicationCommand {
+	minZero := 0.0
+	return &discordgo.ApplicationCommand{
+		Name:        "daily_admin",
+		Description: "Staff controls for the daily problem order",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "status",
+				Description: "Show where the guild is in its problem order",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reseed",
+				Description: "Shuffle a new problem order, starting a new cycle",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "seed", Description: "Seed to use (random if omitted)"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "jump",
+				Description: "Make a specific problem the next daily",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "problem", Description: "Problem ID, e.g. cho-easy-12", Required: true},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "collections",
+				Description: "Choose which collections dailies come from",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "ids",
+						Description: fmt.Sprintf("Comma-separated, or \"all\" (loaded: %s)", strings.Join(pg.Collections(), ", ")),
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "rotation",
+				Description: "Set which difficulty is posted on each day",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "schedule",
+						Description: "e.g. \"mon-thu:easy, fri-sat:medium, sun:hard\", \"easy, medium\", \"default\" or \"off\"",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reveal",
+				Description: "Set when the solution is posted in daily threads",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "hours",
+						Description: "Hours after posting (0 turns reveals off)",
+						Required:    true,
+						MinValue:    &minZero,
+						MaxValue:    24 * 7,
+					},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "lock", Description: "Lock the thread after the reveal"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "template",
+				Description: "Customize how dailies are posted; with no options, preview the current layout",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "title", Description: "Embed title, e.g. \"Daily #{number} · {date}\"", MaxLength: 256},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "description", Description: "Embed text; \\n starts a new line", MaxLength: 1000},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "footer", Description: "Footer text, \"none\" for no footer", MaxLength: 256},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "color", Description: "Hex color, e.g. #C8A165"},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "reset", Description: "Go back to the default layout"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "threads",
+				Description: "Choose whether /daily opens a thread per member or one shared thread a day",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "mode",
+						Description: "Thread mode",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "One thread per member", Value: repo.ThreadsPersonal},
+							{Name: "One shared thread a day", Value: repo.ThreadsShared},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "streaks",
+				Description: "Set how many streak days earn a freeze that covers one missed day",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "freeze_every",
+						Description: fmt.Sprintf("Days of streak per freeze (0 turns freezes off, default %d)", repo.DefaultFreezeEvery),
+						Required:    true,
+						MinValue:    &minZero,
+						MaxValue:    365,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "ratings",
+				Description: "Recompute every rating by replaying the server's answer history",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "jobs",
+				Description: "Show recent scheduled posts, reveals and reminders",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "catchup",
+				Description: "Choose what happens to jobs missed while the bot was offline",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "policy",
+						Description: "Run missed jobs late, or skip them",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Run late", Value: repo.CatchUpRun},
+							{Name: "Skip", Value: repo.CatchUpSkip},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// handleDailyAdmin runs a /daily_admin subcommand for staff
+func handleDailyAdmin(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	if !requireStaff(s, i) {
+		return
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+
+	switch sub.Name {
+	case "status":
+		prog, err := progressRepo.GetProgress(i.GuildID)
+		if err != nil {
+			respondError(s, i, "could not load progress: "+err.Error())
+			return
+		}
+		last, err := progressRepo.LastCycles(i.GuildID)
+		if err != nil {
+			respondError(s, i, "could not load progress: "+err.Error())
+			return
+		}
+		rotation, err := daily.GuildRotation(prog)
+		if err != nil {
+			respondError(s, i, "could not load rotation: "+err.Error())
+			return
+		}
+		collections := "all"
+		if len(prog.Collections) > 0 {
+			collections = strings.Join(prog.Collections, ", ")
+		}
+		pool := selector.Pool(prog.Collections)
+		msg := fmt.Sprintf("Seed: %d\nCollections: %s\nRotation: %s", prog.Seed, collections, rotation)
+		for _, tier := range daily.Tiers {
+			tiered := daily.ByTier(pool, tier)
+			if len(tiered) == 0 {
+				continue
+			}
+			cycle, posted := daily.Cycle(tiered, last, prog.Cycle)
+			msg += fmt.Sprintf("\n%s: cycle %d, %d of %d posted", tier, cycle, posted, len(tiered))
+		}
+		if reveal, err := revealRepo.GetRevealConfig(i.GuildID); err == nil {
+			if reveal.DelayHours > 0 {
+				msg += fmt.Sprintf("\nReveal: after %d hours", reveal.DelayHours)
+				if reveal.LockThread {
+					msg += ", then lock"
+				}
+			} else {
+				msg += "\nReveal: off"
+			}
+		}
+		if mode, err := postRepo.ThreadMode(i.GuildID); err == nil {
+			msg += "\nThreads: " + mode
+		}
+		if every, err := streakRepo.FreezeEvery(i.GuildID); err == nil {
+			msg += "\nStreak freezes: " + freezeRule(every)
+		}
+		if prog.NextProblemID != "" {
+			msg += "\nNext daily: " + prog.NextProblemID
+		}
+		respondEphemeral(s, i, msg)
+
+	case "reseed":
+		seed := rand.Int64()
+		if o, ok := opts["seed"]; ok {
+			seed = o.IntValue()
+		}
+		if err := progressRepo.Reseed(i.GuildID, seed); err != nil {
+			respondError(s, i, "could not reseed: "+err.Error())
+			return
+		}
+		respondEphemeral(s, i, fmt.Sprintf("Reseeded with %d. Every tier starts a new cycle with the next daily.", seed))
+
+	case "jump":
+		id := strings.TrimSpace(opts["problem"].StringValue())
+		if pg.Problem(id) == nil {
+			respondEphemeral(s, i, fmt.Sprintf("No problem with ID %q.", id))
+			return
+		}
+		if err := progressRepo.SetNextProblem(i.GuildID, id); err != nil {
+			respondError(s, i, "could not set next problem: "+err.Error())
+			return
+		}
+		respondEphemeral(s, i, fmt.Sprintf("The next daily will be %s.", id))
+
+	case "collections":
+		ids, err := parseCollections(pg, opts["ids"].StringValue())
+		if err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("Couldn't save: %v.", err))
+			return
+		}
+		if err := progressRepo.SetCollections(i.GuildID, ids); err != nil {
+			respondError(s, i, "could not set collections: "+err.Error())
+			return
+		}
+		respondEphemeral(s, i, "Daily collections updated.")
+
+	case "rotation":
+		value := strings.TrimSpace(opts["schedule"].StringValue())
+		if strings.EqualFold(value, "default") {
+			value = ""
+		}
+		rotation, err := daily.ParseRotation(value)
+		if err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("Invalid rotation: %v.", err))
+			return
+		}
+		if value != "" {
+			value = rotation.String()
+		}
+		if err := progressRepo.SetRotation(i.GuildID, value); err != nil {
+			respondError(s, i, "could not set rotation: "+err.Error())
+			return
+		}
+		if value == "" {
+			rotation, _ = daily.ParseRotation(daily.DefaultRotation)
+		}
+		respondEphemeral(s, i, fmt.Sprintf("Daily rotation set to: %s.", rotation))
+
+	case "reveal":
+		cfg := repo.RevealConfig{GuildID: i.GuildID, DelayHours: int(opts["hours"].IntValue())}
+		if o, ok := opts["lock"]; ok {
+			cfg.LockThread = o.BoolValue()
+		}
+		if err := revealRepo.SetRevealConfig(cfg); err != nil {
+			respondError(s, i, "could not set reveal: "+err.Error())
+			return
+		}
+		msg := "Solutions won't be revealed automatically."
+		if cfg.DelayHours > 0 {
+			msg = fmt.Sprintf("Solutions will be revealed %d hours after each daily is posted", cfg.DelayHours)
+			if cfg.LockThread {
+				msg += ", then the thread is locked"
+			}
+			msg += ". This applies to dailies posted from now on."
+		}
+		respondEphemeral(s, i, msg)
+
+	case "template":
+		if o, ok := opts["reset"]; ok && o.BoolValue() {
+			if err := templateRepo.ResetTemplate(i.GuildID); err != nil {
+				respondError(s, i, "could not reset template: "+err.Error())
+				return
+			}
+		}
+		tmpl, err := templateRepo.GetTemplate(i.GuildID)
+		if err != nil {
+			respondError(s, i, "could not load template: "+err.Error())
+			return
+		}
+		changed := false
+		for _, field := range []struct {
+			name string
+			dst  *string
+		}{{"title", &tmpl.Title}, {"description", &tmpl.Description}, {"footer", &tmpl.Footer}} {
+			o, ok := opts[field.name]
+			if !ok {
+				continue
+			}
+			value := strings.ReplaceAll(o.StringValue(), `\n`, "\n")
+			if field.name == "footer" && strings.EqualFold(value, "none") {
+				value = ""
+			}
+			if err := daily.CheckTemplate(value); err != nil {
+				respondEphemeral(s, i, fmt.Sprintf("Couldn't save the %s: %v.", field.name, err))
+				return
+			}
+			*field.dst, changed = value, true
+		}
+		if o, ok := opts["color"]; ok {
+			color, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(o.StringValue()), "#"), 16, 24)
+			if err != nil {
+				respondEphemeral(s, i, fmt.Sprintf("%q isn't a hex color like #C8A165.", o.StringValue()))
+				return
+			}
+			tmpl.Color, changed = int(color), true
+		}
+		if changed {
+			if err := templateRepo.SetTemplate(*tmpl); err != nil {
+				respondError(s, i, "could not save template: "+err.Error())
+				return
+			}
+		}
+
+		resp := &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("Dailies are posted like this. Placeholders: {%s}", strings.Join(daily.Placeholders, "}, {")),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		}
+		if len(pg.Problems) > 0 {
+			day := time.Now().In(guildLocation(i.GuildID))
+			resp.Embeds = []*discordgo.MessageEmbed{dailyEmbed(tmpl, pg.Problems[0], day, repo.DefaultSchedule, "")}
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: resp,
+		})
+
+	case "threads":
+		mode := opts["mode"].StringValue()
+		if err := postRepo.SetThreadMode(i.GuildID, mode); err != nil {
+			respondError(s, i, "could not set thread mode: "+err.Error())
+			return
+		}
+		if mode == repo.ThreadsShared {
+			respondEphemeral(s, i, "/daily now sends everyone to the day's shared thread, starting it if needed.")
+		} else {
+			respondEphemeral(s, i, "/daily now opens a thread for each member.")
+		}
+
+	case "streaks":
+		every := int(opts["freeze_every"].IntValue())
+		if err := streakRepo.SetFreezeEvery(i.GuildID, every); err != nil {
+			respondError(s, i, "could not set streak freezes: "+err.Error())
+			return
+		}
+		respondEphemeral(s, i, "Streak freezes: "+freezeRule(every)+".")
+
+	case "ratings":
+		// a long history takes a while to replay, so acknowledge first
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+		})
+		if err != nil {
+			respondError(s, i, "could not start recompute")
+			return
+		}
+		users, problems, err := recomputeRatings(i.GuildID, pg)
+		if err != nil {
+			followupError(s, i, "could not recompute ratings: "+err.Error())
+			return
+		}
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: fmt.Sprintf("Recomputed ratings for %d members and %d problems.", users, problems),
+		})
+
+	case "jobs":
+		jobs, err := jobRepo.Recent(i.GuildID, 10)
+		if err != nil {
+			respondError(s, i, "could not list jobs: "+err.Error())
+			return
+		}
+		if len(jobs) == 0 {
+			respondEphemeral(s, i, "No jobs scheduled yet.")
+			return
+		}
+		policy, _ := jobRepo.CatchUpPolicy(i.GuildID)
+		msg := fmt.Sprintf("Missed jobs: %s\n", map[string]string{repo.CatchUpRun: "run late", repo.CatchUpSkip: "skipped"}[policy])
+		loc := guildLocation(i.GuildID)
+		for _, j := range jobs {
+			msg += "\n" + formatJob(j, loc)
+		}
+		respondEphemeral(s, i, msg)
+
+	case "catchup":
+		policy := opts["policy"].StringValue()
+		if err := jobRepo.SetCatchUpPolicy(i.GuildID, policy); err != nil {
+			respondError(s, i, "could not set catch-up policy: "+err.Error())
+			return
+		}
+		if policy == repo.CatchUpSkip {
+			respondEphemeral(s, i, "Jobs missed while the bot was offline will be skipped.")
+		} else {
+			respondEphemeral(s, i, "Jobs missed while the bot was offline will run late.")
+		}
+	}
+}
+
+// formatJob describes a job on one line, e.g.
+// "✓ post 2025-03-01 · due Mar 1 08:00 · done"
+func formatJob(j repo.Job, loc *time.Location) string {
+	icon := map[string]string{repo.JobPending: "⏳", repo.JobRunning: "▶", repo.JobDone: "✓", repo.JobFailed: "✗", repo.JobSkipped: "⏭"}[j.Status]
+	target := j.Key
+	if j.Kind != repo.JobPost {
+		target = "<#" + j.Key + ">"
+	}
+	line := fmt.Sprintf("%s %s %s · due %s · %s", icon, j.Kind, target, j.DueAt.In(loc).Format("Jan 2 15:04"), j.Status)
+	if j.Attempts > 0 {
+		line += fmt.Sprintf(" after %d attempts", j.Attempts)
+		if j.Status == repo.JobPending {
+			line += fmt.Sprintf(", next try %s", j.RunAt.In(loc).Format("15:04"))
+		}
+	}
+	if j.LastError != "" {
+		// keep ten lines well inside Discord's message limit
+		errText := []rune(j.LastError)
+		if len(errText) > 100 {
+			errText = append(errText[:99], '…')
+		}
+		line += fmt.Sprintf(" (%s)", string(errText))
+	}
+	return line
+}
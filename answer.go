@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+)
+
+// parseAnswer reads a message such as "C17" or "C17 B18" as the solver's
+// moves. ok is false when the message is ordinary chat.
+func parseAnswer(content string) (moves []string, ok bool) {
+	fields := strings.FieldsFunc(content, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\n'
+	})
+	if len(fields) == 0 {
+		return nil, false
+	}
+	for _, f := range fields {
+		// only "C17" style points, so words like "ok" aren't read as SGF coords
+		if !strings.ContainsAny(f, "0123456789") {
+			return nil, false
+		}
+		coord, err := parser.ParseCoord(f)
+		if err != nil {
+			return nil, false
+		}
+		moves = append(moves, coord)
+	}
+	return moves, true
+}
+
+// handleAnswer grades a move posted in a daily thread, or in a DM the guild's
+// daily was delivered to, and replies with the board showing the attempt, a
+// verdict mark and the solution's reply
+func handleAnswer(s *discordgo.Session, m *discordgo.MessageCreate, pg *parser.GoParser, guildID string, prob *parser.GoProblem) {
+	moves, ok := parseAnswer(m.Content)
+	if !ok {
+		return
+	}
+	verdict, msg, imgPath, err := gradeAnswer(prob, moves)
+	if err == nil {
+		if st := recordDailyAttempt(s, pg, guildID, m.Author.ID, m.ChannelID, prob, moves, verdict); st != nil {
+			msg += "\n" + streakLine(st)
+		}
+	}
+	replyTo(s, m, msg, imgPath)
+}
+
+// gradeAnswer grades the solver's moves, returning the verdict, a message
+// describing it and the path of a board image showing the moves, or "" if
+// there isn't one. Moves that can't be played aren't graded: err says why
+// and msg tells the solver.
+func gradeAnswer(prob *parser.GoProblem, moves []string) (verdict parser.Verdict, msg, imgPath string, err error) {
+	verdict, line := prob.Grade(moves)
+	if err := prob.CheckMoves(line); err != nil {
+		return verdict, fmt.Sprintf("Can't play that: %v.", err), "", err
+	}
+
+	imgPath, err = parser.RenderOverlay(prob, parser.AnswerOverlay(line, verdict), imageDir, 600, 30)
+	if err != nil {
+		log.Printf("failed to render answer for %s: %v", prob.ID, err)
+		imgPath = ""
+	}
+	return verdict, verdictMessage(verdict, line), imgPath, nil
+}
+
+// recordDailyAttempt stores an answer given in a daily thread or DM, timed
+// from when the daily was posted there. Answers after the reveal are stored
+// ungraded, since the solution is out, so they don't count for ratings,
+// leaderboards or achievements. A correct answer to the day's daily before
+// its reveal counts towards the solver's streak, which is returned;
+// otherwise it returns nil.
+func recordDailyAttempt(s *discordgo.Session, pg *parser.GoParser, guildID, userID, threadID string, prob *parser.GoProblem, moves []string, verdict parser.Verdict) *repo.Streak {
+	now := time.Now()
+	attempt := repo.Attempt{
+		GuildID:   guildID,
+		UserID:    userID,
+		ProblemID: prob.ID,
+		Source:    repo.SourceDaily,
+		Moves:     coordNames(moves),
+		Result:    attemptResult(verdict),
+		ThreadID:  threadID,
+		CreatedAt: now,
+	}
+	streak := false
+	if post := answeredPost(threadID, prob.ID); post != nil {
+		attempt.Took = now.Sub(post.PostedAt)
+		if !post.RevealedAt.IsZero() {
+			attempt.Result = repo.ResultUngraded
+		}
+		streak = post.CountsForStreak(now.In(scheduleLocation(guildID, post.Schedule)).Format(time.DateOnly))
+	}
+	return recordAttempt(s, pg, attempt, prob, streak)
+}
+
+// answeredPost returns the daily an answer in channelID is for: the post of
+// a daily thread or, in a DM, the daily delivered there, which is revealed
+// along with its schedule's shared thread. It returns nil for neither.
+func answeredPost(channelID, problemID string) *repo.DailyPost {
+	if post, err := postRepo.Post(channelID); err == nil {
+		return post
+	}
+	dm, err := subscriptionRepo.LatestDM(channelID, problemID)
+	if err != nil {
+		return nil
+	}
+	post := &repo.DailyPost{
+		ThreadID:  dm.ChannelID,
+		MessageID: dm.MessageID,
+		GuildID:   dm.GuildID,
+		Schedule:  dm.Schedule,
+		Day:       dm.Day,
+		ProblemID: dm.ProblemID,
+		PostedAt:  dm.PostedAt,
+	}
+	if shared, err := postRepo.SharedPost(dm.GuildID, dm.Schedule, dm.Day); err == nil {
+		post.RevealedAt = shared.RevealedAt
+	}
+	return post
+}
+
+// recordAttempt stores a graded answer and lets it move the user's and the
+// problem's ratings, the user's review queue and, if it's correct and
+// streak is set, their streak, which is returned when it moved.
+// Achievements it unlocks are announced where it was answered.
+func recordAttempt(s *discordgo.Session, pg *parser.GoParser, a repo.Attempt, prob *parser.GoProblem, streak bool) *repo.Streak {
+	id, err := attemptRepo.Record(a)
+	if err != nil {
+		log.Printf("failed to record attempt: %v", err)
+		return nil
+	}
+	a.ID = id
+	rateAttempt(a, prob)
+	scheduleReview(a)
+	var st *repo.Streak
+	if streak && a.Result == repo.ResultCorrect {
+		st = advanceStreak(a.GuildID, a.UserID, a.CreatedAt)
+	}
+	checkAchievements(s, pg, a)
+	return st
+}
+
+// attemptResult maps a verdict to the result stored with an attempt
+func attemptResult(verdict parser.Verdict) string {
+	switch verdict {
+	case parser.VerdictCorrect:
+		return repo.ResultCorrect
+	case parser.VerdictWrong:
+		return repo.ResultWrong
+	default:
+		return repo.ResultUngraded
+	}
+}
+
+// coordNames converts SGF coordinates to the "C17" form players type
+func coordNames(moves []string) []string {
+	names := make([]string, len(moves))
+	for i, m := range moves {
+		names[i] = parser.CoordName(m)
+	}
+	return names
+}
+
+// verdictMessage describes a graded line, e.g. "✓ Correct! White answers at B18."
+func verdictMessage(verdict parser.Verdict, line []parser.Move) string {
+	var msg string
+	switch verdict {
+	case parser.VerdictCorrect:
+		msg = "✓ Correct!"
+	case parser.VerdictWrong:
+		msg = "✗ Not quite."
+	default:
+		return "There's no solution on file for this problem, so this move can't be graded."
+	}
+	if n := len(line); n > 1 && line[n-1].Color != line[0].Color {
+		reply := line[n-1]
+		color := "Black"
+		if reply.Color == "W" {
+			color = "White"
+		}
+		msg += fmt.Sprintf(" %s answers at %s.", color, parser.CoordName(reply.Coord))
+	}
+	return msg
+}
+
+// replyTo answers a message, attaching the image at imgPath if there is one
+func replyTo(s *discordgo.Session, m *discordgo.MessageCreate, content, imgPath string) {
+	send := &discordgo.MessageSend{
+		Content:   content,
+		Reference: m.Reference(),
+	}
+	if imgPath != "" {
+		file, err := os.Open(imgPath)
+		if err != nil {
+			log.Printf("failed to open image: %v", err)
+		} else {
+			defer file.Close()
+			send.Files = []*discordgo.File{{
+				Name:        filepath.Base(imgPath),
+				ContentType: "image/png",
+				Reader:      file,
+			}}
+		}
+	}
+	if _, err := s.ChannelMessageSendComplex(m.ChannelID, send); err != nil {
+		log.Printf("failed to reply to answer: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+// Package card draws members' stats cards with the same gg code the boards
+// are drawn with
+package card
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fogleman/gg"
+	"github.com/novnod/barista-bot/parser"
+)
+
+// Card dimensions and heatmap layout
+const (
+	width       = 800
+	height      = 440
+	pad         = 30
+	heatWeeks   = 26
+	heatCell    = 14
+	heatGap     = 3
+	heatColumns = heatWeeks + 1 // the current, partial week too
+)
+
+var (
+	paper  = color.RGBA{R: 250, G: 243, B: 230, A: 255}
+	ink    = color.RGBA{R: 40, G: 32, B: 24, A: 255}
+	faint  = color.RGBA{R: 140, G: 125, B: 105, A: 255}
+	accent = color.RGBA{R: 200, G: 161, B: 101, A: 255} // the daily embeds' color
+	empty  = color.RGBA{R: 232, G: 222, B: 204, A: 255}
+)
+
+// TierAccuracy counts a member's graded answers on one difficulty tier
+type TierAccuracy struct {
+	Tier     string
+	Correct  int
+	Attempts int
+}
+
+// Stats is everything a card shows
+type Stats struct {
+	GuildID     string
+	UserID      string
+	Name        string
+	Rating      float64
+	Deviation   float64
+	Provisional bool
+	History     []float64 // ratings after each answer, oldest first
+	Streak      int
+	BestStreak  int
+	Freezes     int
+	Tiers       []TierAccuracy
+	Activity    map[string]int // answers per YYYY-MM-DD in the guild's zone
+	Today       time.Time      // the heatmap's last day, in the guild's zone
+}
+
+// HeatmapStart returns the first day the heatmap of a card drawn on today
+// shows: the Monday heatWeeks weeks before this week's
+func HeatmapStart(today time.Time) time.Time {
+	y, m, d := today.Date()
+	monday := d - (int(today.Weekday())+6)%7
+	return time.Date(y, m, monday-7*heatWeeks, 0, 0, 0, 0, today.Location())
+}
+
+// RenderStats draws a stats card and saves it as a PNG named after the
+// member and the card's contents, so an unchanged card is only drawn once.
+// Saving a new card removes the member's older ones from the same guild.
+func RenderStats(st Stats, outputDir string) (string, error) {
+	h := fnv.New32a()
+	fmt.Fprint(h, st.Name, st.Rating, st.Deviation, st.Provisional, st.History, st.Streak, st.BestStreak,
+		st.Freezes, st.Tiers, st.Today.Format(time.DateOnly))
+	for day := HeatmapStart(st.Today); !day.After(st.Today); day = day.AddDate(0, 0, 1) {
+		fmt.Fprint(h, st.Activity[day.Format(time.DateOnly)], ",")
+	}
+	prefix := "stats-" + st.GuildID + "-" + st.UserID + "-"
+	name := fmt.Sprintf("%s%08x", prefix, h.Sum32())
+	if path := parser.PNGPath(outputDir, name); fileExists(path) {
+		return path, nil
+	}
+
+	dc := gg.NewContext(width, height)
+	dc.SetColor(paper)
+	dc.Clear()
+	drawHeader(dc, st)
+	drawSparkline(dc, st.History, pad, 95, width-2*pad, 100)
+	drawStreak(dc, st, pad, 240)
+	drawTiers(dc, st.Tiers, pad, 300)
+	drawHeatmap(dc, st, width-pad-heatColumns*(heatCell+heatGap)+heatGap, 240)
+	path, err := parser.SavePNG(dc, outputDir, name)
+	if err != nil {
+		return "", err
+	}
+	pruneCards(outputDir, prefix, path)
+	return path, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// pruneCards removes the cards whose names start with prefix, except keep
+func pruneCards(outputDir, prefix, keep string) {
+	pattern := strings.TrimSuffix(parser.PNGPath(outputDir, prefix), ".png") + "*.png"
+	old, err := filepath.Glob(pattern)
+	if err != nil {
+		return
+	}
+	for _, path := range old {
+		if path != keep {
+			os.Remove(path)
+		}
+	}
+}
+
+// setFont switches to the caption font, keeping gg's built-in face if it
+// isn't installed
+func setFont(dc *gg.Context, bold bool, points float64) {
+	if face := parser.LoadFont(bold, points); face != nil {
+		dc.SetFontFace(face)
+	}
+}
+
+func drawHeader(dc *gg.Context, st Stats) {
+	dc.SetColor(ink)
+	setFont(dc, true, 30)
+	dc.DrawStringAnchored(st.Name, pad, 50, 0, 0.5)
+
+	rating := fmt.Sprintf("%.0f", st.Rating)
+	dc.DrawStringAnchored(rating, width-pad, 42, 1, 0.5)
+	setFont(dc, false, 14)
+	dc.SetColor(faint)
+	sub := fmt.Sprintf("rating ±%.0f", 2*st.Deviation)
+	if st.Provisional {
+		sub += " · provisional"
+	}
+	dc.DrawStringAnchored(sub, width-pad, 72, 1, 0.5)
+}
+
+// drawSparkline plots the rating history in the given box
+func drawSparkline(dc *gg.Context, history []float64, x, y, w, h float64) {
+	dc.SetColor(empty)
+	dc.DrawRoundedRectangle(x, y, w, h, 8)
+	dc.Fill()
+	if len(history) < 2 {
+		dc.SetColor(faint)
+		setFont(dc, false, 14)
+		dc.DrawStringAnchored("The rating chart starts after a couple of graded answers", x+w/2, y+h/2, 0.5, 0.5)
+		return
+	}
+
+	lo, hi := history[0], history[0]
+	for _, r := range history {
+		lo, hi = math.Min(lo, r), math.Max(hi, r)
+	}
+	if hi-lo < 50 {
+		// keep small wobbles from filling the whole chart
+		mid := (hi + lo) / 2
+		lo, hi = mid-25, mid+25
+	}
+	const inset = 12
+	point := func(i int) (float64, float64) {
+		px := x + inset + (w-2*inset)*float64(i)/float64(len(history)-1)
+		py := y + h - inset - (h-2*inset)*(history[i]-lo)/(hi-lo)
+		return px, py
+	}
+	for i := range history {
+		dc.LineTo(point(i))
+	}
+	dc.SetColor(accent)
+	dc.SetLineWidth(3)
+	dc.Stroke()
+	lx, ly := point(len(history) - 1)
+	dc.DrawCircle(lx, ly, 5)
+	dc.Fill()
+
+	dc.SetColor(faint)
+	setFont(dc, false, 12)
+	dc.DrawStringAnchored(fmt.Sprintf("%.0f", hi), x+6, y+10, 0, 0.5)
+	dc.DrawStringAnchored(fmt.Sprintf("%.0f", lo), x+6, y+h-10, 0, 0.5)
+}
+
+func drawStreak(dc *gg.Context, st Stats, x, y float64) {
+	dc.SetColor(ink)
+	setFont(dc, true, 20)
+	dc.DrawStringAnchored(fmt.Sprintf("%d day streak", st.Streak), x, y, 0, 0.5)
+	dc.SetColor(faint)
+	setFont(dc, false, 14)
+	line := fmt.Sprintf("best %d", st.BestStreak)
+	if st.Freezes > 0 {
+		line += fmt.Sprintf(" · %d freeze", st.Freezes)
+		if st.Freezes > 1 {
+			line += "s"
+		}
+	}
+	dc.DrawStringAnchored(line, x, y+26, 0, 0.5)
+}
+
+// drawTiers draws a bar of accuracy for each difficulty tier
+func drawTiers(dc *gg.Context, tiers []TierAccuracy, x, y float64) {
+	const barW, barH, rowH = 180, 14, 44
+	for n, t := range tiers {
+		ty := y + float64(n)*rowH
+		dc.SetColor(ink)
+		setFont(dc, false, 14)
+		label := t.Tier
+		if label != "" {
+			label = strings.ToUpper(label[:1]) + label[1:]
+		}
+		dc.DrawStringAnchored(label, x, ty, 0, 0.5)
+
+		dc.SetColor(empty)
+		dc.DrawRoundedRectangle(x, ty+10, barW, barH, 4)
+		dc.Fill()
+		value := "no answers"
+		if t.Attempts > 0 {
+			share := float64(t.Correct) / float64(t.Attempts)
+			if share > 0 {
+				dc.SetColor(accent)
+				dc.DrawRoundedRectangle(x, ty+10, barW*share, barH, 4)
+				dc.Fill()
+			}
+			value = fmt.Sprintf("%.0f%% of %d", share*100, t.Attempts)
+		}
+		dc.SetColor(faint)
+		setFont(dc, false, 12)
+		dc.DrawStringAnchored(value, x+barW+10, ty+10+barH/2, 0, 0.5)
+	}
+}
+
+// drawHeatmap draws a calendar of daily activity, a column per week from
+// Monday at the top, shaded by how many answers were given
+func drawHeatmap(dc *gg.Context, st Stats, x, y float64) {
+	dc.SetColor(ink)
+	setFont(dc, false, 14)
+	dc.DrawStringAnchored("Activity", x, y, 0, 0.5)
+	top := y + 16
+
+	day := HeatmapStart(st.Today)
+	for col := 0; col < heatColumns; col++ {
+		for row := 0; row < 7 && !day.After(st.Today); row++ {
+			dc.SetColor(heatColor(st.Activity[day.Format(time.DateOnly)]))
+			dc.DrawRoundedRectangle(x+float64(col*(heatCell+heatGap)), top+float64(row*(heatCell+heatGap)), heatCell, heatCell, 3)
+			dc.Fill()
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+}
+
+// heatColor shades a day by its number of answers
+func heatColor(n int) color.Color {
+	switch {
+	case n == 0:
+		return empty
+	case n < 3:
+		return color.RGBA{R: 228, G: 203, B: 160, A: 255}
+	case n < 6:
+		return accent
+	default:
+		return color.RGBA{R: 150, G: 108, B: 50, A: 255}
+	}
+}
@@ -0,0 +1,90 @@
+package card
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func sampleStats() Stats {
+	today := time.Date(2025, 6, 4, 0, 0, 0, 0, time.UTC) // a Wednesday
+	return Stats{
+		GuildID: "1", UserID: "42", Name: "shusaku", Rating: 1623, Deviation: 80,
+		History: []float64{1500, 1540, 1525, 1600, 1623},
+		Streak:  5, BestStreak: 12, Freezes: 1,
+		Tiers: []TierAccuracy{
+			{Tier: "easy", Correct: 9, Attempts: 10},
+			{Tier: "medium", Correct: 3, Attempts: 6},
+			{Tier: "hard"},
+		},
+		Activity: map[string]int{"2025-06-04": 2, "2025-06-02": 7, "2025-01-01": 1},
+		Today:    today,
+	}
+}
+
+func TestRenderStatsCachesByContents(t *testing.T) {
+	dir := t.TempDir()
+	st := sampleStats()
+	path, err := RenderStats(st, dir)
+	if err != nil {
+		t.Fatalf("RenderStats returned error: %v", err)
+	}
+	png, err := os.ReadFile(path)
+	if err != nil || len(png) < 8 || string(png[1:4]) != "PNG" {
+		t.Fatalf("expected a PNG at %s, got %v", path, err)
+	}
+
+	// an unchanged card is served from the file already drawn
+	os.WriteFile(path, []byte("cached"), 0o644)
+	again, err := RenderStats(st, dir)
+	if err != nil || again != path {
+		t.Fatalf("expected the same path, got %s, %v", again, err)
+	}
+	if b, _ := os.ReadFile(again); string(b) != "cached" {
+		t.Errorf("expected the cached card not to be redrawn")
+	}
+
+	st = sampleStats()
+	st.Activity["2024-01-01"] = 5 // before the heatmap; not on the card
+	if same, _ := RenderStats(st, dir); same != path {
+		t.Errorf("expected activity off the heatmap not to change the card")
+	}
+
+	st.Activity = map[string]int{"2025-06-04": 3}
+	changed, _ := RenderStats(st, dir)
+	if changed == path {
+		t.Errorf("expected new activity to draw a new card")
+	}
+	// the member's old card is replaced, so cards don't pile up
+	if fileExists(path) {
+		t.Errorf("expected the old card %s to be removed", path)
+	}
+	other := sampleStats()
+	other.UserID = "43"
+	if _, err := RenderStats(other, dir); err != nil || !fileExists(changed) {
+		t.Errorf("expected another member's card to leave this one alone, got %v", err)
+	}
+	elsewhere := sampleStats()
+	elsewhere.GuildID = "2"
+	if _, err := RenderStats(elsewhere, dir); err != nil || !fileExists(changed) {
+		t.Errorf("expected the member's card in another guild to leave this one alone, got %v", err)
+	}
+}
+
+func TestRenderStatsWithoutHistory(t *testing.T) {
+	if _, err := RenderStats(Stats{UserID: "7", Name: "newcomer", Rating: 1500, Deviation: 350, Provisional: true, Today: time.Now()}, t.TempDir()); err != nil {
+		t.Errorf("RenderStats returned error for a new member: %v", err)
+	}
+}
+
+func TestHeatmapStartsOnMonday(t *testing.T) {
+	for _, today := range []time.Time{
+		time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), // Monday
+		time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC), // Sunday
+	} {
+		start := HeatmapStart(today)
+		if start.Weekday() != time.Monday || start.Format(time.DateOnly) != "2024-12-02" {
+			t.Errorf("HeatmapStart(%s) = %s, want Monday 2024-12-02", today.Format(time.DateOnly), start.Format(time.DateOnly))
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package daily
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/scheduler"
+)
+
+// DefaultRotation is used by guilds that haven't set their own
+const DefaultRotation = "mon-thu:easy, fri-sat:medium, sun:hard"
+
+// Tiers are the difficulty levels a rotation can ask for
+var Tiers = []string{"easy", "medium", "hard"}
+
+// Rotation maps each day to a difficulty tier, either by weekday
+// ("mon-thu:easy, fri-sat:medium, sun:hard") or as a repeating pattern
+// ("easy, easy, medium, hard"). Days without a tier draw from every tier.
+type Rotation struct {
+	weekdays map[time.Weekday]string
+	pattern  []string
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseRotation reads a rotation in either form; "off" disables it
+func ParseRotation(s string) (Rotation, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "off" {
+		return Rotation{}, nil
+	}
+
+	items := strings.Split(s, ",")
+	var r Rotation
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		days, tier, isWeekday := strings.Cut(item, ":")
+		if !isWeekday {
+			tier = item
+		}
+		tier = strings.TrimSpace(tier)
+		if tier != "any" && !slices.Contains(Tiers, tier) {
+			return Rotation{}, fmt.Errorf("unknown tier %q (use %s or any)", tier, strings.Join(Tiers, ", "))
+		}
+		if tier == "any" {
+			tier = ""
+		}
+
+		if !isWeekday {
+			if r.weekdays != nil {
+				return Rotation{}, fmt.Errorf("mix of weekday and pattern entries")
+			}
+			r.pattern = append(r.pattern, tier)
+			continue
+		}
+		if r.pattern != nil {
+			return Rotation{}, fmt.Errorf("mix of weekday and pattern entries")
+		}
+		if r.weekdays == nil {
+			r.weekdays = map[time.Weekday]string{}
+		}
+		wds, err := parseWeekdays(strings.TrimSpace(days))
+		if err != nil {
+			return Rotation{}, err
+		}
+		for _, wd := range wds {
+			r.weekdays[wd] = tier
+		}
+	}
+	return r, nil
+}
+
+// parseWeekdays reads "fri" or a range such as "mon-thu" (ranges may wrap past Sunday)
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	start, ok := weekdayNames[abbrev(from)]
+	if !ok {
+		return nil, fmt.Errorf("unknown weekday %q", from)
+	}
+	if !isRange {
+		return []time.Weekday{start}, nil
+	}
+	end, ok := weekdayNames[abbrev(to)]
+	if !ok {
+		return nil, fmt.Errorf("unknown weekday %q", to)
+	}
+	var days []time.Weekday
+	for d := start; ; d = (d + 1) % 7 {
+		days = append(days, d)
+		if d == end {
+			return days, nil
+		}
+	}
+}
+
+// GuildRotation returns the guild's rotation, or DefaultRotation if staff
+// haven't set one
+func GuildRotation(prog *repo.Progress) (Rotation, error) {
+	if prog.Rotation == "" {
+		return ParseRotation(DefaultRotation)
+	}
+	return ParseRotation(prog.Rotation)
+}
+
+// abbrev shortens "monday" to "mon"
+func abbrev(day string) string {
+	day = strings.TrimSpace(day)
+	if len(day) > 3 {
+		return day[:3]
+	}
+	return day
+}
+
+// TierFor returns the tier for the day t falls on in its own location,
+// or "" when any tier will do
+func (r Rotation) TierFor(t time.Time) string {
+	if len(r.pattern) > 0 {
+		return r.pattern[scheduler.DayNumber(t)%int64(len(r.pattern))]
+	}
+	return r.weekdays[t.Weekday()]
+}
+
+// String writes the rotation back in normalized form, grouping
+// consecutive weekdays that share a tier into ranges from Monday
+func (r Rotation) String() string {
+	if len(r.pattern) > 0 {
+		parts := make([]string, len(r.pattern))
+		for i, tier := range r.pattern {
+			parts[i] = orAny(tier)
+		}
+		return strings.Join(parts, ", ")
+	}
+	if len(r.weekdays) == 0 {
+		return "off"
+	}
+
+	names := []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	week := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+	var parts []string
+	for i := 0; i < len(week); {
+		tier, set := r.weekdays[week[i]]
+		j := i
+		for j+1 < len(week) {
+			next, nextSet := r.weekdays[week[j+1]]
+			if next != tier || nextSet != set {
+				break
+			}
+			j++
+		}
+		if set {
+			span := names[week[i]]
+			if j > i {
+				span += "-" + names[week[j]]
+			}
+			parts = append(parts, span+":"+orAny(tier))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+func orAny(tier string) string {
+	if tier == "" {
+		return "any"
+	}
+	return tier
+}
@@ -0,0 +1,75 @@
+package daily
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRotationWeekdays(t *testing.T) {
+	r, err := ParseRotation("Monday-Thu:easy, fri-sat:MEDIUM, sun:hard")
+	if err != nil {
+		t.Fatalf("ParseRotation returned error: %v", err)
+	}
+	tests := map[time.Weekday]string{
+		time.Monday: "easy", time.Thursday: "easy", time.Friday: "medium",
+		time.Saturday: "medium", time.Sunday: "hard",
+	}
+	for wd, want := range tests {
+		// 2025-01-05 is a Sunday
+		d := time.Date(2025, 1, 5+int(wd), 12, 0, 0, 0, time.UTC)
+		if got := r.TierFor(d); got != want {
+			t.Errorf("%s: expected %s, got %s", wd, want, got)
+		}
+	}
+	if got := r.String(); got != DefaultRotation {
+		t.Errorf("expected %q, got %q", DefaultRotation, got)
+	}
+}
+
+func TestParseRotationWrapsAndLeavesGaps(t *testing.T) {
+	r, err := ParseRotation("sat-mon:hard")
+	if err != nil {
+		t.Fatalf("ParseRotation returned error: %v", err)
+	}
+	if got := r.String(); got != "mon:hard, sat-sun:hard" {
+		t.Errorf("unexpected normalized form %q", got)
+	}
+	if got := r.TierFor(time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)); got != "" {
+		t.Errorf("expected any tier on Wednesday, got %q", got)
+	}
+}
+
+func TestParseRotationPattern(t *testing.T) {
+	r, err := ParseRotation("easy, easy, hard")
+	if err != nil {
+		t.Fatalf("ParseRotation returned error: %v", err)
+	}
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	var got []string
+	for d := range 6 {
+		got = append(got, r.TierFor(start.AddDate(0, 0, d)))
+	}
+	hard := 0
+	for i, tier := range got {
+		if tier == "hard" {
+			hard++
+			if i+3 < len(got) && got[i+3] != "hard" {
+				t.Errorf("expected the pattern to repeat every 3 days, got %v", got)
+			}
+		}
+	}
+	if hard != 2 {
+		t.Errorf("expected hard twice in 6 days, got %v", got)
+	}
+}
+
+func TestParseRotationErrors(t *testing.T) {
+	for _, s := range []string{"mon:expert", "funday:easy", "mon:easy, hard", "easy, tue:hard"} {
+		if _, err := ParseRotation(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+	if r, err := ParseRotation("off"); err != nil || r.TierFor(time.Now()) != "" {
+		t.Errorf("expected off to allow any tier")
+	}
+}
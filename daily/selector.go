@@ -0,0 +1,205 @@
+package daily
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"time"
+
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+)
+
+// Selector picks each guild's daily problem. Every guild walks its own
+// seeded shuffle of the enabled collections without repeats, drawing from
+// the tier its rotation sets for the day; once every problem in a pool has
+// been seen that pool starts a new cycle in a fresh order. Staff exceptions
+// can pause or skip days, or put a chosen problem on a date.
+type Selector struct {
+	problems   *parser.GoParser
+	progress   *repo.ProgressRepository
+	exceptions *repo.ExceptionRepository
+}
+
+// NoDailyError reports a day that has no daily because of a pause or skip
+type NoDailyError struct {
+	Exception repo.Exception
+}
+
+func (e *NoDailyError) Error() string {
+	reason := "dailies are paused"
+	if e.Exception.Kind == repo.ExceptionSkip {
+		reason = "the daily is skipped"
+	}
+	if e.Exception.Note != "" {
+		reason += ": " + e.Exception.Note
+	}
+	return reason
+}
+
+// NewSelector returns a Selector over the loaded problems
+func NewSelector(problems *parser.GoParser, progress *repo.ProgressRepository, exceptions *repo.ExceptionRepository) *Selector {
+	return &Selector{problems: problems, progress: progress, exceptions: exceptions}
+}
+
+// ForDay returns the problem a guild's schedule gets for the calendar day t
+// falls on in its own location, picking and recording the next problem the
+// first time a day is asked for. Each schedule gets its own pick; the
+// schedule's collections, if any, replace the guild's. Paused and skipped
+// days return a *NoDailyError; these win over an override on the same day.
+func (s *Selector) ForDay(guildID, schedule string, collections []string, t time.Time) (*parser.GoProblem, error) {
+	day := t.Format(time.DateOnly)
+	exceptions, err := s.exceptions.ForDay(guildID, schedule, day)
+	if err != nil {
+		return nil, err
+	}
+	var override *parser.GoProblem
+	for _, e := range exceptions {
+		switch e.Kind {
+		case repo.ExceptionPause, repo.ExceptionSkip:
+			return nil, &NoDailyError{Exception: e}
+		case repo.ExceptionOverride:
+			if prob := s.problems.Problem(e.ProblemID); prob != nil {
+				override = prob
+			}
+		}
+	}
+
+	id, err := s.progress.PickForDay(guildID, schedule, day)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if prob := s.problems.Problem(id); err == nil && prob != nil && (override == nil || override == prob) {
+		return prob, nil
+	}
+
+	prog, err := s.progress.GetProgress(guildID)
+	if err != nil {
+		return nil, err
+	}
+	rotation, err := GuildRotation(prog)
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) == 0 {
+		collections = prog.Collections
+	}
+	pool := s.Pool(collections)
+	if tiered := ByTier(pool, rotation.TierFor(t)); len(tiered) > 0 {
+		pool = tiered
+	}
+	if len(pool) == 0 {
+		return nil, fmt.Errorf("no problems available")
+	}
+
+	last, err := s.progress.LastCycles(guildID)
+	if err != nil {
+		return nil, err
+	}
+	var cycle int
+	prob := override
+	if prob == nil {
+		prob = s.problems.Problem(prog.NextProblemID)
+	}
+	if prob != nil {
+		cycle = max(last[prob.ID]+1, prog.Cycle)
+	} else {
+		prob, cycle = Next(pool, last, prog.Cycle, prog.Seed)
+	}
+
+	pick := repo.DailyPick{Schedule: schedule, Cycle: cycle, ProblemID: prob.ID, Day: day}
+	if err := s.progress.RecordPick(guildID, pick); err != nil {
+		return nil, err
+	}
+	return prob, nil
+}
+
+// Pool returns the problems in the given collections, or all of them
+func (s *Selector) Pool(collections []string) []*parser.GoProblem {
+	if len(collections) == 0 {
+		return s.problems.Problems
+	}
+	var pool []*parser.GoProblem
+	for _, p := range s.problems.Problems {
+		if slices.Contains(collections, p.CollectionID) {
+			pool = append(pool, p)
+		}
+	}
+	return pool
+}
+
+// ByTier returns the problems of the given difficulty, or all of them when tier is ""
+func ByTier(pool []*parser.GoProblem, tier string) []*parser.GoProblem {
+	if tier == "" {
+		return pool
+	}
+	var out []*parser.GoProblem
+	for _, p := range pool {
+		if p.Difficulty == tier {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Cycle works out which cycle a pool is in and how many of its problems
+// have been posted in it. last maps problem IDs to the latest cycle they
+// were picked in; picks before base (the cycle set by the last reseed)
+// don't count. Cycles are per pool, so a tier that runs out early starts
+// over without bringing back problems from the others.
+func Cycle(pool []*parser.GoProblem, last map[string]int, base int) (cycle, posted int) {
+	cycle = -1
+	for _, p := range pool {
+		if c := max(last[p.ID], base-1); cycle < 0 || c < cycle {
+			cycle = c
+		}
+	}
+	cycle++
+	for _, p := range pool {
+		if last[p.ID] >= cycle {
+			posted++
+		}
+	}
+	return max(cycle, base), posted
+}
+
+// Next returns the pool's current cycle and the problem not yet posted in
+// it that comes first in the cycle's order. Each problem's place depends
+// only on the seed, cycle and its own ID, so problems added mid-cycle slot
+// in without reshuffling the rest. Next returns nil for an empty pool.
+func Next(pool []*parser.GoProblem, last map[string]int, base int, seed int64) (*parser.GoProblem, int) {
+	cycle, _ := Cycle(pool, last, base)
+	var best *parser.GoProblem
+	var bestRank uint64
+	for _, p := range pool {
+		if last[p.ID] >= cycle {
+			continue
+		}
+		if r := rank(seed, cycle, p.ID); best == nil || r < bestRank {
+			best, bestRank = p, r
+		}
+	}
+	return best, cycle
+}
+
+// rank hashes a problem's position in a seeded cycle
+func rank(seed int64, cycle int, id string) uint64 {
+	h := fnv.New64a()
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], uint64(seed))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(cycle))
+	h.Write(buf[:])
+	h.Write([]byte(id))
+
+	// FNV alone barely stirs the high bits for IDs that differ only at the
+	// end, so finish with splitmix64's mixer
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
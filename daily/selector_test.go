@@ -0,0 +1,252 @@
+package daily
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+)
+
+func testProblems(collection string, n int) []*parser.GoProblem {
+	var problems []*parser.GoProblem
+	for i := 1; i <= n; i++ {
+		problems = append(problems, &parser.GoProblem{
+			ID:           fmt.Sprintf("%s-%d", collection, i),
+			CollectionID: collection,
+			Number:       i,
+		})
+	}
+	return problems
+}
+
+func newTestSelector(t *testing.T, problems []*parser.GoProblem) (*Selector, *repo.ProgressRepository, string) {
+	s, progress, _, path := newTestSelectorWithExceptions(t, problems)
+	return s, progress, path
+}
+
+func newTestSelectorWithExceptions(t *testing.T, problems []*parser.GoProblem) (*Selector, *repo.ProgressRepository, *repo.ExceptionRepository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := repo.InitDBConnection(path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	progress := repo.InitProgressRepository(db)
+	exceptions := repo.InitExceptionRepository(db)
+	return NewSelector(&parser.GoParser{Problems: problems}, progress, exceptions), progress, exceptions, path
+}
+
+func day(n int) time.Time {
+	return time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func TestForDayNoRepeatsWithinCycle(t *testing.T) {
+	s, _, _ := newTestSelector(t, testProblems("easy", 10))
+
+	seen := map[string]bool{}
+	for d := range 10 {
+		prob, err := s.ForDay("g1", "", nil, day(d))
+		if err != nil {
+			t.Fatalf("ForDay returned error: %v", err)
+		}
+		if seen[prob.ID] {
+			t.Fatalf("problem %s repeated on day %d", prob.ID, d)
+		}
+		seen[prob.ID] = true
+
+		again, _ := s.ForDay("g1", "", nil, day(d))
+		if again.ID != prob.ID {
+			t.Errorf("expected the same problem when asked twice on day %d", d)
+		}
+	}
+
+	// the eleventh day starts a new cycle
+	if _, err := s.ForDay("g1", "", nil, day(10)); err != nil {
+		t.Fatalf("expected a new cycle to start, got %v", err)
+	}
+}
+
+func TestForDayGuildsGetTheirOwnOrder(t *testing.T) {
+	s, progress, _ := newTestSelector(t, testProblems("easy", 50))
+	if err := progress.Reseed("g1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := progress.Reseed("g2", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	same := 0
+	for d := range 5 {
+		a, _ := s.ForDay("g1", "", nil, day(d))
+		b, _ := s.ForDay("g2", "", nil, day(d))
+		if a.ID == b.ID {
+			same++
+		}
+	}
+	if same == 5 {
+		t.Error("expected guilds with different seeds to get different problems")
+	}
+}
+
+func TestForDaySurvivesRestartAndAdditions(t *testing.T) {
+	problems := testProblems("easy", 20)
+	s, _, path := newTestSelector(t, problems)
+	first, _ := s.ForDay("g1", "", nil, day(0))
+
+	// reopen the database with a collection added, as after a restart
+	db, err := repo.InitDBConnection(path)
+	if err != nil {
+		t.Fatalf("failed to reopen db: %v", err)
+	}
+	defer db.Close()
+	grown := append(problems, testProblems("hard", 5)...)
+	restarted := NewSelector(&parser.GoParser{Problems: grown}, repo.InitProgressRepository(db), repo.InitExceptionRepository(db))
+
+	again, _ := restarted.ForDay("g1", "", nil, day(0))
+	if again.ID != first.ID {
+		t.Errorf("expected day 0 to stay %s after restart, got %s", first.ID, again.ID)
+	}
+	seen := map[string]bool{first.ID: true}
+	for d := 1; d < 25; d++ {
+		prob, err := restarted.ForDay("g1", "", nil, day(d))
+		if err != nil {
+			t.Fatalf("ForDay returned error: %v", err)
+		}
+		if seen[prob.ID] {
+			t.Fatalf("problem %s repeated within the cycle", prob.ID)
+		}
+		seen[prob.ID] = true
+	}
+}
+
+func TestForDayHonoursJumpAndCollections(t *testing.T) {
+	problems := append(testProblems("easy", 5), testProblems("hard", 5)...)
+	s, progress, _ := newTestSelector(t, problems)
+
+	if err := progress.SetNextProblem("g1", "hard-3"); err != nil {
+		t.Fatal(err)
+	}
+	prob, _ := s.ForDay("g1", "", nil, day(0))
+	if prob.ID != "hard-3" {
+		t.Errorf("expected jump to hard-3, got %s", prob.ID)
+	}
+
+	if err := progress.SetCollections("g1", []string{"easy"}); err != nil {
+		t.Fatal(err)
+	}
+	for d := 1; d <= 5; d++ {
+		prob, _ := s.ForDay("g1", "", nil, day(d))
+		if prob.CollectionID != "easy" {
+			t.Errorf("expected only easy problems, got %s", prob.ID)
+		}
+	}
+}
+
+func TestForDayFollowsRotation(t *testing.T) {
+	var problems []*parser.GoProblem
+	for _, tier := range Tiers {
+		for _, p := range testProblems("cho-"+tier, 5) {
+			p.Difficulty = tier
+			problems = append(problems, p)
+		}
+	}
+	s, progress, _ := newTestSelector(t, problems)
+	if err := progress.SetRotation("g1", "mon-thu:easy, fri-sat:medium, sun:hard"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2025-01-01 is a Wednesday; easy runs out on the second Monday and
+	// starts over without repeating medium or hard early
+	counts := map[string]int{}
+	for d := range 14 {
+		prob, err := s.ForDay("g1", "", nil, day(d))
+		if err != nil {
+			t.Fatalf("ForDay returned error: %v", err)
+		}
+		want := map[time.Weekday]string{time.Friday: "medium", time.Saturday: "medium", time.Sunday: "hard"}[day(d).Weekday()]
+		if want == "" {
+			want = "easy"
+		}
+		if prob.Difficulty != want {
+			t.Errorf("day %d (%s): expected %s, got %s", d, day(d).Weekday(), want, prob.ID)
+		}
+		counts[prob.ID]++
+	}
+	for id, n := range counts {
+		if n > 1 && !strings.HasPrefix(id, "cho-easy") {
+			t.Errorf("%s repeated %d times before its tier ran out", id, n)
+		}
+	}
+}
+
+func TestForDaySchedulesGetTheirOwnPicks(t *testing.T) {
+	problems := append(testProblems("easy", 5), testProblems("hard", 5)...)
+	s, _, _ := newTestSelector(t, problems)
+
+	beginner, err := s.ForDay("g1", "beginner", []string{"easy"}, day(0))
+	if err != nil {
+		t.Fatalf("ForDay returned error: %v", err)
+	}
+	advanced, err := s.ForDay("g1", "advanced", []string{"hard"}, day(0))
+	if err != nil {
+		t.Fatalf("ForDay returned error: %v", err)
+	}
+	if beginner.CollectionID != "easy" || advanced.CollectionID != "hard" {
+		t.Errorf("expected each schedule to use its collections, got %s and %s", beginner.ID, advanced.ID)
+	}
+	if again, _ := s.ForDay("g1", "beginner", []string{"easy"}, day(0)); again.ID != beginner.ID {
+		t.Errorf("expected the beginner pick to stay %s, got %s", beginner.ID, again.ID)
+	}
+}
+
+func TestForDayHonoursExceptions(t *testing.T) {
+	s, progress, exceptions, _ := newTestSelectorWithExceptions(t, testProblems("easy", 10))
+	for _, e := range []repo.Exception{
+		{GuildID: "g1", Kind: repo.ExceptionPause, From: "2025-01-02", To: "2025-01-03", Note: "tournament"},
+		{GuildID: "g1", Kind: repo.ExceptionSkip, From: "2025-01-05", To: "2025-01-05"},
+		{GuildID: "g1", Kind: repo.ExceptionOverride, From: "2025-01-04", To: "2025-01-04", ProblemID: "easy-7"},
+	} {
+		if _, err := exceptions.AddException(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := progress.SetNextProblem("g1", "easy-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []int{1, 2, 4} {
+		var noDaily *NoDailyError
+		if _, err := s.ForDay("g1", "", nil, day(d)); !errors.As(err, &noDaily) {
+			t.Errorf("day %d: expected a NoDailyError, got %v", d, err)
+		}
+	}
+	if prob, _ := s.ForDay("g1", "", nil, day(3)); prob.ID != "easy-7" {
+		t.Errorf("expected the override on day 3, got %s", prob.ID)
+	}
+	// the override doesn't use up the staff jump
+	if prob, _ := s.ForDay("g1", "", nil, day(5)); prob.ID != "easy-3" {
+		t.Errorf("expected the jump after the override, got %s", prob.ID)
+	}
+}
+
+func TestNextIsStableForSeed(t *testing.T) {
+	pool := testProblems("easy", 30)
+	a, cycle := Next(pool, nil, 1, 42)
+	b, _ := Next(pool, nil, 1, 42)
+	if a.ID != b.ID || cycle != 1 {
+		t.Errorf("expected the same first problem in cycle 1 for the same seed")
+	}
+	if _, cycle := Next(pool[:1], map[string]int{pool[0].ID: 1}, 1, 42); cycle != 2 {
+		t.Errorf("expected cycle 2 once every problem is seen, got %d", cycle)
+	}
+	// a reseed to cycle 5 forgets earlier picks
+	if _, posted := Cycle(pool, map[string]int{pool[0].ID: 3}, 5); posted != 0 {
+		t.Errorf("expected picks before the reseed to be forgotten, got %d posted", posted)
+	}
+}
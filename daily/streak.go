@@ -0,0 +1,53 @@
+package daily
+
+import (
+	"time"
+
+	"github.com/novnod/barista-bot/repo"
+)
+
+// MaxFreezes caps how many streak freezes a member can bank
+const MaxFreezes = 2
+
+// AdvanceStreak counts a daily solved on day, a scheduler.DayNumber in the
+// guild's zone. Days missed since the last solve are covered by banked
+// freezes, one each, if there are enough; otherwise the streak restarts.
+// Every freezeEvery days of streak earns a freeze, and 0 turns them off.
+func AdvanceStreak(st repo.Streak, day int64, freezeEvery int) repo.Streak {
+	last, ok := streakDay(st.LastDay)
+	switch {
+	case ok && day <= last:
+		return st
+	case ok && st.Current > 0 && day-last-1 <= int64(st.Freezes):
+		st.Freezes -= int(day - last - 1)
+		st.Current++
+	default:
+		st.Current = 1
+	}
+	st.LastDay = time.Unix(day*86400, 0).UTC().Format(time.DateOnly)
+	if freezeEvery > 0 && st.Current%freezeEvery == 0 && st.Freezes < MaxFreezes {
+		st.Freezes++
+	}
+	st.Best = max(st.Best, st.Current)
+	return st
+}
+
+// CurrentStreak returns the streak as it stands on today: it survives the
+// days missed since the last solve as long as freezes can cover them. A
+// member who hasn't solved today yet keeps yesterday's streak.
+func CurrentStreak(st repo.Streak, today int64) int {
+	last, ok := streakDay(st.LastDay)
+	if !ok || today-last-1 > int64(st.Freezes) {
+		return 0
+	}
+	return st.Current
+}
+
+// streakDay reads a stored YYYY-MM-DD as a day number
+func streakDay(s string) (int64, bool) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Unix() / 86400, true
+}
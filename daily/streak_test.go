@@ -0,0 +1,59 @@
+package daily
+
+import (
+	"testing"
+
+	"github.com/novnod/barista-bot/repo"
+)
+
+func TestAdvanceStreakCountsConsecutiveDays(t *testing.T) {
+	var st repo.Streak
+	for day := int64(100); day < 103; day++ {
+		st = AdvanceStreak(st, day, 0)
+	}
+	st = AdvanceStreak(st, 102, 0) // a second solve the same day
+	if st.Current != 3 || st.Best != 3 || st.LastDay != "1970-04-13" {
+		t.Errorf("expected a 3 day streak ending on day 102, got %+v", st)
+	}
+
+	st = AdvanceStreak(st, 105, 0)
+	if st.Current != 1 || st.Best != 3 {
+		t.Errorf("expected a missed day to restart the streak, got %+v", st)
+	}
+}
+
+func TestFreezesCoverMissedDays(t *testing.T) {
+	var st repo.Streak
+	for day := int64(0); day < 3; day++ {
+		st = AdvanceStreak(st, day, 3)
+	}
+	if st.Freezes != 1 {
+		t.Fatalf("expected a freeze after 3 days, got %+v", st)
+	}
+
+	// day 3 missed; still alive on day 4 thanks to the freeze
+	if got := CurrentStreak(st, 4); got != 3 {
+		t.Errorf("expected the freeze to keep the streak on day 4, got %d", got)
+	}
+	if got := CurrentStreak(st, 5); got != 0 {
+		t.Errorf("expected two missed days to break the streak, got %d", got)
+	}
+	st = AdvanceStreak(st, 4, 3)
+	if st.Current != 4 || st.Freezes != 0 {
+		t.Errorf("expected the freeze to be spent carrying the streak, got %+v", st)
+	}
+	st = AdvanceStreak(st, 6, 3)
+	if st.Current != 1 || st.Best != 4 {
+		t.Errorf("expected the streak to restart without a freeze, got %+v", st)
+	}
+}
+
+func TestFreezesAreCapped(t *testing.T) {
+	var st repo.Streak
+	for day := int64(0); day < 10; day++ {
+		st = AdvanceStreak(st, day, 1)
+	}
+	if st.Freezes != MaxFreezes {
+		t.Errorf("expected %d freezes at most, got %d", MaxFreezes, st.Freezes)
+	}
+}
@@ -0,0 +1,63 @@
+package daily
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/novnod/barista-bot/parser"
+)
+
+// Placeholders are the names a message template can use in braces, e.g. "{date}"
+var Placeholders = []string{"date", "prompt", "to_move", "collection", "difficulty", "number", "problem", "schedule"}
+
+var placeholderRe = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// TemplateVars returns the placeholder values for a daily posted on day
+func TemplateVars(prob *parser.GoProblem, day time.Time, schedule string) map[string]string {
+	toMove := "Black"
+	if prob.ToMove == "W" {
+		toMove = "White"
+	}
+	number := prob.Name
+	if prob.Number > 0 {
+		number = strconv.Itoa(prob.Number)
+	}
+	difficulty := prob.Difficulty
+	if difficulty != "" {
+		difficulty = strings.ToUpper(difficulty[:1]) + difficulty[1:]
+	}
+	return map[string]string{
+		"date":       day.Format("Mon 2 Jan 2006"),
+		"prompt":     prob.Prompt(),
+		"to_move":    toMove,
+		"collection": prob.Collection,
+		"difficulty": difficulty,
+		"number":     number,
+		"problem":    prob.ID,
+		"schedule":   schedule,
+	}
+}
+
+// ExpandTemplate fills in a template's placeholders, leaving unknown ones as written
+func ExpandTemplate(s string, vars map[string]string) string {
+	return placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := vars[m[1:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// CheckTemplate rejects templates that use placeholders that don't exist
+func CheckTemplate(s string) error {
+	for _, m := range placeholderRe.FindAllStringSubmatch(s, -1) {
+		if !slices.Contains(Placeholders, m[1]) {
+			return fmt.Errorf("unknown placeholder {%s} (use %s)", m[1], "{"+strings.Join(Placeholders, "}, {")+"}")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package daily
+
+import (
+	"testing"
+	"time"
+
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	prob := &parser.GoProblem{ID: "cho-easy-12", Name: "Problem 12: Black to live", Collection: "Cho Chikun", Number: 12, Difficulty: "easy", ToMove: "B"}
+	vars := TemplateVars(prob, time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC), "default")
+
+	got := ExpandTemplate("#{number} · {difficulty} · {to_move} · {date} · {other}", vars)
+	if want := "#12 · Easy · Black · Mon 2 Jun 2025 · {other}"; got != want {
+		t.Errorf("ExpandTemplate = %q, want %q", got, want)
+	}
+	if got := ExpandTemplate("{prompt}", vars); got != "Black to live" {
+		t.Errorf("expected the prompt, got %q", got)
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	for _, s := range []string{repo.DefaultTemplate.Title, repo.DefaultTemplate.Description, repo.DefaultTemplate.Footer, "no placeholders", "{schedule}: {problem}"} {
+		if err := CheckTemplate(s); err != nil {
+			t.Errorf("CheckTemplate(%q) returned error: %v", s, err)
+		}
+	}
+	if err := CheckTemplate("Daily {dat}"); err == nil {
+		t.Errorf("expected an unknown placeholder to be rejected")
+	}
+}
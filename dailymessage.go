@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/daily"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+)
+
+// Custom ID prefixes of the buttons under a daily, each followed by the problem ID
+const (
+	answerButton = "daily_answer:"
+	hintButton   = "daily_hint:"
+	revealButton = "daily_reveal:"
+)
+
+// dailyEmbed lays out a daily with the guild's message template. The board
+// image is expected as an attachment named imgName.
+func dailyEmbed(tmpl *repo.MessageTemplate, prob *parser.GoProblem, day time.Time, schedule, imgName string) *discordgo.MessageEmbed {
+	vars := daily.TemplateVars(prob, day, schedule)
+	embed := &discordgo.MessageEmbed{
+		Title:       daily.ExpandTemplate(tmpl.Title, vars),
+		Description: daily.ExpandTemplate(tmpl.Description, vars),
+		Color:       tmpl.Color,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "To move", Value: vars["to_move"], Inline: true},
+			{Name: "Difficulty", Value: orDash(vars["difficulty"]), Inline: true},
+			{Name: "Problem", Value: "#" + vars["number"], Inline: true},
+			{Name: "Collection", Value: orDash(vars["collection"])},
+		},
+	}
+	if footer := daily.ExpandTemplate(tmpl.Footer, vars); footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+	}
+	if imgName != "" {
+		embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://" + imgName}
+	}
+	return embed
+}
+
+// orDash stands in for empty embed field values, which Discord rejects
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
+// dailyButtons are the Answer and Hint buttons under a daily, and the staff
+// Reveal button if reveal is set
+func dailyButtons(prob *parser.GoProblem, reveal bool) []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{Label: "Answer", Style: discordgo.PrimaryButton, CustomID: answerButton + prob.ID},
+		discordgo.Button{Label: "Hint", Style: discordgo.SecondaryButton, CustomID: hintButton + prob.ID},
+	}
+	if reveal {
+		buttons = append(buttons, discordgo.Button{Label: "Reveal (staff)", Style: discordgo.DangerButton, CustomID: revealButton + prob.ID})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// dailyMessage builds the daily's embed with its board image and buttons
+func dailyMessage(guildID string, prob *parser.GoProblem, day time.Time, schedule string) (*discordgo.MessageSend, error) {
+	tmpl, err := templateRepo.GetTemplate(guildID)
+	if err != nil {
+		log.Printf("failed to load message template, using the default: %v", err)
+		tmpl = &repo.DefaultTemplate
+	}
+
+	img, err := problemImage(prob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render problem: %w", err)
+	}
+	embed := dailyEmbed(tmpl, prob, day, schedule, img.Name)
+	if sr, ok := problemRating(guildID, prob.ID); ok {
+		// the guild's own estimate of how hard the problem is
+		embed.Fields[1].Value += fmt.Sprintf(" · rated %.0f", sr.Glicko.Rating)
+	}
+	return &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Files:      []*discordgo.File{img},
+		Components: dailyButtons(prob, true),
+	}, nil
+}
+
+// problemImage renders a problem's board as a file to attach to a message
+func problemImage(prob *parser.GoProblem) (*discordgo.File, error) {
+	imgPath, err := parser.RenderProblem(prob, imageDir, 800, 40)
+	if err != nil {
+		return nil, err
+	}
+	img, err := os.ReadFile(imgPath)
+	if err != nil {
+		return nil, err
+	}
+	return &discordgo.File{Name: filepath.Base(imgPath), ContentType: "image/png", Reader: bytes.NewReader(img)}, nil
+}
+
+// handleDailyButton answers a press of one of the buttons under a daily
+func handleDailyButton(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	id := i.MessageComponentData().CustomID
+	prefix, problemID, _ := strings.Cut(id, ":")
+	prob := pg.Problem(problemID)
+	if prob == nil {
+		respondEphemeral(s, i, "This problem isn't loaded any more.")
+		return
+	}
+
+	switch prefix + ":" {
+	case answerButton:
+		openAnswerForm(s, i, answerButton, prob)
+
+	case hintButton:
+		if err := attemptRepo.RecordHint(interactionUserID(i), prob.ID, time.Now()); err != nil {
+			log.Printf("failed to record hint: %v", err)
+		}
+		respondEphemeral(s, i, hintMessage(prob))
+
+	case revealButton:
+		if !requireStaff(s, i) {
+			return
+		}
+		if post, err := postRepo.Post(i.ChannelID); err == nil && !post.RevealedAt.IsZero() {
+			respondEphemeral(s, i, "The solution has already been revealed.")
+			return
+		}
+		respondEphemeral(s, i, "Revealing the solution.")
+		if err := revealNow(s, pg, i.GuildID, i.ChannelID, prob); err != nil {
+			log.Printf("failed to reveal %s in %s: %v", prob.ID, i.ChannelID, err)
+		}
+
+	default:
+		log.Printf("unknown button: %s", id)
+	}
+}
+
+// openAnswerForm shows the modal for answering prob; its custom ID is
+// prefix followed by the problem ID
+func openAnswerForm(s *discordgo.Session, i *discordgo.InteractionCreate, prefix string, prob *parser.GoProblem) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: prefix + prob.ID,
+			Title:    "Answer: " + prob.Prompt(),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "moves",
+						Label:       "Your move, or a sequence of moves",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "C17 or C17 B18",
+						Required:    true,
+						MaxLength:   60,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("failed to open answer form: %v", err)
+	}
+}
+
+// submittedAnswer reads the problem and moves from an answer form sent with
+// the given custom ID prefix, telling the solver if either is unusable
+func submittedAnswer(s *discordgo.Session, i *discordgo.InteractionCreate, prefix string, pg *parser.GoParser) (*parser.GoProblem, []string, bool) {
+	data := i.ModalSubmitData()
+	prob := pg.Problem(strings.TrimPrefix(data.CustomID, prefix))
+	if prob == nil {
+		respondEphemeral(s, i, "This problem isn't loaded any more.")
+		return nil, nil, false
+	}
+	value := data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+	moves, ok := parseAnswer(value)
+	if !ok {
+		respondEphemeral(s, i, "That doesn't look like a move; try something like C17.")
+		return nil, nil, false
+	}
+	return prob, moves, true
+}
+
+// deferVerdict acknowledges an answer, privately, before it's graded:
+// recording it can grant roles and post announcements, which would
+// otherwise hold up the reply. It reports whether the acknowledgement went
+// through.
+func deferVerdict(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		log.Printf("failed to acknowledge answer: %v", err)
+		return false
+	}
+	return true
+}
+
+// followupVerdict replies to the solver alone, after deferVerdict, with a
+// graded answer and the board image at imgPath, if there is one
+func followupVerdict(s *discordgo.Session, i *discordgo.InteractionCreate, msg, imgPath string, components []discordgo.MessageComponent) {
+	params := &discordgo.WebhookParams{Content: msg, Flags: discordgo.MessageFlagsEphemeral, Components: components}
+	if imgPath != "" {
+		if file, err := os.Open(imgPath); err != nil {
+			log.Printf("failed to open image: %v", err)
+		} else {
+			defer file.Close()
+			params.Files = []*discordgo.File{{Name: filepath.Base(imgPath), ContentType: "image/png", Reader: file}}
+		}
+	}
+	if _, err := s.FollowupMessageCreate(i.Interaction, true, params); err != nil {
+		log.Printf("failed to reply to answer: %v", err)
+	}
+}
+
+// handleAnswerSubmit grades an answer sent with the Answer button, replying
+// only to the solver so the thread isn't spoiled. Answers to a daily
+// delivered by DM count in the guild that sent it.
+func handleAnswerSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	prob, moves, ok := submittedAnswer(s, i, answerButton, pg)
+	if !ok {
+		return
+	}
+	guildID := i.GuildID
+	if guildID == "" {
+		dm, err := subscriptionRepo.LatestDM(i.ChannelID, prob.ID)
+		if err != nil {
+			log.Printf("failed to look up DM daily %s in %s: %v", prob.ID, i.ChannelID, err)
+			respondEphemeral(s, i, "Couldn't tell which server this daily came from.")
+			return
+		}
+		guildID = dm.GuildID
+	}
+	if !deferVerdict(s, i) {
+		return
+	}
+	verdict, msg, imgPath, err := gradeAnswer(prob, moves)
+	if err == nil {
+		if st := recordDailyAttempt(s, pg, guildID, interactionUserID(i), i.ChannelID, prob, moves, verdict); st != nil {
+			msg += "\n" + streakLine(st)
+		}
+	}
+	followupVerdict(s, i, msg, imgPath, nil)
+}
+
+// interactionUserID returns who triggered an interaction, in a guild or a DM
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil {
+		return i.Member.User.ID
+	}
+	return i.User.ID
+}
+
+// hintMessage points at the column of the first correct move
+func hintMessage(prob *parser.GoProblem) string {
+	line := prob.CorrectLine()
+	if len(line) == 0 {
+		return "There's no solution on file for this problem, so there's no hint either."
+	}
+	name := parser.CoordName(line[0].Coord)
+	return fmt.Sprintf("Hint: %s. The first move is in column %s.", prob.Prompt(), name[:1])
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+)
+
+// exceptionsCommand describes /daily_exceptions, which pauses, skips or
+// overrides dailies on particular dates
+func exceptionsCommand() *discordgo.ApplicationCommand {
+	dateOption := func(name, desc string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type: discordgo.ApplicationCommandOptionString, Name: name, Description: desc, Required: true,
+		}
+	}
+	scheduleOption := &discordgo.ApplicationCommandOption{
+		Type: discordgo.ApplicationCommandOptionString, Name: "schedule", Description: "Only this schedule (all if omitted)",
+	}
+	noteOption := &discordgo.ApplicationCommandOption{
+		Type: discordgo.ApplicationCommandOptionString, Name: "note", Description: "Shown to players, e.g. \"club tournament\"", MaxLength: 100,
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        "daily_exceptions",
+		Description: "Pause, skip or choose dailies on particular dates",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "pause",
+				Description: "Post no dailies between two dates",
+				Options: []*discordgo.ApplicationCommandOption{
+					dateOption("from", "First paused day, YYYY-MM-DD"),
+					dateOption("to", "Last paused day, YYYY-MM-DD"),
+					scheduleOption, noteOption,
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "skip",
+				Description: "Post no daily on one date",
+				Options: []*discordgo.ApplicationCommandOption{
+					dateOption("date", "Day to skip, YYYY-MM-DD"),
+					scheduleOption, noteOption,
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "override",
+				Description: "Post a chosen problem on one date",
+				Options: []*discordgo.ApplicationCommandOption{
+					dateOption("date", "Day, YYYY-MM-DD"),
+					{Type: discordgo.ApplicationCommandOptionString, Name: "problem", Description: "Problem ID, e.g. cho-easy-12", Required: true},
+					scheduleOption, noteOption,
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "Show current and upcoming exceptions",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Delete an exception",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "id", Description: "ID from /daily_exceptions list", Required: true},
+				},
+			},
+		},
+	}
+}
+
+// handleExceptions runs a /daily_exceptions subcommand for staff
+func handleExceptions(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	if !requireStaff(s, i) {
+		return
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+	today := time.Now().In(guildLocation(i.GuildID)).Format(time.DateOnly)
+
+	switch sub.Name {
+	case "list":
+		exceptions, err := exceptionRepo.ListExceptions(i.GuildID, today)
+		if err != nil {
+			respondError(s, i, "could not list exceptions: "+err.Error())
+			return
+		}
+		if len(exceptions) == 0 {
+			respondEphemeral(s, i, "No current or upcoming exceptions.")
+			return
+		}
+		var b strings.Builder
+		for _, e := range exceptions {
+			b.WriteString(formatException(e) + "\n")
+		}
+		respondEphemeral(s, i, b.String())
+		return
+
+	case "remove":
+		id := opts["id"].IntValue()
+		if err := exceptionRepo.DeleteException(i.GuildID, id); err == sql.ErrNoRows {
+			respondEphemeral(s, i, fmt.Sprintf("No exception with ID %d.", id))
+		} else if err != nil {
+			respondError(s, i, "could not remove exception: "+err.Error())
+		} else {
+			respondEphemeral(s, i, fmt.Sprintf("Removed exception %d.", id))
+		}
+		return
+	}
+
+	e := repo.Exception{GuildID: i.GuildID, Kind: sub.Name}
+	if o, ok := opts["note"]; ok {
+		e.Note = strings.TrimSpace(o.StringValue())
+	}
+	if o, ok := opts["schedule"]; ok {
+		e.Schedule = strings.ToLower(strings.TrimSpace(o.StringValue()))
+		if _, err := dailyRepo.GetSchedule(i.GuildID, e.Schedule); err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("No schedule named %q.", e.Schedule))
+			return
+		}
+	}
+
+	var err error
+	if sub.Name == repo.ExceptionPause {
+		e.From, err = parseDay(opts["from"].StringValue())
+		if err == nil {
+			e.To, err = parseDay(opts["to"].StringValue())
+		}
+		if err == nil && e.To < e.From {
+			err = fmt.Errorf("the pause ends before it starts")
+		}
+	} else {
+		e.From, err = parseDay(opts["date"].StringValue())
+		e.To = e.From
+	}
+	if err != nil {
+		respondEphemeral(s, i, fmt.Sprintf("Couldn't save: %v.", err))
+		return
+	}
+	if e.To < today {
+		respondEphemeral(s, i, "Couldn't save: that date has already passed.")
+		return
+	}
+	if sub.Name == repo.ExceptionOverride {
+		e.ProblemID = strings.TrimSpace(opts["problem"].StringValue())
+		if pg.Problem(e.ProblemID) == nil {
+			respondEphemeral(s, i, fmt.Sprintf("No problem with ID %q.", e.ProblemID))
+			return
+		}
+	}
+
+	if e.ID, err = exceptionRepo.AddException(e); err != nil {
+		respondError(s, i, "could not save exception: "+err.Error())
+		return
+	}
+	respondEphemeral(s, i, "Saved: "+formatException(e))
+}
+
+// parseDay checks a YYYY-MM-DD date and returns it unchanged
+func parseDay(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if _, err := time.Parse(time.DateOnly, s); err != nil {
+		return "", fmt.Errorf("%q isn't a YYYY-MM-DD date", s)
+	}
+	return s, nil
+}
+
+// formatException describes an exception on one line, e.g.
+// "#3 · pause 2025-03-10 to 2025-03-12 · all schedules · club tournament"
+func formatException(e repo.Exception) string {
+	what := fmt.Sprintf("%s %s", e.Kind, e.From)
+	switch e.Kind {
+	case repo.ExceptionPause:
+		what += " to " + e.To
+	case repo.ExceptionOverride:
+		what += " with " + e.ProblemID
+	}
+	scope := "all schedules"
+	if e.Schedule != "" {
+		scope = e.Schedule + " schedule"
+	}
+	line := fmt.Sprintf("#%d · %s · %s", e.ID, what, scope)
+	if e.Note != "" {
+		line += " · " + e.Note
+	}
+	return line
+}
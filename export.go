@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/worksheet"
+)
+
+// maxWorksheetProblems keeps exported PDFs under Discord's upload limit
+const maxWorksheetProblems = 200
+
+// worksheetCommand describes /worksheet, offering the loaded collections as choices
+func worksheetCommand(pg *parser.GoParser) *discordgo.ApplicationCommand {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, id := range pg.Collections() {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: id, Value: id})
+	}
+	minOne := 1.0
+	return &discordgo.ApplicationCommand{
+		Name:        "worksheet",
+		Description: "Export a printable PDF of problems",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "collection", Description: "Collection to export from", Choices: choices},
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "from", Description: "First problem number", MinValue: &minOne},
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "to", Description: "Last problem number", MinValue: &minOne},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "since", Description: "Export past dailies from this date (YYYY-MM-DD)"},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "until", Description: "Last daily date to export (YYYY-MM-DD), default today"},
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "per_page", Description: "Problems per page (default 6)", MinValue: &minOne, MaxValue: 24},
+			{Type: discordgo.ApplicationCommandOptionBoolean, Name: "answers", Description: "Add an answer key after the problems"},
+		},
+	}
+}
+
+// handleWorksheet builds the requested problem set and replies with a PDF
+func handleWorksheet(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range i.ApplicationCommandData().Options {
+		opts[o.Name] = o
+	}
+
+	problems, title, err := selectWorksheetProblems(pg, i.GuildID, opts)
+	if err != nil {
+		respond(s, i, err.Error())
+		return
+	}
+
+	// Rendering a long set takes a few seconds, so acknowledge first
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		respondError(s, i, "could not start export")
+		return
+	}
+
+	ws := worksheet.Options{Title: title, PerPage: 6}
+	if o, ok := opts["per_page"]; ok {
+		ws.PerPage = int(o.IntValue())
+	}
+	if o, ok := opts["answers"]; ok {
+		ws.AnswerKey = o.BoolValue()
+	}
+
+	var buf bytes.Buffer
+	if err := worksheet.Write(&buf, problems, ws); err != nil {
+		followupError(s, i, fmt.Sprintf("failed to build worksheet: %v", err))
+		return
+	}
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: fmt.Sprintf("%s (%d problems)", title, len(problems)),
+		Files: []*discordgo.File{{
+			Name:        "worksheet.pdf",
+			ContentType: "application/pdf",
+			Reader:      &buf,
+		}},
+	})
+	if err != nil {
+		followupError(s, i, fmt.Sprintf("failed to send worksheet: %v", err))
+	}
+}
+
+// selectWorksheetProblems picks problems either from a date range of the
+// guild's past dailies or from a collection filtered by problem number,
+// with a title
+func selectWorksheetProblems(pg *parser.GoParser, guildID string, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) ([]*parser.GoProblem, string, error) {
+	var problems []*parser.GoProblem
+	var title string
+
+	if o, ok := opts["since"]; ok {
+		since, err := time.Parse(time.DateOnly, o.StringValue())
+		if err != nil {
+			return nil, "", fmt.Errorf("since must be a date like 2025-01-31")
+		}
+		today := time.Now().In(guildLocation(guildID))
+		until := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+		if o, ok := opts["until"]; ok {
+			u, err := time.Parse(time.DateOnly, o.StringValue())
+			if err != nil {
+				return nil, "", fmt.Errorf("until must be a date like 2025-01-31")
+			}
+			until = u
+		}
+		if until.Before(since) {
+			return nil, "", fmt.Errorf("until is before since")
+		}
+		if until.Sub(since) > 366*24*time.Hour {
+			return nil, "", fmt.Errorf("export at most a year of dailies at a time")
+		}
+		picks, err := progressRepo.PicksBetween(guildID, since.Format(time.DateOnly), until.Format(time.DateOnly))
+		if err != nil {
+			return nil, "", fmt.Errorf("could not look up past dailies")
+		}
+		for _, pick := range picks {
+			if prob := pg.Problem(pick.ProblemID); prob != nil {
+				problems = append(problems, prob)
+			}
+		}
+		title = fmt.Sprintf("Dailies %s to %s", since.Format(time.DateOnly), until.Format(time.DateOnly))
+	} else {
+		collection := ""
+		if o, ok := opts["collection"]; ok {
+			collection = o.StringValue()
+		}
+		from, to := 1, math.MaxInt
+		if o, ok := opts["from"]; ok {
+			from = int(o.IntValue())
+		}
+		if o, ok := opts["to"]; ok {
+			to = int(o.IntValue())
+		}
+		for _, prob := range pg.Problems {
+			if (collection == "" || prob.CollectionID == collection) && prob.Number >= from && prob.Number <= to {
+				problems = append(problems, prob)
+			}
+		}
+		title = "Problems"
+		if collection != "" {
+			title = collection + " problems"
+		}
+		if n := len(problems); n > 0 {
+			title += fmt.Sprintf(" %d-%d", problems[0].Number, problems[n-1].Number)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil, "", fmt.Errorf("no problems match that selection")
+	}
+	if len(problems) > maxWorksheetProblems {
+		return nil, "", fmt.Errorf("that's %d problems; export at most %d at a time", len(problems), maxWorksheetProblems)
+	}
+	return problems, title, nil
+}
+
+// followupError reports a failure after the interaction has been deferred
+func followupError(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
+	log.Print(msg)
+	s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{Content: msg})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+)
+
+// Discord's limits on forum tags
+const (
+	maxForumTags    = 20 // per forum
+	maxAppliedTags  = 5  // per post
+	maxForumTagName = 20
+)
+
+// startForumPost opens a post for the daily in a forum channel, tagged with
+// its difficulty and collection, with msg as the starter message. If msg is
+// nil or can't be sent the post starts with a text board instead.
+func startForumPost(s *discordgo.Session, forum *discordgo.Channel, name string, prob *parser.GoProblem, msg *discordgo.MessageSend) (*discordgo.Channel, error) {
+	start := &discordgo.ThreadStart{Name: name, AutoArchiveDuration: 1440, AppliedTags: forumTags(s, forum, prob)}
+	if msg != nil {
+		post, err := s.ForumThreadStartComplex(forum.ID, start, msg)
+		if err == nil {
+			return post, nil
+		}
+		log.Printf("failed to start forum post, using a text board instead: %v", err)
+	}
+
+	board, err := textBoard(prob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render problem: %w", err)
+	}
+	post, err := s.ForumThreadStartComplex(forum.ID, start, &discordgo.MessageSend{Content: board})
+	if err != nil {
+		return nil, fmt.Errorf("could not create forum post: %w", err)
+	}
+	return post, nil
+}
+
+// forumTagNames are the tags a daily is filed under: its difficulty and collection
+func forumTagNames(prob *parser.GoProblem) []string {
+	var names []string
+	if prob.Difficulty != "" {
+		names = append(names, strings.ToUpper(prob.Difficulty[:1])+prob.Difficulty[1:])
+	}
+	if prob.CollectionID != "" {
+		names = append(names, truncateRunes(prob.CollectionID, maxForumTagName))
+	}
+	return names
+}
+
+// forumTags returns the IDs of the forum's tags for the problem, first
+// adding any that are missing if there's room and the bot may edit the forum
+func forumTags(s *discordgo.Session, forum *discordgo.Channel, prob *parser.GoProblem) []string {
+	names := forumTagNames(prob)
+	tags := forum.AvailableTags
+
+	if missing := missingTags(tags, names); len(missing) > 0 {
+		added := append([]discordgo.ForumTag(nil), tags...)
+		for _, name := range missing {
+			added = append(added, discordgo.ForumTag{Name: name})
+		}
+		if edited, err := s.ChannelEdit(forum.ID, &discordgo.ChannelEdit{AvailableTags: &added}); err != nil {
+			log.Printf("failed to add forum tags %v: %v", missing, err)
+		} else {
+			tags = edited.AvailableTags
+		}
+	}
+	return appliedTags(tags, names)
+}
+
+// missingTags returns the names the forum has no tag for, or nil if adding
+// them all would go past the forum's tag limit
+func missingTags(tags []discordgo.ForumTag, names []string) []string {
+	var missing []string
+	for _, name := range names {
+		if findTag(tags, name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(tags)+len(missing) > maxForumTags {
+		return nil
+	}
+	return missing
+}
+
+// appliedTags returns the IDs of the tags with the given names, up to the
+// limit a post can have
+func appliedTags(tags []discordgo.ForumTag, names []string) []string {
+	var ids []string
+	for _, name := range names {
+		if id := findTag(tags, name); id != "" && len(ids) < maxAppliedTags {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// findTag returns the ID of the tag with the given name, ignoring case
+func findTag(tags []discordgo.ForumTag, name string) string {
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Name, name) {
+			return tag.ID
+		}
+	}
+	return ""
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+)
+
+func TestForumTagNames(t *testing.T) {
+	tests := []struct {
+		name string
+		prob parser.GoProblem
+		want []string
+	}{
+		{"difficulty and collection", parser.GoProblem{Difficulty: "easy", CollectionID: "cho-easy"}, []string{"Easy", "cho-easy"}},
+		{"no difficulty", parser.GoProblem{CollectionID: "cho-easy"}, []string{"cho-easy"}},
+		{"no collection", parser.GoProblem{Difficulty: "hard"}, []string{"Hard"}},
+		{"neither", parser.GoProblem{}, nil},
+		{"long collection", parser.GoProblem{CollectionID: "gokyo-shumyo-section-four"}, []string{"gokyo-shumyo-section"}},
+		{"multibyte collection", parser.GoProblem{CollectionID: "詰碁詰碁詰碁詰碁詰碁詰碁"}, []string{"詰碁詰碁詰碁詰碁詰碁詰碁"}},
+	}
+	for _, tt := range tests {
+		if got := forumTagNames(&tt.prob); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: forumTagNames = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindTag(t *testing.T) {
+	tags := []discordgo.ForumTag{{ID: "1", Name: "Easy"}, {ID: "2", Name: "cho-easy"}}
+	tests := []struct {
+		name, want string
+	}{
+		{"Easy", "1"},
+		{"easy", "1"},
+		{"CHO-EASY", "2"},
+		{"Hard", ""},
+	}
+	for _, tt := range tests {
+		if got := findTag(tags, tt.name); got != tt.want {
+			t.Errorf("findTag(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"cho-easy", 20, "cho-easy"},
+		{"abcdef", 3, "abc"},
+		{"abc", 3, "abc"},
+		{"詰碁集", 2, "詰碁"},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateRunes(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestForumTagLimits(t *testing.T) {
+	var full []discordgo.ForumTag
+	for n := 0; n < maxForumTags; n++ {
+		full = append(full, discordgo.ForumTag{ID: fmt.Sprint(n), Name: fmt.Sprintf("tag %d", n)})
+	}
+	if got := missingTags(full[:maxForumTags-1], []string{"Easy", "tag 0"}); !slices.Equal(got, []string{"Easy"}) {
+		t.Errorf("expected the one missing tag to fit, got %q", got)
+	}
+	if got := missingTags(full[:maxForumTags-1], []string{"Easy", "cho-easy"}); got != nil {
+		t.Errorf("expected nothing added past the forum's limit, got %q", got)
+	}
+
+	var names []string
+	for n := 0; n < maxAppliedTags+2; n++ {
+		names = append(names, fmt.Sprintf("tag %d", n))
+	}
+	if got := appliedTags(full, names); len(got) != maxAppliedTags || got[0] != "0" {
+		t.Errorf("expected the first %d tags applied, got %q", maxAppliedTags, got)
+	}
+	if got := appliedTags(full, []string{"Easy", "tag 3"}); !slices.Equal(got, []string{"3"}) {
+		t.Errorf("expected tags the forum lacks to be left off, got %q", got)
+	}
+}
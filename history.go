@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+)
+
+// historyPageSize is how many dailies a /history page shows, one embed each
+const historyPageSize = 5
+
+// historyButton prefixes the custom IDs of /history's page buttons, which
+// carry "page:schedule:problem"
+const historyButton = "history:"
+
+// maxHistoryProblem caps the problem filter, which the page buttons carry
+// along with a schedule name, so their custom IDs stay within Discord's
+// 100 characters
+const maxHistoryProblem = 50
+
+// historyCommand describes /history, which pages through past dailies
+func historyCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "history",
+		Description: "Browse past dailies",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "schedule", Description: "Only this schedule's dailies", MaxLength: 32},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "problem", Description: "Only days that posted this problem ID, to check for repeats", MaxLength: maxHistoryProblem},
+		},
+	}
+}
+
+// handleHistory shows the first page of the guild's past dailies
+func handleHistory(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	var filter repo.HistoryFilter
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "schedule":
+			filter.Schedule = strings.ToLower(strings.TrimSpace(o.StringValue()))
+		case "problem":
+			filter.ProblemID = strings.TrimSpace(o.StringValue())
+		}
+	}
+	data, err := historyPage(i.GuildID, filter, 0, pg)
+	if err != nil {
+		respondError(s, i, "could not load history: "+err.Error())
+		return
+	}
+	data.Flags = discordgo.MessageFlagsEphemeral
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+	if err != nil {
+		log.Printf("failed to send history: %v", err)
+	}
+}
+
+// handleHistoryPage switches a /history message to another page
+func handleHistoryPage(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	parts := strings.SplitN(strings.TrimPrefix(i.MessageComponentData().CustomID, historyButton), ":", 3)
+	if len(parts) != 3 {
+		return
+	}
+	page, _ := strconv.Atoi(parts[0])
+	data, err := historyPage(i.GuildID, repo.HistoryFilter{Schedule: parts[1], ProblemID: parts[2]}, page, pg)
+	if err != nil {
+		respondError(s, i, "could not load history: "+err.Error())
+		return
+	}
+	// drop the previous page's thumbnails
+	data.Attachments = &[]*discordgo.MessageAttachment{}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: data,
+	})
+	if err != nil {
+		log.Printf("failed to update history: %v", err)
+	}
+}
+
+// historyPage builds one page of history: an embed per daily with its
+// board as a thumbnail, and buttons to move between pages
+func historyPage(guildID string, filter repo.HistoryFilter, page int, pg *parser.GoParser) (*discordgo.InteractionResponseData, error) {
+	posts, total, err := postRepo.History(guildID, filter, page*historyPageSize, historyPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return &discordgo.InteractionResponseData{Content: "No dailies match."}, nil
+	}
+	pages := (total + historyPageSize - 1) / historyPageSize
+
+	data := &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Page %d of %d · %d dailies", page+1, pages, total),
+	}
+	attached := map[string]bool{}
+	for _, post := range posts {
+		prob := pg.Problem(post.ProblemID)
+		embed := historyEmbed(guildID, post, prob)
+		if prob != nil {
+			file, err := problemImage(prob)
+			if err != nil {
+				log.Printf("failed to render thumbnail for %s: %v", prob.ID, err)
+			} else {
+				embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: "attachment://" + file.Name}
+				if !attached[file.Name] {
+					data.Files = append(data.Files, file)
+					attached[file.Name] = true
+				}
+			}
+		}
+		data.Embeds = append(data.Embeds, embed)
+	}
+
+	button := func(label string, to int, disabled bool) discordgo.Button {
+		return discordgo.Button{
+			Label:    label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("%s%d:%s:%s", historyButton, to, filter.Schedule, filter.ProblemID),
+			Disabled: disabled,
+		}
+	}
+	data.Components = []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			button("◀ Newer", page-1, page == 0),
+			button("Older ▶", page+1, page+1 >= pages),
+		}},
+	}
+	return data, nil
+}
+
+// historyEmbed describes one past daily; prob is nil if it's no longer loaded
+func historyEmbed(guildID string, post repo.DailyPost, prob *parser.GoProblem) *discordgo.MessageEmbed {
+	title := post.Day
+	if post.Schedule != "" && post.Schedule != repo.DefaultSchedule {
+		title += " · " + post.Schedule
+	}
+	description := post.ProblemID
+	if prob != nil {
+		description = fmt.Sprintf("%s · %s\n`%s`", prob.Prompt(), prob.Details(), prob.ID)
+	}
+	link := fmt.Sprintf("<#%s>", post.ThreadID)
+	if post.MessageID != "" {
+		link += fmt.Sprintf(" · [jump to post](https://discord.com/channels/%s/%s/%s)", guildID, post.ThreadID, post.MessageID)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		Description: description + "\n" + link,
+		Fields:      []*discordgo.MessageEmbedField{{Name: "Solve rate", Value: solveRate(post)}},
+	}
+}
+
+// solveRate describes how the guild did on a daily, as counted at its reveal
+func solveRate(post repo.DailyPost) string {
+	switch {
+	case post.RevealedAt.IsZero():
+		return "Not revealed yet"
+	case post.Players == 0:
+		return "Nobody answered"
+	default:
+		return fmt.Sprintf("%d%% (%d of %d players)", post.Solved*100/post.Players, post.Solved, post.Players)
+	}
+}
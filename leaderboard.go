@@ -0,0 +1,205 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/repo"
+)
+
+// leaderboardPageSize is how many members a /leaderboard page lists
+const leaderboardPageSize = 10
+
+// leaderboardButton prefixes the custom IDs of /leaderboard's page buttons,
+// which carry "page:period:metric"
+const leaderboardButton = "leaderboard:"
+
+// Leaderboard periods
+const (
+	periodWeek  = "week"
+	periodMonth = "month"
+	periodAll   = "all"
+)
+
+// leaderboardCommand describes /leaderboard
+func leaderboardCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "leaderboard",
+		Description: "Rank the server's solvers",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "period",
+				Description: "Which attempts count (this week by default)",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "This week", Value: periodWeek},
+					{Name: "This month", Value: periodMonth},
+					{Name: "All time", Value: periodAll},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "metric",
+				Description: "What to rank by (solves by default)",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Problems solved", Value: repo.MetricSolves},
+					{Name: "Accuracy", Value: repo.MetricAccuracy},
+					{Name: "Speed to first correct answer", Value: repo.MetricSpeed},
+					{Name: "Current streak", Value: repo.MetricStreak},
+				},
+			},
+		},
+	}
+}
+
+// handleLeaderboard shows the first page of a board
+func handleLeaderboard(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" {
+		respondEphemeral(s, i, "Leaderboards are kept per server; use /leaderboard in one.")
+		return
+	}
+	period, metric := periodWeek, repo.MetricSolves
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "period":
+			period = o.StringValue()
+		case "metric":
+			metric = o.StringValue()
+		}
+	}
+	data, err := leaderboardPage(i.GuildID, interactionUserID(i), period, metric, 0)
+	if err != nil {
+		respondError(s, i, "could not load leaderboard: "+err.Error())
+		return
+	}
+	data.Flags = discordgo.MessageFlagsEphemeral
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+	if err != nil {
+		log.Printf("failed to send leaderboard: %v", err)
+	}
+}
+
+// handleLeaderboardPage switches a /leaderboard message to another page
+func handleLeaderboardPage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.SplitN(strings.TrimPrefix(i.MessageComponentData().CustomID, leaderboardButton), ":", 3)
+	if len(parts) != 3 {
+		return
+	}
+	page, _ := strconv.Atoi(parts[0])
+	data, err := leaderboardPage(i.GuildID, interactionUserID(i), parts[1], parts[2], page)
+	if err != nil {
+		respondError(s, i, "could not load leaderboard: "+err.Error())
+		return
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: data,
+	})
+	if err != nil {
+		log.Printf("failed to update leaderboard: %v", err)
+	}
+}
+
+// leaderboardPage builds one page of a board as an embed, with the viewer's
+// own rank in the footer and buttons to move between pages
+func leaderboardPage(guildID, userID, period, metric string, page int) (*discordgo.InteractionResponseData, error) {
+	now := time.Now().In(guildLocation(guildID))
+	q := repo.LeaderboardQuery{GuildID: guildID, Metric: metric, Today: now.Format(time.DateOnly)}
+	var title string
+	switch period {
+	case periodWeek:
+		// weeks start on Monday
+		q.Since = startOfDay(now.AddDate(0, 0, -(int(now.Weekday())+6)%7))
+		title = "This week"
+	case periodMonth:
+		q.Since = startOfDay(now.AddDate(0, 0, 1-now.Day()))
+		title = "This month"
+	default:
+		title = "All time"
+	}
+	if metric == repo.MetricStreak {
+		// streaks stand as of today whatever the period
+		title = "Current streaks"
+	} else {
+		title += " · " + map[string]string{
+			repo.MetricSolves:   "problems solved",
+			repo.MetricAccuracy: "accuracy",
+			repo.MetricSpeed:    "speed to first correct answer",
+		}[metric]
+	}
+
+	entries, total, err := attemptRepo.Leaderboard(q, page*leaderboardPageSize, leaderboardPageSize)
+	if err != nil {
+		return nil, err
+	}
+	embed := &discordgo.MessageEmbed{Title: "🏆 " + title, Color: repo.DefaultTemplate.Color}
+	if total == 0 {
+		embed.Description = "Nobody's on this board yet."
+		if metric == repo.MetricAccuracy || metric == repo.MetricSpeed {
+			embed.Description += fmt.Sprintf(" It takes %d answers to be ranked.", repo.LeaderboardMinimum)
+		}
+		return &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}}, nil
+	}
+	pages := (total + leaderboardPageSize - 1) / leaderboardPageSize
+
+	lines := make([]string, len(entries))
+	for n, e := range entries {
+		lines[n] = fmt.Sprintf("**%d.** <@%s> — %s", e.Rank, e.UserID, leaderboardScore(metric, e))
+	}
+	embed.Description = strings.Join(lines, "\n")
+	footer := fmt.Sprintf("Page %d of %d · %d members", page+1, pages, total)
+	if me, err := attemptRepo.LeaderboardRank(q, userID); err == nil {
+		footer = fmt.Sprintf("You're #%d · ", me.Rank) + footer
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to find leaderboard rank: %v", err)
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+
+	button := func(label string, to int, disabled bool) discordgo.Button {
+		return discordgo.Button{
+			Label:    label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("%s%d:%s:%s", leaderboardButton, to, period, metric),
+			Disabled: disabled,
+		}
+	}
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				button("◀ Previous", page-1, page == 0),
+				button("Next ▶", page+1, page+1 >= pages),
+			}},
+		},
+	}, nil
+}
+
+// leaderboardScore describes a member's standing on a board, e.g. "12 solves · 80%"
+func leaderboardScore(metric string, e repo.LeaderboardEntry) string {
+	accuracy := e.Correct * 100 / max(e.Attempts, 1)
+	switch metric {
+	case repo.MetricAccuracy:
+		return fmt.Sprintf("%d%% (%d of %d)", accuracy, e.Correct, e.Attempts)
+	case repo.MetricSpeed:
+		return fmt.Sprintf("%s on average", e.Speed.Round(time.Second))
+	case repo.MetricStreak:
+		return fmt.Sprintf("%s (best %s)", days(e.Streak), days(e.Best))
+	default:
+		return fmt.Sprintf("%d solved · %d%% accuracy", e.Solves, accuracy)
+	}
+}
+
+// startOfDay returns midnight of t's date in t's location
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -1,23 +1,48 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/novnod/barista-bot/config"
+	"github.com/novnod/barista-bot/daily"
 	"github.com/novnod/barista-bot/parser"
 	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/scheduler"
 )
 
+// imageDir is where rendered boards are written before upload
+const imageDir = "./out"
+
 var (
-	dailyRepo *repo.DailyRepository
+	dailyRepo        *repo.DailyRepository
+	progressRepo     *repo.ProgressRepository
+	revealRepo       *repo.RevealRepository
+	jobRepo          *repo.JobRepository
+	exceptionRepo    *repo.ExceptionRepository
+	templateRepo     *repo.TemplateRepository
+	postRepo         *repo.PostRepository
+	attemptRepo      *repo.AttemptRepository
+	streakRepo       *repo.StreakRepository
+	ratingRepo       *repo.RatingRepository
+	reviewRepo       *repo.ReviewRepository
+	achievementRepo  *repo.AchievementRepository
+	subscriptionRepo *repo.SubscriptionRepository
+	selector         *daily.Selector
+
+	// sharedThreads serializes looking up and creating shared daily threads,
+	// so two posts at once can't both start the day's thread
+	sharedThreads sync.Mutex
 )
 
 func main() {
@@ -34,24 +59,44 @@ func main() {
 	}
 
 	dailyRepo = repo.InitDailyRepository(sqlDB)
+	progressRepo = repo.InitProgressRepository(sqlDB)
+	revealRepo = repo.InitRevealRepository(sqlDB)
+	jobRepo = repo.InitJobRepository(sqlDB)
+	exceptionRepo = repo.InitExceptionRepository(sqlDB)
+	templateRepo = repo.InitTemplateRepository(sqlDB)
+	postRepo = repo.InitPostRepository(sqlDB)
+	attemptRepo = repo.InitAttemptRepository(sqlDB)
+	streakRepo = repo.InitStreakRepository(sqlDB)
+	ratingRepo = repo.InitRatingRepository(sqlDB)
+	reviewRepo = repo.InitReviewRepository(sqlDB)
+	achievementRepo = repo.InitAchievementRepository(sqlDB)
+	subscriptionRepo = repo.InitSubscriptionRepository(sqlDB)
+
+	if err := os.MkdirAll(imageDir, 0755); err != nil {
+		log.Fatalf("failed to create image directory: %v", err)
+	}
 
-	// Load only easy SGF problems
+	// Load every tier; the daily rotation picks between them
 	pg := parser.GoParser{}
-	if err := pg.LoadProblems("./files/cho-easy.sgf"); err != nil {
-		log.Fatalf("failed to load easy problems: %v", err)
+	for _, tier := range daily.Tiers {
+		if err := pg.LoadProblems("./files/cho-" + tier + ".sgf"); err != nil {
+			log.Fatalf("failed to load %s problems: %v", tier, err)
+		}
 	}
+	selector = daily.NewSelector(&pg, progressRepo, exceptionRepo)
 
 	// Initialize Discord session
 	dg, err := discordgo.New("Bot " + cfg.BotToken)
 	if err != nil {
 		log.Fatalf("error creating Discord session: %v", err)
 	}
-	dg.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsGuildMessageReactions
+	dg.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsGuildMessageReactions | discordgo.IntentsMessageContent |
+		discordgo.IntentsDirectMessages
 
 	// Register event handlers
 	dg.AddHandler(onReady)
 	dg.AddHandler(commandHandler(&pg))
-	dg.AddHandler(onMessage)
+	dg.AddHandler(messageHandler(&pg))
 	dg.AddHandler(onMessageReaction)
 
 	// Open WebSocket connection
@@ -67,7 +112,19 @@ func main() {
 	}
 
 	// Register slash commands
-	registerCommands(dg, botUser.ID, "1314429177230921840")
+	registerCommands(dg, botUser.ID, "1314429177230921840", &pg)
+
+	// Post dailies at each guild's configured time, and their solutions later
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := jobRepo.ReleaseRunning(); err != nil {
+		log.Printf("failed to release unfinished jobs: %v", err)
+	}
+	sched := scheduler.New(dailyRepo, jobRepo, postScheduledDaily(dg), scheduler.RealClock()).
+		Handle(repo.JobReveal, revealDaily(dg, &pg)).
+		Handle(repo.JobReminder, remindDaily(dg)).
+		Handle(repo.JobDM, deliverDMs(dg, &pg))
+	go sched.Run(ctx)
 
 	log.Println("Bot is now running. Press Ctrl+C to exit.")
 
@@ -81,8 +138,43 @@ func onReady(s *discordgo.Session, _ *discordgo.Ready) {
 	log.Printf("Logged in as %s#%s", s.State.User.Username, s.State.User.Discriminator)
 }
 
-func onMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
-	log.Printf("Message from: %s and they said: %s", m.Author.Username, m.Content)
+func messageHandler(pg *parser.GoParser) any {
+	return func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		log.Printf("Message from: %s and they said: %s", m.Author.Username, m.Content)
+		if m.Author.Bot {
+			return
+		}
+
+		// Grade answers posted in DMs the daily was delivered to, answering
+		// the latest daily there
+		if m.GuildID == "" {
+			dm, err := subscriptionRepo.LatestDM(m.ChannelID, "")
+			if err == sql.ErrNoRows {
+				return
+			}
+			if err != nil {
+				log.Printf("failed to look up DM %s: %v", m.ChannelID, err)
+				return
+			}
+			if prob := pg.Problem(dm.ProblemID); prob != nil {
+				handleAnswer(s, m, pg, dm.GuildID, prob)
+			}
+			return
+		}
+
+		// Grade answers posted in daily threads
+		thread, err := postRepo.Post(m.ChannelID)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Printf("failed to look up thread %s: %v", m.ChannelID, err)
+			return
+		}
+		if prob := pg.Problem(thread.ProblemID); prob != nil {
+			handleAnswer(s, m, pg, m.GuildID, prob)
+		}
+	}
 }
 
 func onMessageReaction(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
@@ -103,20 +195,83 @@ func commandHandler(pg *parser.GoParser) any {
 			case "edit_daily":
 				handleEditDaily(s, i)
 
+			case "worksheet":
+				handleWorksheet(s, i, pg)
+
+			case "daily_admin":
+				handleDailyAdmin(s, i, pg)
+
+			case "schedule":
+				handleSchedule(s, i, pg)
+
+			case "daily_exceptions":
+				handleExceptions(s, i, pg)
+
+			case "history":
+				handleHistory(s, i, pg)
+
+			case "streak":
+				handleStreak(s, i)
+
+			case "leaderboard":
+				handleLeaderboard(s, i)
+
+			case "stats":
+				handleStats(s, i, pg)
+
+			case "review":
+				handleReview(s, i, pg)
+
+			case "achievements":
+				handleAchievements(s, i)
+
+			case "achievements_admin":
+				handleAchievementsAdmin(s, i)
+
+			case "subscribe":
+				handleSubscribe(s, i)
+
+			case "unsubscribe":
+				handleUnsubscribe(s, i)
+
 			default:
 				log.Printf("unknown command: %s", i.ApplicationCommandData().Name)
 			}
+		case discordgo.InteractionMessageComponent:
+			switch id := i.MessageComponentData().CustomID; {
+			case strings.HasPrefix(id, historyButton):
+				handleHistoryPage(s, i, pg)
+			case strings.HasPrefix(id, leaderboardButton):
+				handleLeaderboardPage(s, i)
+			case id == reviewNextButton, strings.HasPrefix(id, reviewAnswerButton):
+				handleReviewButton(s, i, pg)
+			default:
+				handleDailyButton(s, i, pg)
+			}
 		case discordgo.InteractionModalSubmit:
-			handleModalSubmit(s, i)
+			handleModalSubmit(s, i, pg)
 		}
 	}
 }
 
-func registerCommands(s *discordgo.Session, appID, guildID string) {
+func registerCommands(s *discordgo.Session, appID, guildID string, pg *parser.GoParser) {
 	commands := []*discordgo.ApplicationCommand{
 		{Name: "test", Description: "Just a test"},
 		{Name: "daily", Description: "Starts a daily Go problem thread"},
 		{Name: "edit_daily", Description: "Edit daily settings"},
+		worksheetCommand(pg),
+		dailyAdminCommand(pg),
+		scheduleCommand(pg),
+		exceptionsCommand(),
+		historyCommand(),
+		streakCommand(),
+		leaderboardCommand(),
+		statsCommand(),
+		reviewCommand(),
+		achievementsCommand(),
+		achievementsAdminCommand(),
+		subscribeCommand(),
+		unsubscribeCommand(),
 	}
 	for _, cmd := range commands {
 		if _, err := s.ApplicationCommandCreate(appID, guildID, cmd); err != nil {
@@ -125,20 +280,17 @@ func registerCommands(s *discordgo.Session, appID, guildID string) {
 	}
 }
 
-func handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: "Saving updated time",
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
-	})
-	if err != nil {
-		respondError(s, i, "error saving updated time")
-	}
-
+func handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
 	data := i.ModalSubmitData()
 
+	if strings.HasPrefix(data.CustomID, answerButton) {
+		handleAnswerSubmit(s, i, pg)
+		return
+	}
+	if strings.HasPrefix(data.CustomID, reviewAnswerButton) {
+		handleReviewSubmit(s, i, pg)
+		return
+	}
 	if !strings.HasPrefix(data.CustomID, "edit_daily") {
 		return
 	}
@@ -147,98 +299,383 @@ func handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	channel_id := data.Components[1].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
 	log.Printf("the submitted updated time is %s", daily_time)
 	log.Printf("the channel id is %s", channel_id)
-	err = dailyRepo.SetConfig(i.GuildID, channel_id, daily_time)
+
+	tr, err := scheduler.ParseTrigger(daily_time)
+	if err != nil {
+		respondEphemeral(s, i, fmt.Sprintf("Couldn't save: %v.", err))
+		return
+	}
+	channel_id, err = resolveChannelID(s, i.GuildID, channel_id)
+	if err != nil {
+		respondEphemeral(s, i, fmt.Sprintf("Couldn't save: %v.", err))
+		return
+	}
+	err = dailyRepo.SetConfig(i.GuildID, channel_id, tr.Spec(), tr.Zone())
 	if err != nil {
 		log.Printf("error occured updating config: %s", err)
 		respondError(s, i, "error occured updating config")
+		return
+	}
+
+	respondEphemeral(s, i, fmt.Sprintf("Saved: dailies post in <#%s> at %s.\nNext posts:\n%s",
+		channel_id, tr, formatFireTimes(scheduler.NextN(tr, time.Now(), 5))))
+}
+
+// formatFireTimes lists times in their own zone, with a Discord timestamp
+// that each reader sees in their local time
+func formatFireTimes(times []time.Time) string {
+	var b strings.Builder
+	for _, t := range times {
+		fmt.Fprintf(&b, "• %s (<t:%d:R>)\n", t.Format("Mon 2 Jan 15:04 MST"), t.Unix())
+	}
+	return b.String()
+}
+
+// resolveChannelID turns a channel mention ("<#123>"), "#name" or raw ID
+// into the ID of a channel in the guild
+func resolveChannelID(s *discordgo.Session, guildID, input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "<#") && strings.HasSuffix(input, ">") {
+		return input[2 : len(input)-1], nil
+	}
+	if _, err := strconv.ParseUint(input, 10, 64); err == nil {
+		return input, nil
+	}
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return "", fmt.Errorf("could not look up channels: %w", err)
+	}
+	name := strings.TrimPrefix(input, "#")
+	for _, c := range channels {
+		if strings.EqualFold(c.Name, name) {
+			return c.ID, nil
+		}
 	}
+	return "", fmt.Errorf("no channel named %q", name)
 }
 
 func handleDaily(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
-	// Ensure invoked in main channel, not inside a thread
+	// Ensure invoked in a main channel, or in a post of a forum channel
+	// (forums have nowhere else to run commands), which then gets the daily
 	channel, err := s.Channel(i.ChannelID)
-	if err != nil || channel.IsThread() {
+	if err == nil && channel.IsThread() {
+		channel, err = lookupChannel(s, channel.ParentID)
+		if err == nil && channel.Type != discordgo.ChannelTypeGuildForum {
+			err = errors.New("not a forum post")
+		}
+	}
+	if err != nil {
 		respond(s, i, "Please use this command in a main channel.")
 		return
 	}
 
-	// Create a thread for the user
-	threadName := fmt.Sprintf("%s's Daily Thread", i.Member.User.Username)
-	thread, err := s.ThreadStart(i.ChannelID, threadName, discordgo.ChannelTypeGuildPublicThread, 1440)
+	// Determine today's problem from the channel's schedule, in its zone
+	cfg := scheduleForChannel(i.GuildID, channel.ID)
+	name, collections, loc := "", []string(nil), time.UTC
+	if cfg != nil {
+		name, collections = cfg.Name, cfg.Collections
+		if tr, err := scheduler.ConfigTrigger(*cfg); err == nil {
+			loc = tr.Loc()
+		}
+	}
+	now := time.Now().In(loc)
+	prob, err := selector.ForDay(i.GuildID, name, collections, now)
+	var noDaily *daily.NoDailyError
+	if errors.As(err, &noDaily) {
+		respond(s, i, fmt.Sprintf("No daily today: %v.", noDaily))
+		return
+	}
 	if err != nil {
-		respondError(s, i, "could not create thread")
+		respondError(s, i, fmt.Sprintf("could not pick today's problem: %v", err))
 		return
 	}
 
-	// Acknowledge interaction
-	respond(s, i, "Daily practice thread created!")
-
-	// Determine today's problem index deterministically
-	days := time.Now().UTC().Unix() / 86400
-	problems := pg.Problems
-	count := len(problems)
-	if count == 0 {
-		respondError(s, i, "no problems available")
+	// Guilds with shared threads send everyone to the day's thread
+	mode, err := postRepo.ThreadMode(i.GuildID)
+	if err != nil {
+		log.Printf("failed to get thread mode, using personal threads: %v", err)
+	}
+	if mode == repo.ThreadsShared {
+		joinSharedDaily(s, i, channel.ID, name, prob, now, cfg)
 		return
 	}
-	idx := int(days % int64(count))
-	prob := problems[idx]
 
-	// Render problem image
-	imgPath, err := parser.RenderProblem(prob, "./out", 800, 40)
+	// Creating the thread and rendering the board can take a few seconds,
+	// so acknowledge first
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
 	if err != nil {
-		respondError(s, i, fmt.Sprintf("failed to render problem: %v", err))
+		respondError(s, i, "could not start daily")
+		return
+	}
+
+	// Create a thread or forum post for the user and post the problem in it
+	threadName := fmt.Sprintf("%s's Daily Thread", i.Member.User.Username)
+	if _, err := postDaily(s, channel.ID, threadName, prob, now, cfg, false); err != nil {
+		followupError(s, i, err.Error())
 		return
 	}
+	s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: "Daily practice thread created!",
+	})
+}
 
-	// Open image file for sending
-	file, err := os.Open(imgPath)
+// joinSharedDaily adds the member to the schedule's shared thread for day,
+// posting the daily first if nobody has yet
+func joinSharedDaily(s *discordgo.Session, i *discordgo.InteractionCreate, channelID, schedule string, prob *parser.GoProblem, day time.Time, cfg *repo.DailyConfig) {
+	// Another member may be posting the daily, so acknowledge before
+	// waiting on them
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
 	if err != nil {
-		respondError(s, i, fmt.Sprintf("failed to open image: %v", err))
+		respondError(s, i, "could not start daily")
+		return
+	}
+
+	sharedThreads.Lock()
+	defer sharedThreads.Unlock()
+
+	var threadID, msg string
+	existing, err := postRepo.SharedPost(i.GuildID, schedule, day.Format(time.DateOnly))
+	switch {
+	case err == nil:
+		threadID, msg = existing.ThreadID, "Today's daily is in <#%s>, and you've been added to it."
+	case err == sql.ErrNoRows:
+		thread, err := postDaily(s, channelID, dailyThreadName(day, schedule), prob, day, cfg, true)
+		if err != nil {
+			followupError(s, i, err.Error())
+			return
+		}
+		threadID, msg = thread.ID, "Today's daily is up in <#%s>!"
+	default:
+		followupError(s, i, "could not look up today's thread: "+err.Error())
 		return
 	}
-	defer file.Close()
 
-	// Send problem image to thread
-	if _, err := s.ChannelFileSend(thread.ID, filepath.Base(imgPath), file); err != nil {
-		respondError(s, i, fmt.Sprintf("failed to send image: %v", err))
+	if err := s.ThreadMemberAdd(threadID, i.Member.User.ID); err != nil {
+		log.Printf("failed to add %s to thread %s: %v", i.Member.User.ID, threadID, err)
 	}
+	s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: fmt.Sprintf(msg, threadID),
+	})
 }
 
-func handleEditDaily(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	isStaff := false
-	var staffID string
+// dailyThreadName names the thread of a schedule's daily, e.g.
+// "Daily Problem 2025-06-01 (advanced)"
+func dailyThreadName(day time.Time, schedule string) string {
+	name := "Daily Problem " + day.Format(time.DateOnly)
+	if schedule != "" && schedule != repo.DefaultSchedule {
+		name += " (" + schedule + ")"
+	}
+	return name
+}
 
-	config, err := dailyRepo.GetConfig(i.GuildID)
-	if err != nil && err != sql.ErrNoRows {
-		respondError(s, i, "error occured retreiving settings from db: "+err.Error())
-		return
+// postDaily posts the daily for day in channelID: as a forum post in a
+// forum channel, otherwise in a new thread. Either falls back to a text
+// board if the image can't be sent. cfg is the schedule the daily belongs
+// to, if any, and decides when it is revealed; shared marks the thread as
+// the canonical one for the schedule and day.
+func postDaily(s *discordgo.Session, channelID, threadName string, prob *parser.GoProblem, day time.Time, cfg *repo.DailyConfig, shared bool) (*discordgo.Channel, error) {
+	channel, err := lookupChannel(s, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find channel: %w", err)
+	}
+	schedule := ""
+	if cfg != nil {
+		schedule = cfg.Name
+	}
+	msg, err := dailyMessage(channel.GuildID, prob, day, schedule)
+	if err != nil {
+		log.Printf("failed to build daily, sending text board instead: %v", err)
 	}
 
-	if err == sql.ErrNoRows {
-		config = &repo.DailyConfig{}
+	if channel.Type == discordgo.ChannelTypeGuildForum {
+		post, err := startForumPost(s, channel, threadName, prob, msg)
+		if err != nil {
+			return nil, err
+		}
+		// a forum post's starter message shares its ID
+		trackDaily(post, post.ID, schedule, prob, day, cfg, shared)
+		return post, nil
 	}
 
-	guild, err := s.Guild(i.GuildID)
+	thread, err := s.ThreadStart(channelID, threadName, discordgo.ChannelTypeGuildPublicThread, 1440)
 	if err != nil {
-		respondError(s, i, "an internal server error occured getting the guild information")
-		return
+		return nil, fmt.Errorf("could not create thread: %w", err)
+	}
+	messageID, err := sendDaily(s, thread.ID, prob, msg)
+	trackDaily(thread, messageID, schedule, prob, day, cfg, shared)
+	if err != nil {
+		return thread, fmt.Errorf("failed to send problem: %w", err)
 	}
+	return thread, nil
+}
 
-	for _, role := range guild.Roles {
-		if role.Name == "Staff" {
-			staffID = role.ID
+// sendDaily sends the daily's message in its thread, falling back to a text
+// board, and returns the ID of the message sent
+func sendDaily(s *discordgo.Session, threadID string, prob *parser.GoProblem, msg *discordgo.MessageSend) (string, error) {
+	if msg != nil {
+		sent, err := s.ChannelMessageSendComplex(threadID, msg)
+		if err == nil {
+			return sent.ID, nil
 		}
+		log.Printf("failed to send daily, sending text board instead: %v", err)
 	}
-	log.Printf("%s roles are: %v", i.Member.User.GlobalName, i.Member.Roles)
-	for _, id := range i.Member.Roles {
-		if id == staffID {
-			isStaff = true
+	sent, err := sendTextBoard(s, threadID, prob)
+	if err != nil {
+		return "", err
+	}
+	return sent.ID, nil
+}
+
+// trackDaily records a new daily post, so answers in its thread are graded
+// and it shows in the history, and schedules its reveal
+func trackDaily(thread *discordgo.Channel, messageID, schedule string, prob *parser.GoProblem, day time.Time, cfg *repo.DailyConfig, shared bool) {
+	record := repo.DailyPost{
+		ThreadID:  thread.ID,
+		MessageID: messageID,
+		GuildID:   thread.GuildID,
+		Schedule:  schedule,
+		Day:       day.Format(time.DateOnly),
+		ProblemID: prob.ID,
+		Shared:    shared,
+		PostedAt:  time.Now(),
+	}
+	if err := postRepo.RecordPost(record); err != nil {
+		log.Printf("failed to record thread %s: %v", thread.ID, err)
+	}
+	if err := scheduleReveal(thread, prob, cfg); err != nil {
+		log.Printf("failed to schedule reveal for thread %s: %v", thread.ID, err)
+	}
+}
+
+// lookupChannel returns a channel from the state cache, or fetches it
+func lookupChannel(s *discordgo.Session, channelID string) (*discordgo.Channel, error) {
+	if channel, err := s.State.Channel(channelID); err == nil {
+		return channel, nil
+	}
+	return s.Channel(channelID)
+}
+
+// postScheduledDaily posts the day's problem in the schedule's channel and
+// queues it for the schedule's DM subscribers
+func postScheduledDaily(s *discordgo.Session) scheduler.PostFunc {
+	return func(cfg repo.DailyConfig, at time.Time) error {
+		prob, err := selector.ForDay(cfg.GuildID, cfg.Name, cfg.Collections, at)
+		var noDaily *daily.NoDailyError
+		if errors.As(err, &noDaily) {
+			return &scheduler.SkipError{Reason: noDaily.Error()}
+		}
+		if err != nil {
+			return err
+		}
+
+		// the scheduled post is the day's shared thread, unless /daily
+		// already started it
+		sharedThreads.Lock()
+		defer sharedThreads.Unlock()
+		if existing, err := postRepo.SharedPost(cfg.GuildID, cfg.Name, at.Format(time.DateOnly)); err == nil {
+			log.Printf("daily for guild %s already posted in thread %s", cfg.GuildID, existing.ThreadID)
+			queueDMs(cfg, at, existing.ProblemID)
+			return nil
+		} else if err != sql.ErrNoRows {
+			return err
 		}
+		thread, err := postDaily(s, cfg.ChannelID, dailyThreadName(at, cfg.Name), prob, at, &cfg, true)
+		if thread != nil {
+			queueDMs(cfg, at, prob.ID)
+		}
+		if err != nil && thread != nil {
+			// the thread is up, so retrying would post a second one
+			log.Printf("daily for guild %s posted with errors: %v", cfg.GuildID, err)
+			return nil
+		}
+		return err
 	}
+}
 
-	if !isStaff {
-		respondError(s, i, fmt.Sprintf("not a staff memeber"))
+// scheduleForChannel returns the guild's schedule posting in channelID,
+// falling back to the default schedule, or nil if the guild has neither
+func scheduleForChannel(guildID, channelID string) *repo.DailyConfig {
+	schedules, err := dailyRepo.ListSchedules(guildID)
+	if err != nil {
+		log.Printf("failed to list schedules: %v", err)
+		return nil
+	}
+	var fallback *repo.DailyConfig
+	for i := range schedules {
+		if schedules[i].ChannelID == channelID {
+			return &schedules[i]
+		}
+		if schedules[i].Name == repo.DefaultSchedule {
+			fallback = &schedules[i]
+		}
+	}
+	return fallback
+}
+
+// guildLocation returns the zone of the guild's default schedule, or UTC
+func guildLocation(guildID string) *time.Location {
+	cfg, err := dailyRepo.GetConfig(guildID)
+	if err != nil {
+		return time.UTC
+	}
+	tr, err := scheduler.ConfigTrigger(*cfg)
+	if err != nil {
+		return time.UTC
+	}
+	return tr.Loc()
+}
+
+// scheduleLocation returns the zone of one of the guild's schedules, or the
+// guild's zone if it has no such schedule
+func scheduleLocation(guildID, schedule string) *time.Location {
+	cfg, err := dailyRepo.GetSchedule(guildID, schedule)
+	if err != nil {
+		return guildLocation(guildID)
+	}
+	tr, err := scheduler.ConfigTrigger(*cfg)
+	if err != nil {
+		return guildLocation(guildID)
+	}
+	return tr.Loc()
+}
+
+// sendTextBoard posts the problem as an emoji board, for when images can't be sent
+func sendTextBoard(s *discordgo.Session, channelID string, prob *parser.GoProblem) (*discordgo.Message, error) {
+	msg, err := textBoard(prob)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChannelMessageSend(channelID, msg)
+}
+
+// textBoard renders the problem as an emoji board, titled if it fits in a message
+func textBoard(prob *parser.GoProblem) (string, error) {
+	board, err := parser.RenderText(prob, parser.TextEmoji)
+	if err != nil {
+		return "", err
+	}
+	if withName := fmt.Sprintf("**%s**\n%s", prob.Name, board); len([]rune(withName)) <= parser.DiscordMessageLimit {
+		return withName, nil
+	}
+	return board, nil
+}
+
+func handleEditDaily(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	config, err := dailyRepo.GetConfig(i.GuildID)
+	if err != nil && err != sql.ErrNoRows {
+		respondError(s, i, "error occured retreiving settings from db: "+err.Error())
+		return
+	}
+
+	if err == sql.ErrNoRows {
+		config = &repo.DailyConfig{}
+	}
+
+	if !requireStaff(s, i) {
 		return
 	}
 
@@ -252,12 +689,12 @@ func handleEditDaily(s *discordgo.Session, i *discordgo.InteractionCreate) {
 					Components: []discordgo.MessageComponent{
 						discordgo.TextInput{
 							CustomID:    "daily-time",
-							Label:       "time for dailies?",
+							Label:       "Daily time (HH:MM or cron, and time zone)",
 							Style:       discordgo.TextInputShort,
-							Placeholder: "Daily start time (HH:MM) tz",
-							Value:       config.TimeHHMM,
+							Placeholder: "08:00 Europe/Berlin or 30 7 * * 1-5; 0 10 * * sat +02:00",
+							Value:       strings.TrimSpace(config.TimeHHMM + " " + config.Timezone),
 							Required:    true,
-							MaxLength:   10,
+							MaxLength:   200,
 							MinLength:   4,
 						},
 					},
@@ -286,6 +723,36 @@ func handleEditDaily(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 }
 
+// requireStaff checks the invoking member has the guild's "Staff" role,
+// responding with an error when they don't
+func requireStaff(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	if i.Member == nil {
+		respondError(s, i, "this command only works in a server")
+		return false
+	}
+	guild, err := s.Guild(i.GuildID)
+	if err != nil {
+		respondError(s, i, "an internal server error occured getting the guild information")
+		return false
+	}
+
+	var staffID string
+	for _, role := range guild.Roles {
+		if role.Name == "Staff" {
+			staffID = role.ID
+		}
+	}
+	log.Printf("%s roles are: %v", i.Member.User.GlobalName, i.Member.Roles)
+	for _, id := range i.Member.Roles {
+		if id == staffID {
+			return true
+		}
+	}
+
+	respondError(s, i, "not a staff member")
+	return false
+}
+
 func respond(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -293,6 +760,13 @@ func respond(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
 	})
 }
 
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: msg, Flags: discordgo.MessageFlagsEphemeral},
+	})
+}
+
 func respondError(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
 	log.Print(msg)
 	respond(s, i, msg)
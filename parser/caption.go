@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+)
+
+const (
+	fontPath     = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+	boldFontPath = "/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf"
+)
+
+// caption is the text block drawn underneath the board
+type caption struct {
+	title     string
+	lines     []string
+	toMove    string
+	titleFace font.Face
+	bodyFace  font.Face
+	pad       float64
+	titleH    float64
+	bodyH     float64
+}
+
+// layoutCaption sizes the fonts to the image width and wraps the details
+// (collection, problem number, difficulty) to fit on the image.
+func layoutCaption(p *GoProblem, width int) *caption {
+	c := &caption{
+		title:     p.Prompt(),
+		toMove:    p.ToMove,
+		titleFace: loadFace(boldFontPath, max(float64(width)/32, 9)),
+		bodyFace:  loadFace(fontPath, max(float64(width)/44, 8)),
+		pad:       max(float64(width)/40, 6),
+	}
+
+	dc := gg.NewContext(width, 1)
+	if c.titleFace != nil {
+		dc.SetFontFace(c.titleFace)
+	}
+	c.titleH = dc.FontHeight() * 1.4
+	if c.bodyFace != nil {
+		dc.SetFontFace(c.bodyFace)
+	}
+	c.bodyH = dc.FontHeight() * 1.4
+	if details := p.Details(); details != "" {
+		c.lines = dc.WordWrap(details, float64(width)-2*c.pad)
+	}
+	return c
+}
+
+// height is the vertical space the caption needs
+func (c *caption) height() float64 {
+	return 2*c.pad + c.titleH + float64(len(c.lines))*c.bodyH
+}
+
+// draw renders the caption with its top edge at y
+func (c *caption) draw(dc *gg.Context, y float64) {
+	y += c.pad
+
+	// to-move stone icon next to the title
+	r := c.titleH * 0.3
+	cx, cy := c.pad+r, y+c.titleH/2
+	fill := color.Color(color.Black)
+	if c.toMove == "W" {
+		fill = color.White
+	}
+	dc.DrawCircle(cx, cy, r)
+	dc.SetColor(fill)
+	dc.Fill()
+	dc.SetLineWidth(1)
+	dc.SetColor(color.Black)
+	dc.DrawCircle(cx, cy, r)
+	dc.Stroke()
+
+	if c.titleFace != nil {
+		dc.SetFontFace(c.titleFace)
+	}
+	dc.DrawStringAnchored(c.title, cx+2*r, cy, 0, 0.35)
+	y += c.titleH
+
+	if c.bodyFace != nil {
+		dc.SetFontFace(c.bodyFace)
+	}
+	for _, line := range c.lines {
+		dc.DrawStringAnchored(line, c.pad, y+c.bodyH/2, 0, 0.35)
+		y += c.bodyH
+	}
+}
+
+// Details joins the collection, problem number and difficulty, e.g.
+// "Cho Chikun's Encyclopedia ... · Problem 12 · Easy"
+func (p *GoProblem) Details() string {
+	var parts []string
+	if p.Collection != "" {
+		parts = append(parts, p.Collection)
+	}
+	if p.Number > 0 {
+		parts = append(parts, fmt.Sprintf("Problem %d", p.Number))
+	} else if p.Name != "" {
+		parts = append(parts, p.Name)
+	}
+	if p.Difficulty != "" {
+		parts = append(parts, strings.ToUpper(p.Difficulty[:1])+p.Difficulty[1:])
+	}
+	return strings.Join(parts, " · ")
+}
+
+// loadFace loads a TrueType font, returning nil so gg's built-in face is
+// used when the font isn't installed
+func loadFace(path string, points float64) font.Face {
+	face, err := gg.LoadFontFace(path, points)
+	if err != nil {
+		return nil
+	}
+	return face
+}
+
+// LoadFont loads the caption font, bold or regular, for other images drawn
+// with gg. It returns nil when the font isn't installed.
+func LoadFont(bold bool, points float64) font.Face {
+	if bold {
+		return loadFace(boldFontPath, points)
+	}
+	return loadFace(fontPath, points)
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/fogleman/gg"
+)
+
+// RenderDiagram draws only the part of the board around the stones on a
+// white background, for printed worksheets. Lines that continue past the
+// cropped area run out to the image edge. linePx is the spacing between
+// lines. The crop covers the overlay's moves too, so a problem and its
+// answer come out the same size.
+func RenderDiagram(p *GoProblem, ov *Overlay, linePx int) (image.Image, error) {
+	board, err := stoneGrid(p)
+	if err != nil {
+		return nil, err
+	}
+	area := board
+	if ov != nil {
+		for _, m := range ov.Moves {
+			if x, y, err := sgfToIndex(m.Coord); err == nil {
+				area[x][y] = 'B'
+			}
+		}
+		if err := ov.apply(&board); err != nil {
+			return nil, err
+		}
+	}
+	x0, y0, x1, y1 := cropBounds(area, 2)
+
+	step := float64(linePx)
+	margin := step * 0.8
+	w := int(2*margin + float64(x1-x0)*step)
+	h := int(2*margin + float64(y1-y0)*step)
+	dc := gg.NewContext(w, h)
+	dc.SetColor(color.White)
+	dc.Clear()
+
+	// point (0, 0) sits off-image when the crop doesn't start at the edge
+	ox := margin - float64(x0)*step
+	oy := margin - float64(y0)*step
+
+	// Grid, running off the image wherever the board continues
+	dc.SetColor(color.Black)
+	top, bottom := oy+float64(y0)*step, oy+float64(y1)*step
+	if y0 > 0 {
+		top = 0
+	}
+	if y1 < 18 {
+		bottom = float64(h)
+	}
+	left, right := ox+float64(x0)*step, ox+float64(x1)*step
+	if x0 > 0 {
+		left = 0
+	}
+	if x1 < 18 {
+		right = float64(w)
+	}
+	for x := x0; x <= x1; x++ {
+		dc.SetLineWidth(edgeWidth(x, step))
+		dc.DrawLine(ox+float64(x)*step, top, ox+float64(x)*step, bottom)
+		dc.Stroke()
+	}
+	for y := y0; y <= y1; y++ {
+		dc.SetLineWidth(edgeWidth(y, step))
+		dc.DrawLine(left, oy+float64(y)*step, right, oy+float64(y)*step)
+		dc.Stroke()
+	}
+
+	// Star points
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			if isStarPoint(x) && isStarPoint(y) && board[x][y] == 0 {
+				dc.DrawCircle(ox+float64(x)*step, oy+float64(y)*step, step*0.1)
+				dc.Fill()
+			}
+		}
+	}
+
+	// Stones
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			if board[x][y] == 0 {
+				continue
+			}
+			fill := color.Black
+			if board[x][y] == 'W' {
+				fill = color.White
+			}
+			cx, cy := ox+float64(x)*step, oy+float64(y)*step
+			dc.DrawCircle(cx, cy, step*0.46)
+			dc.SetColor(fill)
+			dc.Fill()
+			dc.SetLineWidth(step * 0.05)
+			dc.SetColor(color.Black)
+			dc.DrawCircle(cx, cy, step*0.46)
+			dc.Stroke()
+		}
+	}
+
+	if ov != nil {
+		ov.drawMarks(dc, board, ox, oy, step, color.White)
+	}
+	return dc.Image(), nil
+}
+
+// edgeWidth draws the board's outer lines heavier than the inner ones
+func edgeWidth(i int, step float64) float64 {
+	if i == 0 || i == 18 {
+		return step * 0.08
+	}
+	return step * 0.04
+}
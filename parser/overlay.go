@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+
+	"github.com/fogleman/gg"
+)
+
+// MarkKind selects how a Mark is drawn
+type MarkKind int
+
+const (
+	MarkCorrect MarkKind = iota // green ✓
+	MarkWrong                   // red ✗
+	MarkCircle                  // outlined circle, e.g. the last move
+	MarkLabel                   // text such as a move number
+)
+
+// Mark annotates a single point on the board
+type Mark struct {
+	Coord string
+	Kind  MarkKind
+	Label string
+}
+
+// Overlay holds extra stones and marks drawn on top of a problem, so answers
+// and solutions can be shown without modifying the GoProblem itself
+type Overlay struct {
+	Moves []Move // played in order, removing any stones they capture
+	Marks []Mark
+}
+
+// AnswerOverlay marks a graded line: the solver's last stone gets a ✓ or ✗
+// (a circle when ungraded) and every other stone is numbered in play order
+func AnswerOverlay(line []Move, verdict Verdict) Overlay {
+	ov := Overlay{Moves: line}
+	last := -1
+	if len(line) > 0 {
+		last = 0
+		for i, m := range line {
+			if m.Color == line[0].Color {
+				last = i
+			}
+		}
+	}
+	for i, m := range line {
+		if i != last {
+			if len(line) > 1 {
+				ov.Marks = append(ov.Marks, Mark{Coord: m.Coord, Kind: MarkLabel, Label: fmt.Sprint(i + 1)})
+			}
+			continue
+		}
+		kind := MarkCircle
+		switch verdict {
+		case VerdictCorrect:
+			kind = MarkCorrect
+		case VerdictWrong:
+			kind = MarkWrong
+		}
+		ov.Marks = append(ov.Marks, Mark{Coord: m.Coord, Kind: kind})
+	}
+	return ov
+}
+
+// SolutionOverlay numbers every move of a solution line in play order
+func SolutionOverlay(line []Move) Overlay {
+	ov := Overlay{Moves: line}
+	for i, m := range line {
+		ov.Marks = append(ov.Marks, Mark{Coord: m.Coord, Kind: MarkLabel, Label: fmt.Sprint(i + 1)})
+	}
+	return ov
+}
+
+// RenderOverlay draws the problem with the overlay applied and saves it as
+// a PNG named after the problem and the overlay's contents
+func RenderOverlay(p *GoProblem, ov Overlay, outputDir string, boardsizePx, marginPx int) (string, error) {
+	dc, err := drawProblem(p, &ov, boardsizePx, marginPx)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New32a()
+	fmt.Fprint(h, ov.Moves, ov.Marks)
+	return SavePNG(dc, outputDir, fmt.Sprintf("%s-%08x", p.fileStem(), h.Sum32()))
+}
+
+// CheckMoves reports the first move that lands on an occupied point once
+// the moves before it (and their captures) have been played
+func (p *GoProblem) CheckMoves(moves []Move) error {
+	board, err := stoneGrid(p)
+	if err != nil {
+		return err
+	}
+	ov := Overlay{Moves: moves}
+	return ov.apply(&board)
+}
+
+// apply plays the overlay's moves onto board
+func (ov *Overlay) apply(board *[19][19]byte) error {
+	for _, m := range ov.Moves {
+		x, y, err := sgfToIndex(m.Coord)
+		if err != nil {
+			return err
+		}
+		if board[x][y] != 0 {
+			return fmt.Errorf("%s is already occupied", CoordName(m.Coord))
+		}
+		stone := byte('B')
+		if m.Color == "W" {
+			stone = 'W'
+		}
+		playMove(board, stone, x, y)
+	}
+	return nil
+}
+
+// drawMarks draws the overlay's marks over the stones already on dc, with
+// point (0, 0) at (ox, oy); background is used to clear lines behind labels
+func (ov *Overlay) drawMarks(dc *gg.Context, board [19][19]byte, ox, oy, step float64, background color.Color) {
+	if face := loadFace(boldFontPath, step*0.45); face != nil {
+		dc.SetFontFace(face)
+	}
+	for _, m := range ov.Marks {
+		x, y, err := sgfToIndex(m.Coord)
+		if err != nil {
+			continue
+		}
+		cx := ox + float64(x)*step
+		cy := oy + float64(y)*step
+		r := step * 0.25
+
+		// marks on stones contrast with the stone, marks on empty points with the wood
+		ink := color.Color(color.Black)
+		if board[x][y] == 'B' {
+			ink = color.White
+		}
+
+		switch m.Kind {
+		case MarkCorrect:
+			dc.SetColor(color.RGBA{R: 40, G: 170, B: 70, A: 255})
+			dc.SetLineWidth(step * 0.12)
+			dc.MoveTo(cx-r, cy)
+			dc.LineTo(cx-r*0.3, cy+r*0.7)
+			dc.LineTo(cx+r, cy-r*0.8)
+			dc.Stroke()
+		case MarkWrong:
+			dc.SetColor(color.RGBA{R: 210, G: 40, B: 40, A: 255})
+			dc.SetLineWidth(step * 0.12)
+			dc.DrawLine(cx-r, cy-r, cx+r, cy+r)
+			dc.DrawLine(cx-r, cy+r, cx+r, cy-r)
+			dc.Stroke()
+		case MarkCircle:
+			dc.SetColor(ink)
+			dc.SetLineWidth(step * 0.07)
+			dc.DrawCircle(cx, cy, r)
+			dc.Stroke()
+		case MarkLabel:
+			if board[x][y] == 0 {
+				// clear the grid lines behind the label
+				dc.SetColor(background)
+				dc.DrawCircle(cx, cy, step*0.35)
+				dc.Fill()
+			}
+			dc.SetColor(ink)
+			dc.DrawStringAnchored(m.Label, cx, cy, 0.5, 0.35)
+		}
+	}
+}
+
+// playMove places a stone and removes any opposing groups left without liberties
+func playMove(board *[19][19]byte, stone byte, x, y int) {
+	board[x][y] = stone
+	for _, nb := range neighbours(x, y) {
+		nx, ny := nb[0], nb[1]
+		if c := board[nx][ny]; c != 0 && c != stone {
+			if group, free := groupAt(board, nx, ny); !free {
+				for _, pt := range group {
+					board[pt[0]][pt[1]] = 0
+				}
+			}
+		}
+	}
+}
+
+// groupAt returns the chain of stones containing (x, y) and whether it has a liberty
+func groupAt(board *[19][19]byte, x, y int) ([][2]int, bool) {
+	stone := board[x][y]
+	seen := map[[2]int]bool{{x, y}: true}
+	stack := [][2]int{{x, y}}
+	var group [][2]int
+	free := false
+	for len(stack) > 0 {
+		pt := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		group = append(group, pt)
+		for _, nb := range neighbours(pt[0], pt[1]) {
+			switch c := board[nb[0]][nb[1]]; {
+			case c == 0:
+				free = true
+			case c == stone && !seen[nb]:
+				seen[nb] = true
+				stack = append(stack, nb)
+			}
+		}
+	}
+	return group, free
+}
+
+// neighbours returns the on-board points orthogonally adjacent to (x, y)
+func neighbours(x, y int) [][2]int {
+	var out [][2]int
+	for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		nx, ny := x+d[0], y+d[1]
+		if nx >= 0 && nx < 19 && ny >= 0 && ny < 19 {
+			out = append(out, [2]int{nx, ny})
+		}
+	}
+	return out
+}
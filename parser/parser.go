@@ -5,24 +5,36 @@ import (
 	"fmt"
 	"image/color"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/fogleman/gg"
 )
 
 type GoProblem struct {
-	Name  string
-	Black []string
-	White []string
+	ID           string
+	Name         string
+	Collection   string // display title, e.g. "Cho Chikun's Encyclopedia ..."
+	CollectionID string // file stem, e.g. "cho-easy"
+	Number       int
+	Difficulty   string
+	ToMove       string // "B" or "W"
+	Black        []string
+	White        []string
+	Solution     []*SolutionNode
 }
 
 type GoParser struct {
 	Problems []*GoProblem
 }
 
-// LoadProblems reads an SGF file line-by-line, parsing each as a GoProblem
+// LoadProblems reads an SGF file line-by-line, parsing each as a GoProblem.
+// A leading comment-only node names the collection; problem IDs and the
+// difficulty come from the file name (e.g. "cho-easy.sgf" → "cho-easy-12", "easy").
 func (p *GoParser) LoadProblems(fileLocation string) error {
 	file, err := os.Open(fileLocation)
 	if err != nil {
@@ -30,6 +42,11 @@ func (p *GoParser) LoadProblems(fileLocation string) error {
 	}
 	defer file.Close()
 
+	stem := strings.TrimSuffix(filepath.Base(fileLocation), filepath.Ext(fileLocation))
+	difficulty := difficultyFromStem(stem)
+	collection := stem
+	count := 0
+
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -38,30 +55,121 @@ func (p *GoParser) LoadProblems(fileLocation string) error {
 			log.Printf("skipping line: %s: %v", line, err)
 			continue
 		}
+		if len(prob.Black) == 0 && len(prob.White) == 0 {
+			// collection header, e.g. "(;C[Cho Chikun's Encyclopedia ...]"
+			collection = prob.Name
+			continue
+		}
+		count++
+		prob.Number = count
+		if m := numberRe.FindStringSubmatch(prob.Name); m != nil {
+			prob.Number, _ = strconv.Atoi(m[1])
+		}
+		prob.ID = fmt.Sprintf("%s-%d", stem, prob.Number)
+		prob.Collection = collection
+		prob.CollectionID = stem
+		prob.Difficulty = difficulty
 		p.Problems = append(p.Problems, prob)
 	}
 	return scanner.Err()
 }
 
-// ParseSGFLine extracts stones and comment, returning a GoProblem or error
+// Problem returns the loaded problem with the given ID, or nil
+func (p *GoParser) Problem(id string) *GoProblem {
+	if id == "" {
+		return nil
+	}
+	for _, prob := range p.Problems {
+		if prob.ID == id {
+			return prob
+		}
+	}
+	return nil
+}
+
+// Collections returns the IDs of the loaded collections in load order
+func (p *GoParser) Collections() []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, prob := range p.Problems {
+		if prob.CollectionID != "" && !seen[prob.CollectionID] {
+			seen[prob.CollectionID] = true
+			ids = append(ids, prob.CollectionID)
+		}
+	}
+	return ids
+}
+
+// ParseSGFLine extracts stones and comment, returning a GoProblem or error.
+// Variations after the root node are read as the problem's solution tree.
 func (p *GoParser) ParseSGFLine(line string) (*GoProblem, error) {
-	black := extractCoords(blackRunRe, line)
-	white := extractCoords(whiteRunRe, line)
-	comment := extractComment(commentRe, line)
+	root := rootSegment(line)
+	black := extractCoords(blackRunRe, root)
+	white := extractCoords(whiteRunRe, root)
+	comment := extractComment(commentRe, root)
 
 	if len(black) == 0 && len(white) == 0 && comment == "" {
 		return nil, fmt.Errorf("no SGF properties found in line")
 	}
 
-	return &GoProblem{Name: comment, Black: black, White: white}, nil
+	toMove := "B"
+	if m := playerRe.FindStringSubmatch(root); m != nil {
+		toMove = m[1]
+	}
+
+	return &GoProblem{
+		Name:     comment,
+		ToMove:   toMove,
+		Black:    black,
+		White:    white,
+		Solution: parseSolution(line),
+	}, nil
 }
 
-// RenderProblem draws the GoProblem onto a 19×19 board PNG.
-// boardsizePx is the total image width/height in pixels (e.g. 800).
+// Prompt describes the task, e.g. "Black to live" or "White to play"
+func (p *GoProblem) Prompt() string {
+	color := "Black"
+	if p.ToMove == "W" {
+		color = "White"
+	}
+	name := strings.ToLower(p.Name)
+	switch {
+	case strings.Contains(name, "kill"):
+		return color + " to kill"
+	case strings.Contains(name, "live"):
+		return color + " to live"
+	}
+	return color + " to play"
+}
+
+// RenderProblem draws the GoProblem onto a 19×19 board PNG with a caption
+// underneath showing the side to move, collection, number and difficulty.
+// boardsizePx is the width and the height of the board area in pixels (e.g. 800).
 // marginPx leaves blank space around the outer lines (e.g. 40).
 func RenderProblem(p *GoProblem, outputDir string, boardsizePx, marginPx int) (string, error) {
-	dc := gg.NewContext(boardsizePx, boardsizePx)
-	dc.SetColor(color.RGBA{R: 240, G: 200, B: 150, A: 255}) // light wood background
+	dc, err := drawProblem(p, nil, boardsizePx, marginPx)
+	if err != nil {
+		return "", err
+	}
+	return SavePNG(dc, outputDir, p.fileStem())
+}
+
+// drawProblem renders the board, stones, optional overlay and caption
+func drawProblem(p *GoProblem, ov *Overlay, boardsizePx, marginPx int) (*gg.Context, error) {
+	board, err := stoneGrid(p)
+	if err != nil {
+		return nil, err
+	}
+	if ov != nil {
+		if err := ov.apply(&board); err != nil {
+			return nil, err
+		}
+	}
+
+	capt := layoutCaption(p, boardsizePx)
+	captionTop := float64(boardsizePx - marginPx/2)
+	dc := gg.NewContext(boardsizePx, int(math.Ceil(captionTop+capt.height())))
+	dc.SetColor(woodColor)
 	dc.Clear()
 
 	// Draw grid
@@ -87,58 +195,72 @@ func RenderProblem(p *GoProblem, outputDir string, boardsizePx, marginPx int) (s
 		}
 	}
 
-	// Helper to draw stones
-	drawStone := func(coord string, fill color.Color) error {
-		x, y, err := sgfToIndex(coord)
-		if err != nil {
-			return err
-		}
-		cx := float64(marginPx) + float64(x)*step
-		cy := float64(marginPx) + float64(y)*step
-		stoneR := step * 0.4
-		dc.DrawCircle(cx, cy, stoneR)
-		dc.SetColor(fill)
-		dc.Fill()
-		dc.SetLineWidth(1)
-		dc.SetColor(color.Black)
-		dc.DrawCircle(cx, cy, stoneR)
-		dc.Stroke()
-		return nil
-	}
-
 	// Place stones
-	for _, c := range p.Black {
-		if err := drawStone(c, color.Black); err != nil {
-			return "", err
+	stoneR := step * 0.4
+	for x := range n {
+		for y := range n {
+			if board[x][y] == 0 {
+				continue
+			}
+			fill := color.Black
+			if board[x][y] == 'W' {
+				fill = color.White
+			}
+			cx := float64(marginPx) + float64(x)*step
+			cy := float64(marginPx) + float64(y)*step
+			dc.DrawCircle(cx, cy, stoneR)
+			dc.SetColor(fill)
+			dc.Fill()
+			dc.SetLineWidth(1)
+			dc.SetColor(color.Black)
+			dc.DrawCircle(cx, cy, stoneR)
+			dc.Stroke()
 		}
 	}
-	for _, c := range p.White {
-		if err := drawStone(c, color.White); err != nil {
-			return "", err
-		}
+
+	if ov != nil {
+		ov.drawMarks(dc, board, float64(marginPx), float64(marginPx), step, woodColor)
 	}
 
-	// Label problem name
-	dc.SetColor(color.Black)
-	dc.LoadFontFace("/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf", 14)
-	dc.DrawStringAnchored(p.Name, float64(boardsizePx)/2, float64(boardsizePx)-10, 0.5, 0.5)
+	// Caption below the board
+	capt.draw(dc, captionTop)
+	return dc, nil
+}
 
-	// Save image
-	filename := fmt.Sprintf("%s.png", sanitizeFilename(p.Name))
-	outPath := filepath.Join(outputDir, filename)
+// SavePNG writes dc to outputDir under a sanitized name and returns the path
+func SavePNG(dc *gg.Context, outputDir, name string) (string, error) {
+	outPath := PNGPath(outputDir, name)
 	if err := dc.SavePNG(outPath); err != nil {
 		return "", err
 	}
 	return outPath, nil
 }
 
+// PNGPath is where SavePNG writes an image with the given name
+func PNGPath(outputDir, name string) string {
+	return filepath.Join(outputDir, fmt.Sprintf("%s.png", sanitizeFilename(name)))
+}
+
+// fileStem names the rendered image, preferring the unique problem ID
+func (p *GoProblem) fileStem() string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.Name
+}
+
 // --------- Utilities ---------
 
+// woodColor is the light wood board background
+var woodColor = color.RGBA{R: 240, G: 200, B: 150, A: 255}
+
 var (
 	blackRunRe = regexp.MustCompile(`AB((?:\[[a-s]{2}\])+)`)
 	whiteRunRe = regexp.MustCompile(`AW((?:\[[a-s]{2}\])+)`)
 	coordRe    = regexp.MustCompile(`\[([a-s]{2})\]`)
 	commentRe  = regexp.MustCompile(`C\[(.*?)\]`)
+	playerRe   = regexp.MustCompile(`PL\[([BW])\]`)
+	numberRe   = regexp.MustCompile(`(?i)problem\s+(\d+)`)
 )
 
 // extractCoords finds a run of coords (e.g. "[cc][dd]...") and returns each coord
@@ -152,6 +274,34 @@ func extractCoords(runRe *regexp.Regexp, line string) []string {
 	return coords
 }
 
+// rootSegment returns the line up to the node after the root, skipping over
+// brackets so parentheses inside comments don't end the root early
+func rootSegment(line string) string {
+	depth, nodes := 0, 0
+	inValue := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case inValue && c == '\\':
+			i++
+		case c == '[':
+			inValue = true
+		case c == ']':
+			inValue = false
+		case !inValue && c == '(':
+			depth++
+			if depth > 1 {
+				return line[:i]
+			}
+		case !inValue && c == ';':
+			nodes++
+			if nodes > 1 {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
 // extractComment returns the first matched comment, or empty string
 func extractComment(re *regexp.Regexp, line string) string {
 	if m := re.FindStringSubmatch(line); m != nil {
@@ -173,6 +323,16 @@ func sgfToIndex(s string) (int, int, error) {
 	return x, y, nil
 }
 
+// difficultyFromStem returns the tier suffix of a collection file name ("cho-easy" → "easy")
+func difficultyFromStem(stem string) string {
+	tier := stem[strings.LastIndex(stem, "-")+1:]
+	switch tier {
+	case "easy", "medium", "hard":
+		return tier
+	}
+	return ""
+}
+
 // sanitizeFilename converts "Example problem" → "Example_problem"
 func sanitizeFilename(name string) string {
 	var out string
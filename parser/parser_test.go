@@ -3,7 +3,9 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestParseSimpleProblem(t *testing.T) {
@@ -146,3 +148,172 @@ func TestDumpExampleImage(t *testing.T) {
 	}
 	t.Logf("wrote example image to %s", imgPath)
 }
+
+func TestRenderTextCropsToCorner(t *testing.T) {
+	prob := &GoProblem{
+		Name:  "Corner",
+		Black: []string{"ab", "bb"},
+		White: []string{"ac"},
+	}
+	out, err := RenderText(prob, TextUnicode)
+	if err != nil {
+		t.Fatalf("RenderText returned error: %v", err)
+	}
+	if !strings.HasPrefix(out, "```\n") || !strings.HasSuffix(out, "```") {
+		t.Errorf("expected output wrapped in a code block, got %q", out)
+	}
+	lines := strings.Split(strings.Trim(out, "`\n"), "\n")
+	// ruler plus rows 19 down to 15
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 lines in cropped board, got %d:\n%s", len(lines), out)
+	}
+	if lines[0] != "   A B C D" {
+		t.Errorf("unexpected column ruler %q", lines[0])
+	}
+	if lines[2] != "18 ●─●─┼─┼" {
+		t.Errorf("unexpected row 18 %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], "17 ○") {
+		t.Errorf("expected white stone at A17, got %q", lines[3])
+	}
+}
+
+func TestRenderTextStaysUnderLimit(t *testing.T) {
+	// stones in opposite corners force the whole board to be drawn
+	prob := &GoProblem{
+		Black: []string{"aa", "ss"},
+		White: []string{"as", "sa"},
+	}
+	for _, style := range []TextStyle{TextUnicode, TextEmoji} {
+		out, err := RenderText(prob, style)
+		if err != nil {
+			t.Fatalf("RenderText returned error: %v", err)
+		}
+		if n := utf8.RuneCountInString(out); n > DiscordMessageLimit {
+			t.Errorf("style %d: expected at most %d characters, got %d", style, DiscordMessageLimit, n)
+		}
+		if !strings.Contains(out, "19 ") || !strings.Contains(out, " 1 ") {
+			t.Errorf("style %d: expected full row ruler, got:\n%s", style, out)
+		}
+	}
+}
+
+func TestLoadProblemsMetadata(t *testing.T) {
+	fileName := filepath.Join("..", "files", "cho-easy.sgf")
+	parser := GoParser{}
+	if err := parser.LoadProblems(fileName); err != nil {
+		t.Fatalf("unexpected problem occurred with loading problems: %v", err)
+	}
+	first := parser.Problems[0]
+	if first.ID != "cho-easy-1" || first.Number != 1 {
+		t.Errorf("expected first problem cho-easy-1, got %q (number %d)", first.ID, first.Number)
+	}
+	if first.Collection != "Cho Chikun's Encyclopedia of Life & Death (Vol 1)" {
+		t.Errorf("expected collection from header comment, got %q", first.Collection)
+	}
+	if first.Difficulty != "easy" {
+		t.Errorf("expected difficulty 'easy', got %q", first.Difficulty)
+	}
+	if first.Prompt() != "Black to play" {
+		t.Errorf("expected prompt 'Black to play', got %q", first.Prompt())
+	}
+}
+
+func TestParseSolutionTree(t *testing.T) {
+	sgf := "(;AB[ba][bb]AW[ca][cb]C[problem 1](;B[ab]C[wrong (too slow)];W[aa])(;B[aa];W[ab];B[ac]C[RIGHT]))"
+	parser := GoParser{}
+	problem, err := parser.ParseSGFLine(sgf)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if problem.Name != "problem 1" {
+		t.Errorf("expected root comment as name, got %q", problem.Name)
+	}
+	if len(problem.Solution) != 2 {
+		t.Fatalf("expected 2 first moves in solution, got %d", len(problem.Solution))
+	}
+	if problem.Solution[0].Correct || !problem.Solution[1].Correct {
+		t.Errorf("expected only the second variation to be correct")
+	}
+
+	verdict, line := problem.Grade([]string{"aa"})
+	if verdict != VerdictCorrect {
+		t.Errorf("expected aa to be correct, got %v", verdict)
+	}
+	if len(line) != 2 || line[1] != (Move{Color: "W", Coord: "ab"}) {
+		t.Errorf("expected white reply at ab, got %v", line)
+	}
+
+	verdict, line = problem.Grade([]string{"ab"})
+	if verdict != VerdictWrong || len(line) != 2 {
+		t.Errorf("expected ab to be wrong with a refutation, got %v %v", verdict, line)
+	}
+
+	if got := problem.CorrectLine(); len(got) != 3 || got[2].Coord != "ac" {
+		t.Errorf("unexpected correct line %v", got)
+	}
+}
+
+func TestGradeWithoutSolution(t *testing.T) {
+	problem := &GoProblem{Black: []string{"cc"}}
+	verdict, line := problem.Grade([]string{"dd", "ee"})
+	if verdict != VerdictUnknown {
+		t.Errorf("expected unknown verdict, got %v", verdict)
+	}
+	if len(line) != 1 || line[0] != (Move{Color: "B", Coord: "dd"}) {
+		t.Errorf("expected only the first move, got %v", line)
+	}
+}
+
+func TestParseCoord(t *testing.T) {
+	cases := map[string]string{"A19": "aa", "c17": "cc", "T1": "ss", "J10": "ij", "cc": "cc"}
+	for in, want := range cases {
+		got, err := ParseCoord(in)
+		if err != nil || got != want {
+			t.Errorf("ParseCoord(%q) = %q, %v; want %q", in, got, err, want)
+		}
+		if CoordName(got) != strings.ToUpper(in) && len(in) > 2 {
+			t.Errorf("CoordName(%q) = %q, want %q", got, CoordName(got), strings.ToUpper(in))
+		}
+	}
+	for _, in := range []string{"I5", "A20", "Z1", "hello"} {
+		if _, err := ParseCoord(in); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestCheckMovesCaptures(t *testing.T) {
+	// white stone in the corner with one liberty left at ba
+	problem := &GoProblem{Black: []string{"ab"}, White: []string{"aa"}}
+	moves := []Move{{Color: "B", Coord: "ba"}, {Color: "W", Coord: "aa"}}
+	if err := problem.CheckMoves(moves); err != nil {
+		t.Errorf("expected capture to free aa, got %v", err)
+	}
+	if err := problem.CheckMoves([]Move{{Color: "B", Coord: "ab"}}); err == nil {
+		t.Error("expected error for occupied point")
+	}
+}
+
+func TestRenderOverlay(t *testing.T) {
+	prob := &GoProblem{ID: "test-1", Black: []string{"dd"}, White: []string{"cc"}}
+	ov := AnswerOverlay([]Move{{Color: "B", Coord: "ee"}, {Color: "W", Coord: "ef"}}, VerdictWrong)
+	if len(ov.Marks) != 2 || ov.Marks[0].Kind != MarkWrong || ov.Marks[1].Label != "2" {
+		t.Errorf("unexpected marks %v", ov.Marks)
+	}
+	imgPath, err := RenderOverlay(prob, ov, t.TempDir(), 200, 20)
+	if err != nil {
+		t.Fatalf("RenderOverlay returned error: %v", err)
+	}
+	if _, err := os.Stat(imgPath); err != nil {
+		t.Errorf("expected image file at %s: %v", imgPath, err)
+	}
+	if len(prob.Black) != 1 || len(prob.White) != 1 {
+		t.Error("expected overlay to leave the problem unchanged")
+	}
+
+	sol := SolutionOverlay([]Move{{Color: "B", Coord: "ee"}, {Color: "W", Coord: "ef"}})
+	if len(sol.Marks) != 2 || sol.Marks[0].Label != "1" || sol.Marks[1].Label != "2" {
+		t.Errorf("expected every solution move numbered, got %v", sol.Marks)
+	}
+}
@@ -0,0 +1,306 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Move is a single stone played by "B" or "W" at an SGF coordinate
+type Move struct {
+	Color string
+	Coord string
+}
+
+// SolutionNode is one move in a problem's solution tree. Correct marks
+// moves that lie on a line ending in a "RIGHT"/"Correct" comment.
+type SolutionNode struct {
+	Move     Move
+	Comment  string
+	Correct  bool
+	Children []*SolutionNode
+}
+
+// Verdict is the outcome of grading an answer
+type Verdict int
+
+const (
+	// VerdictUnknown means the problem has no solution tree to grade against
+	VerdictUnknown Verdict = iota
+	VerdictCorrect
+	VerdictWrong
+)
+
+// Grade walks the solution tree with the solver's moves (SGF coords) and
+// returns the verdict along with the line played: the solver's moves
+// interleaved with the tree's replies to them. Problems without a solution
+// tree grade as VerdictUnknown with only the first move in the line.
+func (p *GoProblem) Grade(moves []string) (Verdict, []Move) {
+	if len(moves) == 0 {
+		return VerdictUnknown, nil
+	}
+	solver := "B"
+	if p.ToMove == "W" {
+		solver = "W"
+	}
+	if len(p.Solution) == 0 {
+		return VerdictUnknown, []Move{{Color: solver, Coord: moves[0]}}
+	}
+
+	var line []Move
+	level := p.Solution
+	for _, coord := range moves {
+		node := findMove(level, coord)
+		if node == nil {
+			return VerdictWrong, append(line, Move{Color: solver, Coord: coord})
+		}
+		line = append(line, node.Move)
+		if len(node.Children) > 0 {
+			line = append(line, node.Children[0].Move)
+		}
+		if !node.Correct {
+			return VerdictWrong, line
+		}
+		if len(node.Children) == 0 {
+			break // solved; ignore anything played after
+		}
+		level = node.Children[0].Children
+		if len(level) == 0 {
+			break
+		}
+	}
+	return VerdictCorrect, line
+}
+
+// CorrectLine returns the first correct sequence through the solution tree,
+// alternating solver moves and replies
+func (p *GoProblem) CorrectLine() []Move {
+	var line []Move
+	level := p.Solution
+	for len(level) > 0 {
+		var next *SolutionNode
+		for _, n := range level {
+			if n.Correct {
+				next = n
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		line = append(line, next.Move)
+		if len(next.Children) == 0 {
+			break
+		}
+		reply := next.Children[0]
+		line = append(line, reply.Move)
+		level = reply.Children
+	}
+	return line
+}
+
+// findMove returns the node in level played at coord, or nil
+func findMove(level []*SolutionNode, coord string) *SolutionNode {
+	for _, n := range level {
+		if n.Move.Coord == coord {
+			return n
+		}
+	}
+	return nil
+}
+
+// ParseCoord accepts a board point as "C17" (column letter skipping I, row
+// counted from the bottom) or as an SGF pair like "cc", returning the SGF form
+func ParseCoord(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 2 && s[0] >= 'a' && s[0] <= 's' && s[1] >= 'a' && s[1] <= 's' {
+		return s, nil
+	}
+	if len(s) < 2 || len(s) > 3 {
+		return "", fmt.Errorf("invalid coordinate %q", s)
+	}
+	col := strings.IndexByte(columnLetters, strings.ToUpper(s)[0])
+	row, err := strconv.Atoi(s[1:])
+	if col < 0 || err != nil || row < 1 || row > 19 {
+		return "", fmt.Errorf("invalid coordinate %q", s)
+	}
+	return string([]byte{'a' + byte(col), 'a' + byte(19-row)}), nil
+}
+
+// CoordName converts an SGF coordinate to the "C17" form shown to players
+func CoordName(sgf string) string {
+	x, y, err := sgfToIndex(sgf)
+	if err != nil {
+		return sgf
+	}
+	return fmt.Sprintf("%c%d", columnLetters[x], 19-y)
+}
+
+// --------- SGF game tree ---------
+
+// sgfNode is a parsed SGF node with its properties and variations
+type sgfNode struct {
+	props    map[string][]string
+	children []*sgfNode
+}
+
+// parseSolution reads the variations that follow the root node of an SGF
+// line. Lines without variations, or that fail to parse, have no solution.
+func parseSolution(line string) []*SolutionNode {
+	root, err := parseGameTree(line)
+	if err != nil {
+		return nil
+	}
+	var out []*SolutionNode
+	for _, child := range root.children {
+		if n := toSolution(child); n != nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// toSolution converts an SGF node and its descendants into solution nodes
+func toSolution(n *sgfNode) *SolutionNode {
+	var move Move
+	switch {
+	case len(n.props["B"]) > 0:
+		move = Move{Color: "B", Coord: n.props["B"][0]}
+	case len(n.props["W"]) > 0:
+		move = Move{Color: "W", Coord: n.props["W"][0]}
+	default:
+		return nil
+	}
+	if _, _, err := sgfToIndex(move.Coord); err != nil {
+		return nil
+	}
+
+	node := &SolutionNode{Move: move, Comment: strings.Join(n.props["C"], "\n")}
+	upper := strings.ToUpper(node.Comment)
+	node.Correct = strings.Contains(upper, "RIGHT") || strings.Contains(upper, "CORRECT")
+	for _, c := range n.children {
+		if child := toSolution(c); child != nil {
+			node.Children = append(node.Children, child)
+			node.Correct = node.Correct || child.Correct
+		}
+	}
+	return node
+}
+
+// parseGameTree parses "(;...)" into its root node. Nodes in a sequence
+// become a chain of single children so every node has its variations below it.
+func parseGameTree(s string) (*sgfNode, error) {
+	ps := &sgfScanner{s: s}
+	ps.skipSpace()
+	return ps.tree()
+}
+
+type sgfScanner struct {
+	s   string
+	pos int
+}
+
+func (ps *sgfScanner) skipSpace() {
+	for ps.pos < len(ps.s) && strings.ContainsRune(" \t\r\n", rune(ps.s[ps.pos])) {
+		ps.pos++
+	}
+}
+
+func (ps *sgfScanner) peek() byte {
+	if ps.pos >= len(ps.s) {
+		return 0
+	}
+	return ps.s[ps.pos]
+}
+
+// tree parses "(" node+ tree* ")"
+func (ps *sgfScanner) tree() (*sgfNode, error) {
+	if ps.peek() != '(' {
+		return nil, fmt.Errorf("expected '(' at %d", ps.pos)
+	}
+	ps.pos++
+	ps.skipSpace()
+
+	var first, last *sgfNode
+	for ps.peek() == ';' {
+		ps.pos++
+		n, err := ps.node()
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = n
+		} else {
+			last.children = append(last.children, n)
+		}
+		last = n
+		ps.skipSpace()
+	}
+	if first == nil {
+		return nil, fmt.Errorf("empty game tree at %d", ps.pos)
+	}
+
+	for ps.peek() == '(' {
+		child, err := ps.tree()
+		if err != nil {
+			return nil, err
+		}
+		last.children = append(last.children, child)
+		ps.skipSpace()
+	}
+	if ps.peek() != ')' {
+		return nil, fmt.Errorf("expected ')' at %d", ps.pos)
+	}
+	ps.pos++
+	return first, nil
+}
+
+// node parses the properties following a ';'
+func (ps *sgfScanner) node() (*sgfNode, error) {
+	n := &sgfNode{props: map[string][]string{}}
+	for {
+		ps.skipSpace()
+		start := ps.pos
+		for c := ps.peek(); c >= 'A' && c <= 'Z'; c = ps.peek() {
+			ps.pos++
+		}
+		if start == ps.pos {
+			return n, nil
+		}
+		ident := ps.s[start:ps.pos]
+		ps.skipSpace()
+		if ps.peek() != '[' {
+			return nil, fmt.Errorf("property %s has no value", ident)
+		}
+		for ps.peek() == '[' {
+			v, err := ps.value()
+			if err != nil {
+				return nil, err
+			}
+			n.props[ident] = append(n.props[ident], v)
+			ps.skipSpace()
+		}
+	}
+}
+
+// value parses "[...]", honouring backslash escapes
+func (ps *sgfScanner) value() (string, error) {
+	ps.pos++ // '['
+	var b strings.Builder
+	for ps.pos < len(ps.s) {
+		c := ps.s[ps.pos]
+		ps.pos++
+		switch c {
+		case '\\':
+			if ps.pos < len(ps.s) {
+				b.WriteByte(ps.s[ps.pos])
+				ps.pos++
+			}
+		case ']':
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated property value")
+}
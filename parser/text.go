@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DiscordMessageLimit is the maximum number of characters in a Discord message
+const DiscordMessageLimit = 2000
+
+// TextStyle selects the glyphs RenderText draws the board with
+type TextStyle int
+
+const (
+	// TextUnicode draws the grid with box-drawing characters and ●/○ stones
+	TextUnicode TextStyle = iota
+	// TextEmoji draws the board with emoji squares and stones
+	TextEmoji
+)
+
+// columnLetters are the board column labels; "I" is skipped by convention
+const columnLetters = "ABCDEFGHJKLMNOPQRST"
+
+// RenderText draws the part of the board around the stones as a text grid
+// inside a code block, with column letters and row numbers along the edges.
+// Emoji boards that would not fit in a Discord message fall back to Unicode.
+func RenderText(p *GoProblem, style TextStyle) (string, error) {
+	board, err := stoneGrid(p)
+	if err != nil {
+		return "", err
+	}
+	x0, y0, x1, y1 := cropBounds(board, 2)
+
+	out := renderTextGrid(board, x0, y0, x1, y1, style)
+	if utf8.RuneCountInString(out) > DiscordMessageLimit && style == TextEmoji {
+		out = renderTextGrid(board, x0, y0, x1, y1, TextUnicode)
+	}
+	if n := utf8.RuneCountInString(out); n > DiscordMessageLimit {
+		return "", fmt.Errorf("text board is %d characters, over the %d limit", n, DiscordMessageLimit)
+	}
+	return out, nil
+}
+
+// renderTextGrid writes the rows x0..x1, y0..y1 of board in the given style
+func renderTextGrid(board [19][19]byte, x0, y0, x1, y1 int, style TextStyle) string {
+	var b strings.Builder
+	b.WriteString("```\n")
+
+	// Column ruler
+	b.WriteString("   ")
+	for x := x0; x <= x1; x++ {
+		if style == TextEmoji {
+			// fullwidth letters line up with the double-width emoji
+			b.WriteRune('Ａ' + rune(columnLetters[x]-'A'))
+		} else {
+			b.WriteByte(columnLetters[x])
+			if x < x1 {
+				b.WriteByte(' ')
+			}
+		}
+	}
+	b.WriteByte('\n')
+
+	for y := y0; y <= y1; y++ {
+		fmt.Fprintf(&b, "%2d ", 19-y)
+		for x := x0; x <= x1; x++ {
+			b.WriteString(textPoint(board[x][y], x, y, style))
+			if style == TextUnicode && x < x1 {
+				b.WriteString("─")
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	b.WriteString("```")
+	return b.String()
+}
+
+// textPoint returns the glyph for a single intersection
+func textPoint(stone byte, x, y int, style TextStyle) string {
+	if style == TextEmoji {
+		switch stone {
+		case 'B':
+			return "⚫"
+		case 'W':
+			return "⚪"
+		}
+		return "🟫"
+	}
+
+	switch stone {
+	case 'B':
+		return "●"
+	case 'W':
+		return "○"
+	}
+	top, bottom, left, right := y == 0, y == 18, x == 0, x == 18
+	switch {
+	case top && left:
+		return "┌"
+	case top && right:
+		return "┐"
+	case bottom && left:
+		return "└"
+	case bottom && right:
+		return "┘"
+	case top:
+		return "┬"
+	case bottom:
+		return "┴"
+	case left:
+		return "├"
+	case right:
+		return "┤"
+	case isStarPoint(x) && isStarPoint(y):
+		return "╋"
+	}
+	return "┼"
+}
+
+// stoneGrid places the problem's stones on a 19×19 grid indexed [x][y]
+func stoneGrid(p *GoProblem) ([19][19]byte, error) {
+	var board [19][19]byte
+	for _, c := range p.Black {
+		x, y, err := sgfToIndex(c)
+		if err != nil {
+			return board, err
+		}
+		board[x][y] = 'B'
+	}
+	for _, c := range p.White {
+		x, y, err := sgfToIndex(c)
+		if err != nil {
+			return board, err
+		}
+		board[x][y] = 'W'
+	}
+	return board, nil
+}
+
+// cropBounds returns the inclusive bounding box of the stones on board,
+// grown by pad lines and snapped to any board edge it comes close to.
+// An empty board returns the whole board.
+func cropBounds(board [19][19]byte, pad int) (x0, y0, x1, y1 int) {
+	x0, y0, x1, y1 = 19, 19, -1, -1
+	for x := range 19 {
+		for y := range 19 {
+			if board[x][y] == 0 {
+				continue
+			}
+			x0, y0 = min(x0, x), min(y0, y)
+			x1, y1 = max(x1, x), max(y1, y)
+		}
+	}
+	if x1 < 0 {
+		return 0, 0, 18, 18
+	}
+
+	x0, y0 = max(x0-pad, 0), max(y0-pad, 0)
+	x1, y1 = min(x1+pad, 18), min(y1+pad, 18)
+	// show the edge rather than leave a line or two short of it
+	if x0 <= pad {
+		x0 = 0
+	}
+	if y0 <= pad {
+		y0 = 0
+	}
+	if x1 >= 18-pad {
+		x1 = 18
+	}
+	if y1 >= 18-pad {
+		y1 = 18
+	}
+	return x0, y0, x1, y1
+}
+
+// isStarPoint reports whether a line index carries hoshi
+func isStarPoint(i int) bool {
+	return i == 3 || i == 9 || i == 15
+}
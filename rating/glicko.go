@@ -0,0 +1,132 @@
+// Package rating implements Glicko-2 ratings, treating every graded answer
+// as a match between a user and a problem
+package rating
+
+import "math"
+
+// Glicko-2 constants
+const (
+	// Tau limits how quickly volatility moves; Glickman suggests 0.3 to 1.2
+	Tau = 0.5
+	// scale converts between the Glicko and Glicko-2 scales
+	scale = 173.7178
+	// epsilon is the convergence tolerance of the volatility iteration
+	epsilon = 0.000001
+)
+
+// Rating is a Glicko-2 rating on the familiar Glicko scale
+type Rating struct {
+	Rating     float64
+	Deviation  float64 // RD; the rating is ±2 RD with 95% confidence
+	Volatility float64
+}
+
+// Default is the rating of a newcomer
+var Default = Rating{Rating: 1500, Deviation: 350, Volatility: 0.06}
+
+// tierRatings seed problems from their collection's difficulty label until
+// they have matches of their own
+var tierRatings = map[string]float64{"easy": 1200, "medium": 1500, "hard": 1800}
+
+// ForTier returns the starting rating of a problem of the given difficulty
+func ForTier(difficulty string) Rating {
+	r := Default
+	if v, ok := tierRatings[difficulty]; ok {
+		r.Rating = v
+	}
+	return r
+}
+
+// Result is the outcome of one match against an opponent
+type Result struct {
+	Opponent Rating
+	Score    float64 // 1 for a win, 0.5 for a draw, 0 for a loss
+}
+
+// Update returns r after a rating period with the given results. With no
+// results only the deviation grows, as time passes without evidence.
+func Update(r Rating, results []Result) Rating {
+	mu := (r.Rating - 1500) / scale
+	phi := r.Deviation / scale
+	if len(results) == 0 {
+		return Rating{
+			Rating:     r.Rating,
+			Deviation:  math.Min(math.Sqrt(phi*phi+r.Volatility*r.Volatility)*scale, Default.Deviation),
+			Volatility: r.Volatility,
+		}
+	}
+
+	var vInv, sum float64
+	for _, res := range results {
+		muJ := (res.Opponent.Rating - 1500) / scale
+		g := gee(res.Opponent.Deviation / scale)
+		e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+		vInv += g * g * e * (1 - e)
+		sum += g * (res.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	sigma := volatility(phi, r.Volatility, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phiNew := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	muNew := mu + phiNew*phiNew*sum
+	return Rating{
+		Rating:     muNew*scale + 1500,
+		Deviation:  math.Min(phiNew*scale, Default.Deviation),
+		Volatility: sigma,
+	}
+}
+
+// Match rates one graded answer: the user scores score against the problem
+// and the problem the rest. Both are updated from their ratings before the
+// match.
+func Match(user, problem Rating, score float64) (Rating, Rating) {
+	return Update(user, []Result{{Opponent: problem, Score: score}}),
+		Update(problem, []Result{{Opponent: user, Score: 1 - score}})
+}
+
+// Expected returns the chance that a player rated r beats opponent
+func Expected(r, opponent Rating) float64 {
+	g := gee(opponent.Deviation / scale)
+	return 1 / (1 + math.Exp(-g*(r.Rating-opponent.Rating)/scale))
+}
+
+func gee(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// volatility finds the new volatility with the Illinois algorithm, step 5
+// of Glickman's description of Glicko-2
+func volatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(Tau*Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*Tau) < 0 {
+			k++
+		}
+		B = a - k*Tau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
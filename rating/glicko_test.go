@@ -0,0 +1,49 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUpdateMatchesGlickmansExample(t *testing.T) {
+	// the worked example from Glickman's "Example of the Glicko-2 system"
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	got := Update(player, []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: 1},
+		{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: 0},
+		{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: 0},
+	})
+	if math.Abs(got.Rating-1464.06) > 0.01 || math.Abs(got.Deviation-151.52) > 0.01 || math.Abs(got.Volatility-0.05999) > 0.00001 {
+		t.Errorf("expected 1464.06 / 151.52 / 0.05999, got %+v", got)
+	}
+}
+
+func TestMatchMovesBothSides(t *testing.T) {
+	user, problem := Match(Default, ForTier("hard"), 1)
+	if user.Rating <= Default.Rating || problem.Rating >= 1800 {
+		t.Errorf("expected solving a hard problem to raise the user and lower the problem, got %+v and %+v", user, problem)
+	}
+	if user.Deviation >= Default.Deviation {
+		t.Errorf("expected a match to shrink the deviation, got %v", user.Deviation)
+	}
+
+	easy := ForTier("easy")
+	missed, _ := Match(Default, easy, 0)
+	hardMiss, _ := Match(Default, ForTier("hard"), 0)
+	if Default.Rating-missed.Rating <= Default.Rating-hardMiss.Rating {
+		t.Errorf("expected missing an easy problem to cost more than a hard one, got %v and %v", missed.Rating, hardMiss.Rating)
+	}
+	if e := Expected(Default, easy); e < 0.5 || e > 1 {
+		t.Errorf("expected a newcomer to be favoured against an easy problem, got %v", e)
+	}
+}
+
+func TestIdleDeviationGrowsUpToDefault(t *testing.T) {
+	r := Rating{Rating: 1700, Deviation: 50, Volatility: 0.06}
+	if got := Update(r, nil); got.Deviation <= 50 || got.Rating != 1700 {
+		t.Errorf("expected only the deviation to grow, got %+v", got)
+	}
+	if got := Update(Default, nil); got.Deviation != Default.Deviation {
+		t.Errorf("expected the deviation to be capped, got %v", got.Deviation)
+	}
+}
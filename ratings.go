@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/rating"
+	"github.com/novnod/barista-bot/repo"
+)
+
+// provisionalDeviation is the RD above which a rating is shown as provisional
+const provisionalDeviation = 110
+
+// ratingsMu serializes rating updates, so two matches on the same problem,
+// or a match and a recompute, can't overwrite each other
+var ratingsMu sync.Mutex
+
+// rateAttempt counts a graded attempt as a Glicko-2 match between the user
+// and the problem. Answers after the user's first correct one on a problem
+// don't count, so solving it again can't farm rating.
+func rateAttempt(a repo.Attempt, prob *parser.GoProblem) {
+	if a.Result == repo.ResultUngraded || a.GuildID == "" {
+		return
+	}
+	ratingsMu.Lock()
+	defer ratingsMu.Unlock()
+
+	earlier, err := attemptRepo.ForProblem(a.UserID, a.ProblemID)
+	if err != nil {
+		log.Printf("failed to rate attempt: %v", err)
+		return
+	}
+	for _, e := range earlier {
+		if e.GuildID == a.GuildID && e.ID < a.ID && e.Result == repo.ResultCorrect {
+			return
+		}
+	}
+
+	user, err := loadRating(a.GuildID, repo.SubjectUser, a.UserID, rating.Default)
+	if err != nil {
+		log.Printf("failed to rate attempt: %v", err)
+		return
+	}
+	problem, err := loadRating(a.GuildID, repo.SubjectProblem, a.ProblemID, rating.ForTier(prob.Difficulty))
+	if err != nil {
+		log.Printf("failed to rate attempt: %v", err)
+		return
+	}
+	playMatch(user, problem, a)
+	if err := ratingRepo.SaveMatch(*user, *problem, a.ID); err != nil {
+		log.Printf("failed to rate attempt: %v", err)
+	}
+}
+
+// loadRating returns a stored rating, or one starting from prior
+func loadRating(guildID, subject, subjectID string, prior rating.Rating) (*repo.SubjectRating, error) {
+	sr, err := ratingRepo.GetRating(guildID, subject, subjectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &repo.SubjectRating{GuildID: guildID, Subject: subject, SubjectID: subjectID, Glicko: prior}, nil
+	}
+	return sr, err
+}
+
+// playMatch updates both sides of an attempt in place
+func playMatch(user, problem *repo.SubjectRating, a repo.Attempt) {
+	score := 0.0
+	if a.Result == repo.ResultCorrect {
+		score = 1
+	}
+	user.Glicko, problem.Glicko = rating.Match(user.Glicko, problem.Glicko, score)
+	user.Matches++
+	problem.Matches++
+	user.UpdatedAt, problem.UpdatedAt = a.CreatedAt, a.CreatedAt
+}
+
+// recomputeRatings replays a guild's graded attempts from scratch, with the
+// same rules as rateAttempt, and replaces its ratings with the result
+func recomputeRatings(guildID string, pg *parser.GoParser) (users, problems int, err error) {
+	ratingsMu.Lock()
+	defer ratingsMu.Unlock()
+
+	attempts, err := attemptRepo.Graded(guildID)
+	if err != nil {
+		return 0, 0, err
+	}
+	ratings := map[string]*repo.SubjectRating{}
+	get := func(subject, id string, prior rating.Rating) *repo.SubjectRating {
+		key := subject + ":" + id
+		if ratings[key] == nil {
+			ratings[key] = &repo.SubjectRating{GuildID: guildID, Subject: subject, SubjectID: id, Glicko: prior}
+		}
+		return ratings[key]
+	}
+	solved := map[string]bool{}
+	var history []repo.RatingPoint
+	for _, a := range attempts {
+		key := a.UserID + ":" + a.ProblemID
+		if solved[key] {
+			continue
+		}
+		solved[key] = a.Result == repo.ResultCorrect
+
+		prior := rating.Default
+		if prob := pg.Problem(a.ProblemID); prob != nil {
+			prior = rating.ForTier(prob.Difficulty)
+		}
+		user := get(repo.SubjectUser, a.UserID, rating.Default)
+		playMatch(user, get(repo.SubjectProblem, a.ProblemID, prior), a)
+		history = append(history, repo.RatingPoint{
+			GuildID: guildID, UserID: a.UserID, AttemptID: a.ID,
+			Rating: user.Glicko.Rating, Deviation: user.Glicko.Deviation, CreatedAt: a.CreatedAt,
+		})
+	}
+
+	all := make([]repo.SubjectRating, 0, len(ratings))
+	for _, sr := range ratings {
+		all = append(all, *sr)
+		if sr.Subject == repo.SubjectUser {
+			users++
+		} else {
+			problems++
+		}
+	}
+	if err := ratingRepo.ReplaceRatings(guildID, all, history); err != nil {
+		return 0, 0, err
+	}
+	return users, problems, nil
+}
+
+// problemRating returns the guild's rating of a problem, if it has been
+// answered there
+func problemRating(guildID, problemID string) (*repo.SubjectRating, bool) {
+	sr, err := ratingRepo.GetRating(guildID, repo.SubjectProblem, problemID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to get problem rating: %v", err)
+		}
+		return nil, false
+	}
+	return sr, true
+}
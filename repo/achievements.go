@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/novnod/barista-bot/achievement"
+)
+
+// AchievementRepository stores guilds' own achievement rules, the roles
+// achievements grant and which members have unlocked what
+type AchievementRepository struct {
+	db *sql.DB
+}
+
+// InitAchievementRepository returns a new repository bound to db
+func InitAchievementRepository(db *sql.DB) *AchievementRepository {
+	return &AchievementRepository{db: db}
+}
+
+// Rules returns the built-in rules followed by the guild's own
+func (r *AchievementRepository) Rules(guildID string) ([]achievement.Rule, error) {
+	rows, err := r.db.Query(
+		`SELECT key, name, description, metric, threshold, tier FROM achievement_rules
+         WHERE guild_id = ? ORDER BY metric, threshold, key`,
+		guildID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list achievements: %w", err)
+	}
+	defer rows.Close()
+
+	rules := append([]achievement.Rule(nil), achievement.Builtin...)
+	for rows.Next() {
+		var rule achievement.Rule
+		if err := rows.Scan(&rule.Key, &rule.Name, &rule.Description, &rule.Metric, &rule.Threshold, &rule.Tier); err != nil {
+			return nil, fmt.Errorf("failed to list achievements: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// SaveRule adds a guild rule or replaces the one with the same key
+func (r *AchievementRepository) SaveRule(guildID string, rule achievement.Rule) error {
+	_, err := r.db.Exec(
+		`INSERT INTO achievement_rules(guild_id, key, name, description, metric, threshold, tier) VALUES(?, ?, ?, ?, ?, ?, ?)
+         ON CONFLICT(guild_id, key) DO UPDATE SET name=excluded.name, description=excluded.description,
+             metric=excluded.metric, threshold=excluded.threshold, tier=excluded.tier;`,
+		guildID, rule.Key, rule.Name, rule.Description, rule.Metric, rule.Threshold, rule.Tier,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save achievement: %w", err)
+	}
+	return nil
+}
+
+// RemoveRule deletes a guild rule with its role, returning sql.ErrNoRows if
+// there isn't one. Members keep what they've unlocked.
+func (r *AchievementRepository) RemoveRule(guildID, key string) error {
+	res, err := r.db.Exec(`DELETE FROM achievement_rules WHERE guild_id = ? AND key = ?`, guildID, key)
+	if err != nil {
+		return fmt.Errorf("failed to remove achievement: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return r.SetRole(guildID, key, "")
+}
+
+// Roles maps achievement keys to the role each grants in the guild
+func (r *AchievementRepository) Roles(guildID string) (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT key, role_id FROM achievement_roles WHERE guild_id = ?`, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list achievement roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := map[string]string{}
+	for rows.Next() {
+		var key, roleID string
+		if err := rows.Scan(&key, &roleID); err != nil {
+			return nil, fmt.Errorf("failed to list achievement roles: %w", err)
+		}
+		roles[key] = roleID
+	}
+	return roles, rows.Err()
+}
+
+// SetRole sets the role an achievement grants; "" grants none
+func (r *AchievementRepository) SetRole(guildID, key, roleID string) error {
+	var err error
+	if roleID == "" {
+		_, err = r.db.Exec(`DELETE FROM achievement_roles WHERE guild_id = ? AND key = ?`, guildID, key)
+	} else {
+		_, err = r.db.Exec(
+			`INSERT INTO achievement_roles(guild_id, key, role_id) VALUES(?, ?, ?)
+             ON CONFLICT(guild_id, key) DO UPDATE SET role_id=excluded.role_id;`,
+			guildID, key, roleID,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set achievement role: %w", err)
+	}
+	return nil
+}
+
+// Unlocked maps the keys a member has unlocked to when
+func (r *AchievementRepository) Unlocked(guildID, userID string) (map[string]time.Time, error) {
+	rows, err := r.db.Query(`SELECT key, unlocked_at FROM achievements WHERE guild_id = ? AND user_id = ?`, guildID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unlocked achievements: %w", err)
+	}
+	defer rows.Close()
+
+	unlocked := map[string]time.Time{}
+	for rows.Next() {
+		var key string
+		var at int64
+		if err := rows.Scan(&key, &at); err != nil {
+			return nil, fmt.Errorf("failed to list unlocked achievements: %w", err)
+		}
+		unlocked[key] = time.Unix(at, 0)
+	}
+	return unlocked, rows.Err()
+}
+
+// Unlock records that a member unlocked an achievement. It reports false if
+// they already had it, so each unlock is announced once.
+func (r *AchievementRepository) Unlock(guildID, userID, key string, at time.Time) (bool, error) {
+	res, err := r.db.Exec(
+		`INSERT INTO achievements(guild_id, user_id, key, unlocked_at) VALUES(?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+		guildID, userID, key, at.Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to unlock achievement: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
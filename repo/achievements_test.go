@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/novnod/barista-bot/achievement"
+)
+
+func TestAchievementRules(t *testing.T) {
+	r := InitAchievementRepository(openTestDB(t))
+
+	rules, err := r.Rules("g1")
+	if err != nil {
+		t.Fatalf("Rules returned error: %v", err)
+	}
+	if len(rules) != len(achievement.Builtin) {
+		t.Fatalf("expected only the built-in rules, got %d", len(rules))
+	}
+
+	rule := achievement.Rule{Key: "medium-10", Name: "Middle game", Metric: achievement.MetricSolves, Threshold: 10, Tier: "medium"}
+	if err := r.SaveRule("g1", rule); err != nil {
+		t.Fatalf("SaveRule returned error: %v", err)
+	}
+	rule.Threshold = 20
+	if err := r.SaveRule("g1", rule); err != nil {
+		t.Fatalf("SaveRule returned error on replace: %v", err)
+	}
+	rules, _ = r.Rules("g1")
+	if len(rules) != len(achievement.Builtin)+1 || rules[len(rules)-1] != rule {
+		t.Errorf("expected the guild rule after the built-ins, got %+v", rules)
+	}
+	if rules, _ := r.Rules("g2"); len(rules) != len(achievement.Builtin) {
+		t.Errorf("expected rules to be per guild, got %d", len(rules))
+	}
+
+	r.SetRole("g1", "medium-10", "role1")
+	r.SetRole("g1", "first-solve", "role2")
+	if roles, _ := r.Roles("g1"); roles["medium-10"] != "role1" || roles["first-solve"] != "role2" {
+		t.Errorf("unexpected roles %v", roles)
+	}
+	if err := r.RemoveRule("g1", "medium-10"); err != nil {
+		t.Fatalf("RemoveRule returned error: %v", err)
+	}
+	if roles, _ := r.Roles("g1"); len(roles) != 1 {
+		t.Errorf("expected the removed rule's role to go too, got %v", roles)
+	}
+	if err := r.RemoveRule("g1", "medium-10"); err != sql.ErrNoRows {
+		t.Errorf("expected ErrNoRows removing it again, got %v", err)
+	}
+}
+
+func TestUnlockOnce(t *testing.T) {
+	r := InitAchievementRepository(openTestDB(t))
+	at := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	if ok, err := r.Unlock("g1", "u1", "first-solve", at); !ok || err != nil {
+		t.Fatalf("expected a first unlock, got %v, %v", ok, err)
+	}
+	if ok, _ := r.Unlock("g1", "u1", "first-solve", at.Add(time.Hour)); ok {
+		t.Errorf("expected a repeat unlock to report false")
+	}
+	unlocked, err := r.Unlocked("g1", "u1")
+	if err != nil {
+		t.Fatalf("Unlocked returned error: %v", err)
+	}
+	if len(unlocked) != 1 || !unlocked["first-solve"].Equal(at) {
+		t.Errorf("expected the first unlock's time, got %v", unlocked)
+	}
+}
@@ -0,0 +1,277 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Attempt sources
+const (
+	SourceDaily  = "daily"  // answered in a daily thread
+	SourceReview = "review" // served by the review queue
+)
+
+// Attempt results
+const (
+	ResultCorrect  = "correct"
+	ResultWrong    = "wrong"
+	ResultUngraded = "ungraded" // the problem has no solution on file, or it was answered after the reveal
+)
+
+// Attempt is one graded answer by a user
+type Attempt struct {
+	ID        int64
+	GuildID   string
+	UserID    string
+	ProblemID string
+	Source    string
+	Moves     []string // as submitted, e.g. ["C17", "B18"]
+	Result    string
+	Took      time.Duration // from the problem being posted to the answer, if known
+	HintUsed  bool          // set by Record from the user's hints
+	ThreadID  string
+	CreatedAt time.Time
+}
+
+// AttemptSummary totals a user's attempts in a guild
+type AttemptSummary struct {
+	Attempts int
+	Correct  int
+	Problems int // distinct problems tried
+	Solved   int // distinct problems answered correctly
+}
+
+// ProblemTally counts a user's graded attempts at one problem
+type ProblemTally struct {
+	ProblemID string
+	Attempts  int
+	Correct   int
+}
+
+// ActivityBucket counts a user's attempts in one hour
+type ActivityBucket struct {
+	Hour     time.Time
+	Attempts int
+}
+
+// PostStats summarizes the answers to a daily post
+type PostStats struct {
+	Players  int // people who answered at least once
+	Attempts int
+	Solved   int // players with a correct answer
+	FirstTry int // players whose first answer was correct
+}
+
+// AttemptRepository stores every graded answer and the hints users asked for
+type AttemptRepository struct {
+	db *sql.DB
+}
+
+// InitAttemptRepository returns a new repository bound to db
+func InitAttemptRepository(db *sql.DB) *AttemptRepository {
+	return &AttemptRepository{db: db}
+}
+
+// Record stores an attempt, marking it as hinted if the user asked for a
+// hint on the problem before answering, and returns its ID
+func (r *AttemptRepository) Record(a Attempt) (int64, error) {
+	res, err := r.db.Exec(
+		`INSERT INTO attempts(guild_id, user_id, problem_id, source, moves, result, took_ms, hint_used, thread_id, created_at)
+         VALUES(?, ?, ?, ?, ?, ?, ?,
+             EXISTS(SELECT 1 FROM hints WHERE user_id = ? AND problem_id = ? AND used_at <= ?), ?, ?)`,
+		a.GuildID, a.UserID, a.ProblemID, a.Source, strings.Join(a.Moves, " "), a.Result, a.Took.Milliseconds(),
+		a.UserID, a.ProblemID, a.CreatedAt.Unix(), a.ThreadID, a.CreatedAt.Unix(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record attempt: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// RecordHint notes that a user asked for a hint on a problem; only the
+// first time counts
+func (r *AttemptRepository) RecordHint(userID, problemID string, at time.Time) error {
+	_, err := r.db.Exec(
+		`INSERT INTO hints(user_id, problem_id, used_at) VALUES(?, ?, ?) ON CONFLICT DO NOTHING`,
+		userID, problemID, at.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record hint: %w", err)
+	}
+	return nil
+}
+
+// ForUser lists a user's attempts in a guild, newest first
+func (r *AttemptRepository) ForUser(guildID, userID string, limit int) ([]Attempt, error) {
+	attempts, err := r.query(`WHERE guild_id = ? AND user_id = ? ORDER BY created_at DESC, id DESC LIMIT ?`, guildID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// ForProblem lists a user's attempts at a problem in any guild, oldest first
+func (r *AttemptRepository) ForProblem(userID, problemID string) ([]Attempt, error) {
+	attempts, err := r.query(`WHERE user_id = ? AND problem_id = ? ORDER BY created_at, id`, userID, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// Graded lists a guild's correct and wrong attempts, oldest first, for
+// replaying into ratings
+func (r *AttemptRepository) Graded(guildID string) ([]Attempt, error) {
+	attempts, err := r.query(`WHERE guild_id = ? AND result != 'ungraded' ORDER BY created_at, id`, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// Summary totals a user's attempts in a guild
+func (r *AttemptRepository) Summary(guildID, userID string) (*AttemptSummary, error) {
+	var sum AttemptSummary
+	err := r.db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(result = 'correct'), 0), COUNT(DISTINCT problem_id),
+             COUNT(DISTINCT CASE WHEN result = 'correct' THEN problem_id END)
+         FROM attempts WHERE guild_id = ? AND user_id = ?`,
+		guildID, userID,
+	).Scan(&sum.Attempts, &sum.Correct, &sum.Problems, &sum.Solved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize attempts: %w", err)
+	}
+	return &sum, nil
+}
+
+// CleanSolves counts the problems a user has solved in a guild without
+// having asked for a hint first
+func (r *AttemptRepository) CleanSolves(guildID, userID string) (int, error) {
+	var n int
+	err := r.db.QueryRow(
+		`SELECT COUNT(DISTINCT problem_id) FROM attempts
+         WHERE guild_id = ? AND user_id = ? AND result = 'correct' AND NOT hint_used`,
+		guildID, userID,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count clean solves: %w", err)
+	}
+	return n, nil
+}
+
+// Tallies counts a user's graded attempts in a guild per problem
+func (r *AttemptRepository) Tallies(guildID, userID string) ([]ProblemTally, error) {
+	rows, err := r.db.Query(
+		`SELECT problem_id, COUNT(*), SUM(result = 'correct') FROM attempts
+         WHERE guild_id = ? AND user_id = ? AND result != 'ungraded' GROUP BY problem_id`,
+		guildID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tally attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var tallies []ProblemTally
+	for rows.Next() {
+		var t ProblemTally
+		if err := rows.Scan(&t.ProblemID, &t.Attempts, &t.Correct); err != nil {
+			return nil, fmt.Errorf("failed to tally attempts: %w", err)
+		}
+		tallies = append(tallies, t)
+	}
+	return tallies, rows.Err()
+}
+
+// Activity counts a user's attempts in a guild since the given time per
+// hour, which callers bin into days in the guild's zone
+func (r *AttemptRepository) Activity(guildID, userID string, since time.Time) ([]ActivityBucket, error) {
+	rows, err := r.db.Query(
+		`SELECT created_at / 3600, COUNT(*) FROM attempts
+         WHERE guild_id = ? AND user_id = ? AND created_at >= ? GROUP BY created_at / 3600 ORDER BY 1`,
+		guildID, userID, since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count activity: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []ActivityBucket
+	for rows.Next() {
+		var hour int64
+		var b ActivityBucket
+		if err := rows.Scan(&hour, &b.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to count activity: %w", err)
+		}
+		b.Hour = time.Unix(hour*3600, 0)
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// PostStats summarizes the answers given in a daily post's thread, however
+// they were submitted. A shared post also counts the answers in the DMs its
+// schedule's daily was delivered to that day, since they're revealed with it.
+func (r *AttemptRepository) PostStats(p DailyPost) (PostStats, error) {
+	var stats PostStats
+	attempts, err := r.query(
+		`WHERE problem_id = ? AND (thread_id = ? OR (? AND EXISTS(
+             SELECT 1 FROM dm_dailies d WHERE d.channel_id = attempts.thread_id AND d.guild_id = attempts.guild_id
+             AND d.schedule = ? AND d.day = ? AND d.problem_id = attempts.problem_id AND d.posted_at <= attempts.created_at)))
+         ORDER BY created_at, id`,
+		p.ProblemID, p.ThreadID, p.Shared, p.Schedule, p.Day,
+	)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count answers: %w", err)
+	}
+
+	first := map[string]string{}
+	solved := map[string]bool{}
+	for _, a := range attempts {
+		stats.Attempts++
+		if _, answered := first[a.UserID]; !answered {
+			first[a.UserID] = a.Result
+		}
+		if a.Result == ResultCorrect {
+			solved[a.UserID] = true
+		}
+	}
+	stats.Players = len(first)
+	stats.Solved = len(solved)
+	for _, result := range first {
+		if result == ResultCorrect {
+			stats.FirstTry++
+		}
+	}
+	return stats, nil
+}
+
+// query runs a SELECT over attempts with the given clause
+func (r *AttemptRepository) query(clause string, args ...any) ([]Attempt, error) {
+	rows, err := r.db.Query(
+		`SELECT id, guild_id, user_id, problem_id, source, moves, result, took_ms, hint_used, thread_id, created_at
+         FROM attempts `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []Attempt
+	for rows.Next() {
+		var a Attempt
+		var moves string
+		var tookMs, createdAt int64
+		if err := rows.Scan(&a.ID, &a.GuildID, &a.UserID, &a.ProblemID, &a.Source, &moves, &a.Result,
+			&tookMs, &a.HintUsed, &a.ThreadID, &createdAt); err != nil {
+			return nil, err
+		}
+		a.Moves = strings.Fields(moves)
+		a.Took = time.Duration(tookMs) * time.Millisecond
+		a.CreatedAt = time.Unix(createdAt, 0)
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
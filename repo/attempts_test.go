@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordAttempts(t *testing.T) {
+	r := InitAttemptRepository(openTestDB(t))
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	record := func(user, problem, result string, at time.Time) {
+		t.Helper()
+		a := Attempt{GuildID: "g1", UserID: user, ProblemID: problem, Source: SourceDaily, Moves: []string{"C17", "B18"},
+			Result: result, Took: at.Sub(start), ThreadID: "t1", CreatedAt: at}
+		if _, err := r.Record(a); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+	record("u1", "p1", ResultWrong, start.Add(time.Minute))
+	if err := r.RecordHint("u1", "p1", start.Add(2*time.Minute)); err != nil {
+		t.Fatalf("RecordHint returned error: %v", err)
+	}
+	if err := r.RecordHint("u1", "p1", start.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordHint returned error on a repeat: %v", err)
+	}
+	record("u1", "p1", ResultCorrect, start.Add(3*time.Minute))
+	record("u1", "p2", ResultCorrect, start.Add(4*time.Minute))
+	record("u2", "p1", ResultCorrect, start.Add(5*time.Minute))
+
+	attempts, err := r.ForProblem("u1", "p1")
+	if err != nil {
+		t.Fatalf("ForProblem returned error: %v", err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %+v", attempts)
+	}
+	first, second := attempts[0], attempts[1]
+	if first.HintUsed || !second.HintUsed {
+		t.Errorf("expected only the answer after the hint to be marked, got %v and %v", first.HintUsed, second.HintUsed)
+	}
+	if first.Took != time.Minute || len(first.Moves) != 2 || first.Moves[1] != "B18" || first.Result != ResultWrong {
+		t.Errorf("expected the attempt to round-trip, got %+v", first)
+	}
+
+	sum, err := r.Summary("g1", "u1")
+	if err != nil {
+		t.Fatalf("Summary returned error: %v", err)
+	}
+	if *sum != (AttemptSummary{Attempts: 3, Correct: 2, Problems: 2, Solved: 2}) {
+		t.Errorf("unexpected summary %+v", sum)
+	}
+	if recent, _ := r.ForUser("g1", "u1", 1); len(recent) != 1 || recent[0].ProblemID != "p2" {
+		t.Errorf("expected the newest attempt first, got %+v", recent)
+	}
+	if clean, err := r.CleanSolves("g1", "u1"); err != nil || clean != 1 {
+		t.Errorf("expected only p2 to count as solved without a hint, got %d, %v", clean, err)
+	}
+}
+
+func TestTalliesAndActivity(t *testing.T) {
+	r := InitAttemptRepository(openTestDB(t))
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	for n, result := range []string{ResultWrong, ResultCorrect, ResultCorrect, ResultUngraded} {
+		problem := "p1"
+		if n >= 2 {
+			problem = "p2"
+		}
+		at := start.Add(time.Duration(n) * 25 * time.Minute)
+		if _, err := r.Record(Attempt{GuildID: "g1", UserID: "u1", ProblemID: problem, Source: SourceDaily, Result: result, CreatedAt: at}); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+
+	tallies, err := r.Tallies("g1", "u1")
+	if err != nil {
+		t.Fatalf("Tallies returned error: %v", err)
+	}
+	if len(tallies) != 2 || tallies[0] != (ProblemTally{"p1", 2, 1}) || tallies[1] != (ProblemTally{"p2", 1, 1}) {
+		t.Errorf("unexpected tallies %+v", tallies)
+	}
+
+	buckets, err := r.Activity("g1", "u1", start.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Activity returned error: %v", err)
+	}
+	// 08:25 and 08:50, then 09:15
+	if len(buckets) != 2 || buckets[0].Attempts != 2 || !buckets[1].Hour.Equal(start.Add(time.Hour)) || buckets[1].Attempts != 1 {
+		t.Errorf("unexpected activity %+v", buckets)
+	}
+}
+
+func TestPostStats(t *testing.T) {
+	db := openTestDB(t)
+	r := InitAttemptRepository(db)
+	subs := InitSubscriptionRepository(db)
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	post := DailyPost{ThreadID: "t1", GuildID: "g1", Schedule: DefaultSchedule, Day: "2025-06-01", ProblemID: "p1", Shared: true}
+
+	dm := DMDaily{MessageID: "m1", ChannelID: "dm1", GuildID: "g1", UserID: "u3", Schedule: DefaultSchedule,
+		Day: "2025-06-01", ProblemID: "p1", PostedAt: start}
+	if err := subs.RecordDM(dm); err != nil {
+		t.Fatalf("RecordDM returned error: %v", err)
+	}
+
+	record := func(user, thread, problem, result string, at time.Time) {
+		t.Helper()
+		a := Attempt{GuildID: "g1", UserID: user, ProblemID: problem, Source: SourceDaily, Moves: []string{"C17"},
+			Result: result, ThreadID: thread, CreatedAt: at}
+		if _, err := r.Record(a); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+	record("u1", "t1", "p1", ResultCorrect, start.Add(time.Minute))
+	record("u2", "t1", "p1", ResultWrong, start.Add(2*time.Minute))
+	record("u2", "t1", "p1", ResultCorrect, start.Add(3*time.Minute))
+	record("u3", "dm1", "p1", ResultWrong, start.Add(4*time.Minute))
+	// another thread, and the same DM channel before the day's delivery
+	record("u4", "t2", "p1", ResultCorrect, start.Add(time.Minute))
+	record("u3", "dm1", "p1", ResultCorrect, start.Add(-time.Hour))
+
+	stats, err := r.PostStats(post)
+	if err != nil {
+		t.Fatalf("PostStats returned error: %v", err)
+	}
+	if stats != (PostStats{Players: 3, Attempts: 4, Solved: 2, FirstTry: 1}) {
+		t.Errorf("unexpected stats for the shared post %+v", stats)
+	}
+
+	// a personal thread only counts its own answers
+	post.Shared = false
+	if stats, _ := r.PostStats(post); stats != (PostStats{Players: 2, Attempts: 3, Solved: 2, FirstTry: 1}) {
+		t.Errorf("unexpected stats for a personal thread %+v", stats)
+	}
+}
@@ -3,15 +3,28 @@ package repo
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	_ "github.com/glebarez/sqlite"
 )
 
-// DailyConfig holds the daily posting settings for a guild
+// DefaultSchedule is the schedule edited through /edit_daily, and the one
+// a guild's existing settings became when schedules were introduced
+const DefaultSchedule = "default"
+
+// InheritReveal marks a schedule that uses the guild's reveal settings
+const InheritReveal = -1
+
+// DailyConfig is one of a guild's named daily schedules
 type DailyConfig struct {
-	GuildID   string
-	ChannelID string
-	TimeHHMM  string
+	GuildID     string
+	Name        string
+	ChannelID   string
+	TimeHHMM    string // "HH:MM" or a cron trigger, see scheduler.ParseTrigger
+	Timezone    string
+	Collections []string // empty uses the guild's collections
+	RevealHours int      // InheritReveal, 0 for no reveal, or hours after posting
+	LockThread  bool     // only used when RevealHours isn't InheritReveal
 }
 
 // DailyRepository wraps a SQL DB for daily configs
@@ -19,6 +32,253 @@ type DailyRepository struct {
 	db *sql.DB
 }
 
+// migrations upgrade an existing schema in order. PRAGMA user_version
+// records how many have been applied; append new steps, never edit old ones.
+var migrations = []string{
+	`ALTER TABLE daily_config ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC'`,
+	`CREATE TABLE guild_progress (
+    guild_id        TEXT PRIMARY KEY,
+    seed            INTEGER NOT NULL,
+    cycle           INTEGER NOT NULL DEFAULT 1,
+    next_problem_id TEXT NOT NULL DEFAULT '',
+    collections     TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE daily_picks (
+    guild_id   TEXT NOT NULL,
+    cycle      INTEGER NOT NULL,
+    problem_id TEXT NOT NULL,
+    day        TEXT NOT NULL,
+    PRIMARY KEY (guild_id, cycle, problem_id)
+);
+CREATE INDEX daily_picks_day ON daily_picks(guild_id, day);`,
+	`ALTER TABLE guild_progress ADD COLUMN rotation TEXT NOT NULL DEFAULT ''`,
+	`CREATE TABLE reveal_config (
+    guild_id    TEXT PRIMARY KEY,
+    delay_hours INTEGER NOT NULL,
+    lock_thread INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE daily_reveals (
+    thread_id  TEXT PRIMARY KEY,
+    guild_id   TEXT NOT NULL,
+    problem_id TEXT NOT NULL,
+    reveal_at  INTEGER NOT NULL,
+    revealed   INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX daily_reveals_due ON daily_reveals(revealed, reveal_at);`,
+	`CREATE TABLE jobs (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    guild_id   TEXT NOT NULL,
+    kind       TEXT NOT NULL,
+    key        TEXT NOT NULL,
+    payload    TEXT NOT NULL DEFAULT '',
+    due_at     INTEGER NOT NULL,
+    run_at     INTEGER NOT NULL,
+    status     TEXT NOT NULL DEFAULT 'pending',
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_at INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (guild_id, kind, key)
+);
+CREATE INDEX jobs_due ON jobs(status, run_at);
+INSERT INTO jobs(guild_id, kind, key, payload, due_at, run_at, status)
+    SELECT guild_id, 'reveal', thread_id, problem_id, reveal_at, reveal_at,
+           CASE revealed WHEN 1 THEN 'done' ELSE 'pending' END
+    FROM daily_reveals;
+DROP TABLE daily_reveals;
+CREATE TABLE catch_up_policy (
+    guild_id TEXT PRIMARY KEY,
+    policy   TEXT NOT NULL
+);`,
+	`CREATE TABLE daily_config_named (
+    guild_id     TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    channel_id   TEXT NOT NULL,
+    time_hhmm    TEXT NOT NULL,
+    timezone     TEXT NOT NULL DEFAULT 'UTC',
+    collections  TEXT NOT NULL DEFAULT '',
+    reveal_hours INTEGER NOT NULL DEFAULT -1,
+    lock_thread  INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (guild_id, name)
+);
+INSERT INTO daily_config_named(guild_id, name, channel_id, time_hhmm, timezone)
+    SELECT guild_id, 'default', channel_id, time_hhmm, timezone FROM daily_config;
+DROP TABLE daily_config;
+ALTER TABLE daily_config_named RENAME TO daily_config;
+ALTER TABLE daily_picks ADD COLUMN schedule TEXT NOT NULL DEFAULT '';
+UPDATE daily_picks SET schedule = 'default';
+DROP INDEX daily_picks_day;
+CREATE INDEX daily_picks_day ON daily_picks(guild_id, schedule, day);
+UPDATE jobs SET payload = 'default', key = 'default/' || key WHERE kind = 'post';
+UPDATE jobs SET payload = json_object('problem', payload) WHERE kind IN ('reveal', 'reminder');`,
+	`CREATE TABLE schedule_exceptions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    guild_id   TEXT NOT NULL,
+    schedule   TEXT NOT NULL DEFAULT '',
+    kind       TEXT NOT NULL,
+    start_day  TEXT NOT NULL,
+    end_day    TEXT NOT NULL,
+    problem_id TEXT NOT NULL DEFAULT '',
+    note       TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX schedule_exceptions_day ON schedule_exceptions(guild_id, start_day);`,
+	`CREATE TABLE message_templates (
+    guild_id    TEXT PRIMARY KEY,
+    title       TEXT NOT NULL,
+    description TEXT NOT NULL,
+    footer      TEXT NOT NULL,
+    color       INTEGER NOT NULL
+)`,
+	`CREATE TABLE daily_threads (
+    thread_id  TEXT PRIMARY KEY,
+    guild_id   TEXT NOT NULL,
+    schedule   TEXT NOT NULL,
+    day        TEXT NOT NULL,
+    problem_id TEXT NOT NULL,
+    shared     INTEGER NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX daily_threads_shared ON daily_threads(guild_id, schedule, day) WHERE shared;
+CREATE TABLE thread_mode (
+    guild_id TEXT PRIMARY KEY,
+    mode     TEXT NOT NULL
+);`,
+	`ALTER TABLE daily_threads RENAME TO daily_posts;
+ALTER TABLE daily_posts ADD COLUMN message_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE daily_posts ADD COLUMN posted_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE daily_posts ADD COLUMN revealed_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE daily_posts ADD COLUMN players INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE daily_posts ADD COLUMN solved INTEGER NOT NULL DEFAULT 0;
+DROP INDEX daily_threads_shared;
+CREATE UNIQUE INDEX daily_posts_shared ON daily_posts(guild_id, schedule, day) WHERE shared;
+CREATE INDEX daily_posts_day ON daily_posts(guild_id, day);`,
+	`CREATE TABLE attempts (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    guild_id   TEXT NOT NULL,
+    user_id    TEXT NOT NULL,
+    problem_id TEXT NOT NULL,
+    source     TEXT NOT NULL,
+    moves      TEXT NOT NULL,
+    result     TEXT NOT NULL,
+    took_ms    INTEGER NOT NULL DEFAULT 0,
+    hint_used  INTEGER NOT NULL DEFAULT 0,
+    thread_id  TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX attempts_guild ON attempts(guild_id, created_at);
+CREATE INDEX attempts_user ON attempts(user_id, problem_id);
+CREATE TABLE hints (
+    user_id    TEXT NOT NULL,
+    problem_id TEXT NOT NULL,
+    used_at    INTEGER NOT NULL,
+    PRIMARY KEY (user_id, problem_id)
+);`,
+	`CREATE TABLE streaks (
+    guild_id TEXT NOT NULL,
+    user_id  TEXT NOT NULL,
+    current  INTEGER NOT NULL DEFAULT 0,
+    best     INTEGER NOT NULL DEFAULT 0,
+    last_day TEXT NOT NULL DEFAULT '',
+    freezes  INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (guild_id, user_id)
+);
+CREATE TABLE streak_config (
+    guild_id     TEXT PRIMARY KEY,
+    freeze_every INTEGER NOT NULL
+);`,
+	`CREATE TABLE ratings (
+    guild_id   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    rating     REAL NOT NULL,
+    deviation  REAL NOT NULL,
+    volatility REAL NOT NULL,
+    matches    INTEGER NOT NULL DEFAULT 0,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, subject, subject_id)
+);
+CREATE TABLE rating_history (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    guild_id   TEXT NOT NULL,
+    user_id    TEXT NOT NULL,
+    attempt_id INTEGER NOT NULL,
+    rating     REAL NOT NULL,
+    deviation  REAL NOT NULL,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX rating_history_user ON rating_history(guild_id, user_id, created_at);`,
+	`CREATE TABLE reviews (
+    user_id    TEXT NOT NULL,
+    problem_id TEXT NOT NULL,
+    guild_id   TEXT NOT NULL,
+    ease       REAL NOT NULL,
+    interval   INTEGER NOT NULL,
+    reps       INTEGER NOT NULL DEFAULT 0,
+    due_at     INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, problem_id)
+);
+CREATE INDEX reviews_due ON reviews(user_id, due_at);`,
+	`CREATE TABLE achievement_rules (
+    guild_id    TEXT NOT NULL,
+    key         TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    metric      TEXT NOT NULL,
+    threshold   INTEGER NOT NULL,
+    tier        TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (guild_id, key)
+);
+CREATE TABLE achievement_roles (
+    guild_id TEXT NOT NULL,
+    key      TEXT NOT NULL,
+    role_id  TEXT NOT NULL,
+    PRIMARY KEY (guild_id, key)
+);
+CREATE TABLE achievements (
+    guild_id    TEXT NOT NULL,
+    user_id     TEXT NOT NULL,
+    key         TEXT NOT NULL,
+    unlocked_at INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, user_id, key)
+);`,
+	`CREATE TABLE dm_subscriptions (
+    guild_id   TEXT NOT NULL,
+    user_id    TEXT NOT NULL,
+    schedule   TEXT NOT NULL,
+    disabled   TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, user_id, schedule)
+);
+CREATE TABLE dm_dailies (
+    message_id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL,
+    guild_id   TEXT NOT NULL,
+    user_id    TEXT NOT NULL,
+    schedule   TEXT NOT NULL,
+    day        TEXT NOT NULL,
+    problem_id TEXT NOT NULL,
+    posted_at  INTEGER NOT NULL
+);
+CREATE UNIQUE INDEX dm_dailies_delivery ON dm_dailies(guild_id, schedule, day, user_id);
+CREATE INDEX dm_dailies_channel ON dm_dailies(channel_id, posted_at);`,
+	`CREATE TABLE daily_picks_by_day (
+    guild_id   TEXT NOT NULL,
+    schedule   TEXT NOT NULL,
+    day        TEXT NOT NULL,
+    cycle      INTEGER NOT NULL,
+    problem_id TEXT NOT NULL,
+    PRIMARY KEY (guild_id, schedule, day)
+);
+INSERT INTO daily_picks_by_day(guild_id, schedule, day, cycle, problem_id)
+    SELECT guild_id, schedule, day, cycle, problem_id FROM (
+        SELECT guild_id, schedule, day, cycle, problem_id,
+            ROW_NUMBER() OVER (PARTITION BY guild_id, schedule, day ORDER BY cycle DESC, rowid DESC) AS n
+        FROM daily_picks
+    ) WHERE n = 1;
+DROP TABLE daily_picks;
+ALTER TABLE daily_picks_by_day RENAME TO daily_picks;
+CREATE INDEX daily_picks_problem ON daily_picks(guild_id, problem_id);`,
+}
+
 // InitDBConnection opens (or creates) the SQLite database at dbPath
 // and applies the necessary schema for daily_config.
 func InitDBConnection(dbPath string) (*sql.DB, error) {
@@ -43,23 +303,56 @@ CREATE TABLE IF NOT EXISTS daily_config (
 		db.Close()
 		return nil, fmt.Errorf("failed to init schema: %w", err)
 	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
+// migrate applies the migrations the database hasn't seen yet
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		// PRAGMA doesn't take bind parameters
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
 // InitDailyRepository returns a new repository bound to db
 func InitDailyRepository(db *sql.DB) *DailyRepository {
 	return &DailyRepository{db: db}
 }
 
-// SetConfig inserts or updates the daily config for a guild
-func (r *DailyRepository) SetConfig(guildID, channelID, timeHHMM string) error {
+// SetConfig sets the channel and time of the guild's default schedule,
+// creating it if needed
+func (r *DailyRepository) SetConfig(guildID, channelID, timeHHMM, timezone string) error {
 	_, err := r.db.Exec(
-		`INSERT INTO daily_config(guild_id, channel_id, time_hhmm)
-         VALUES(?, ?, ?)
-         ON CONFLICT(guild_id) DO UPDATE SET
+		`INSERT INTO daily_config(guild_id, name, channel_id, time_hhmm, timezone)
+         VALUES(?, ?, ?, ?, ?)
+         ON CONFLICT(guild_id, name) DO UPDATE SET
              channel_id=excluded.channel_id,
-             time_hhmm=excluded.time_hhmm;`,
-		guildID, channelID, timeHHMM,
+             time_hhmm=excluded.time_hhmm,
+             timezone=excluded.timezone;`,
+		guildID, DefaultSchedule, channelID, timeHHMM, timezone,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to set config: %w", err)
@@ -67,18 +360,100 @@ func (r *DailyRepository) SetConfig(guildID, channelID, timeHHMM string) error {
 	return nil
 }
 
-// GetConfig retrieves the daily config for a guild
+// GetConfig retrieves the guild's default schedule
 func (r *DailyRepository) GetConfig(guildID string) (*DailyConfig, error) {
-	row := r.db.QueryRow(
-		`SELECT guild_id, channel_id, time_hhmm FROM daily_config WHERE guild_id = ?`,
-		guildID,
+	return r.GetSchedule(guildID, DefaultSchedule)
+}
+
+// SaveSchedule inserts or replaces one of a guild's schedules
+func (r *DailyRepository) SaveSchedule(cfg DailyConfig) error {
+	_, err := r.db.Exec(
+		`INSERT INTO daily_config(guild_id, name, channel_id, time_hhmm, timezone, collections, reveal_hours, lock_thread)
+         VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+         ON CONFLICT(guild_id, name) DO UPDATE SET
+             channel_id=excluded.channel_id,
+             time_hhmm=excluded.time_hhmm,
+             timezone=excluded.timezone,
+             collections=excluded.collections,
+             reveal_hours=excluded.reveal_hours,
+             lock_thread=excluded.lock_thread;`,
+		cfg.GuildID, cfg.Name, cfg.ChannelID, cfg.TimeHHMM, cfg.Timezone,
+		strings.Join(cfg.Collections, ","), cfg.RevealHours, cfg.LockThread,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return nil
+}
+
+// GetSchedule retrieves a guild's schedule by name, returning sql.ErrNoRows
+// if there isn't one
+func (r *DailyRepository) GetSchedule(guildID, name string) (*DailyConfig, error) {
+	configs, err := r.query(`WHERE guild_id = ? AND name = ?`, guildID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+	if len(configs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &configs[0], nil
+}
+
+// ListSchedules returns the guild's schedules by name
+func (r *DailyRepository) ListSchedules(guildID string) ([]DailyConfig, error) {
+	configs, err := r.query(`WHERE guild_id = ? ORDER BY name`, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	return configs, nil
+}
+
+// DeleteSchedule removes a guild's schedule, returning sql.ErrNoRows if
+// there wasn't one
+func (r *DailyRepository) DeleteSchedule(guildID, name string) error {
+	res, err := r.db.Exec(`DELETE FROM daily_config WHERE guild_id = ? AND name = ?`, guildID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListConfigs returns every schedule of every guild
+func (r *DailyRepository) ListConfigs() ([]DailyConfig, error) {
+	configs, err := r.query(``)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configs: %w", err)
+	}
+	return configs, nil
+}
+
+// query runs a SELECT over daily_config with the given clause
+func (r *DailyRepository) query(clause string, args ...any) ([]DailyConfig, error) {
+	rows, err := r.db.Query(
+		`SELECT guild_id, name, channel_id, time_hhmm, timezone, collections, reveal_hours, lock_thread
+         FROM daily_config `+clause,
+		args...,
 	)
-	var cfg DailyConfig
-	if err := row.Scan(&cfg.GuildID, &cfg.ChannelID, &cfg.TimeHHMM); err != nil {
-		if err == sql.ErrNoRows {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []DailyConfig
+	for rows.Next() {
+		var cfg DailyConfig
+		var collections string
+		if err := rows.Scan(&cfg.GuildID, &cfg.Name, &cfg.ChannelID, &cfg.TimeHHMM, &cfg.Timezone,
+			&collections, &cfg.RevealHours, &cfg.LockThread); err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("failed to get config: %w", err)
+		if collections != "" {
+			cfg.Collections = strings.Split(collections, ",")
+		}
+		configs = append(configs, cfg)
 	}
-	return &cfg, nil
+	return configs, rows.Err()
 }
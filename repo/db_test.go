@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := InitDBConnection(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrationsRecordVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := InitDBConnection(path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	db.Close()
+
+	// reopening must not re-run migrations
+	db, err = InitDBConnection(path)
+	if err != nil {
+		t.Fatalf("failed to reopen db: %v", err)
+	}
+	defer db.Close()
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatalf("failed to read version: %v", err)
+	}
+	if version != len(migrations) {
+		t.Errorf("expected schema version %d, got %d", len(migrations), version)
+	}
+}
+
+func TestSetAndGetConfig(t *testing.T) {
+	r := InitDailyRepository(openTestDB(t))
+
+	if _, err := r.GetConfig("g1"); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows for missing config, got %v", err)
+	}
+	if err := r.SetConfig("g1", "c1", "08:00", "Europe/Berlin"); err != nil {
+		t.Fatalf("SetConfig returned error: %v", err)
+	}
+	if err := r.SetConfig("g1", "c2", "09:30", "UTC"); err != nil {
+		t.Fatalf("SetConfig returned error: %v", err)
+	}
+
+	cfg, err := r.GetConfig("g1")
+	if err != nil {
+		t.Fatalf("GetConfig returned error: %v", err)
+	}
+	if cfg.ChannelID != "c2" || cfg.TimeHHMM != "09:30" || cfg.Timezone != "UTC" {
+		t.Errorf("expected updated config, got %+v", cfg)
+	}
+
+	configs, err := r.ListConfigs()
+	if err != nil || len(configs) != 1 {
+		t.Errorf("expected one config, got %v (%v)", configs, err)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	r := InitDailyRepository(openTestDB(t))
+
+	if err := r.SetConfig("g1", "c1", "08:00", "UTC"); err != nil {
+		t.Fatal(err)
+	}
+	advanced := DailyConfig{
+		GuildID: "g1", Name: "advanced", ChannelID: "c2", TimeHHMM: "20:00", Timezone: "Europe/Berlin",
+		Collections: []string{"cho-hard"}, RevealHours: 12, LockThread: true,
+	}
+	if err := r.SaveSchedule(advanced); err != nil {
+		t.Fatalf("SaveSchedule returned error: %v", err)
+	}
+
+	schedules, err := r.ListSchedules("g1")
+	if err != nil || len(schedules) != 2 {
+		t.Fatalf("expected two schedules, got %v (%v)", schedules, err)
+	}
+	got := schedules[0]
+	if got.Name != "advanced" || got.Collections[0] != "cho-hard" || got.RevealHours != 12 || !got.LockThread {
+		t.Errorf("expected the advanced schedule back, got %+v", got)
+	}
+	if schedules[1].Name != DefaultSchedule || schedules[1].RevealHours != InheritReveal {
+		t.Errorf("expected the default schedule to inherit reveals, got %+v", schedules[1])
+	}
+
+	if err := r.DeleteSchedule("g1", "advanced"); err != nil {
+		t.Fatalf("DeleteSchedule returned error: %v", err)
+	}
+	if err := r.DeleteSchedule("g1", "advanced"); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows deleting a missing schedule, got %v", err)
+	}
+}
+
+func TestSchedulesMigrationKeepsExistingConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the database as it was before named schedules
+	steps := []string{
+		`CREATE TABLE daily_config (guild_id TEXT PRIMARY KEY, channel_id TEXT NOT NULL, time_hhmm TEXT NOT NULL)`,
+	}
+	steps = append(steps, migrations[:5]...)
+	steps = append(steps,
+		`PRAGMA user_version = 5`,
+		`INSERT INTO daily_config VALUES ('g1', 'c1', '08:00', 'Europe/Berlin')`,
+		`INSERT INTO jobs(guild_id, kind, key, payload, due_at, run_at) VALUES ('g1', 'post', '2025-01-01', '', 0, 0)`,
+		`INSERT INTO jobs(guild_id, kind, key, payload, due_at, run_at) VALUES ('g1', 'reveal', 't1', 'cho-easy-1', 0, 0)`,
+	)
+	for _, step := range steps {
+		if _, err := db.Exec(step); err != nil {
+			t.Fatalf("failed to set up old schema: %v", err)
+		}
+	}
+	db.Close()
+
+	db, err = InitDBConnection(path)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	defer db.Close()
+	cfg, err := InitDailyRepository(db).GetConfig("g1")
+	if err != nil || cfg.ChannelID != "c1" || cfg.Timezone != "Europe/Berlin" {
+		t.Errorf("expected the config to become the default schedule, got %+v (%v)", cfg, err)
+	}
+	jobs, _ := InitJobRepository(db).Recent("g1", 10)
+	keys := map[string]string{}
+	for _, j := range jobs {
+		keys[j.Key] = j.Payload
+	}
+	if keys["default/2025-01-01"] != "default" || keys["t1"] != `{"problem":"cho-easy-1"}` {
+		t.Errorf("expected jobs to be rewritten for schedules, got %v", keys)
+	}
+}
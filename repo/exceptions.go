@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Exception kinds
+const (
+	ExceptionPause    = "pause"    // no dailies from From to To
+	ExceptionSkip     = "skip"     // no daily on one day
+	ExceptionOverride = "override" // post ProblemID on one day
+)
+
+// Exception changes what a guild's schedules post on some days. Days are
+// YYYY-MM-DD in each schedule's own zone.
+type Exception struct {
+	ID        int64
+	GuildID   string
+	Schedule  string // empty applies to every schedule
+	Kind      string
+	From      string
+	To        string
+	ProblemID string
+	Note      string
+}
+
+// ExceptionRepository stores schedule exceptions
+type ExceptionRepository struct {
+	db *sql.DB
+}
+
+// InitExceptionRepository returns a new repository bound to db
+func InitExceptionRepository(db *sql.DB) *ExceptionRepository {
+	return &ExceptionRepository{db: db}
+}
+
+// AddException stores an exception and returns its ID
+func (r *ExceptionRepository) AddException(e Exception) (int64, error) {
+	res, err := r.db.Exec(
+		`INSERT INTO schedule_exceptions(guild_id, schedule, kind, start_day, end_day, problem_id, note)
+         VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		e.GuildID, e.Schedule, e.Kind, e.From, e.To, e.ProblemID, e.Note,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add exception: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListExceptions returns the guild's exceptions ending on or after day,
+// earliest first
+func (r *ExceptionRepository) ListExceptions(guildID, day string) ([]Exception, error) {
+	return r.query(`WHERE guild_id = ? AND end_day >= ? ORDER BY start_day, id`, guildID, day)
+}
+
+// ForDay returns the exceptions covering day for the guild's schedule
+func (r *ExceptionRepository) ForDay(guildID, schedule, day string) ([]Exception, error) {
+	return r.query(
+		`WHERE guild_id = ? AND (schedule = '' OR schedule = ?) AND start_day <= ? AND end_day >= ?
+         ORDER BY id`,
+		guildID, schedule, day, day,
+	)
+}
+
+// DeleteException removes one of the guild's exceptions, returning
+// sql.ErrNoRows if there wasn't one
+func (r *ExceptionRepository) DeleteException(guildID string, id int64) error {
+	res, err := r.db.Exec(`DELETE FROM schedule_exceptions WHERE guild_id = ? AND id = ?`, guildID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete exception: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// query runs a SELECT over schedule_exceptions with the given clause
+func (r *ExceptionRepository) query(clause string, args ...any) ([]Exception, error) {
+	rows, err := r.db.Query(
+		`SELECT id, guild_id, schedule, kind, start_day, end_day, problem_id, note
+         FROM schedule_exceptions `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exceptions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Exception
+	for rows.Next() {
+		var e Exception
+		if err := rows.Scan(&e.ID, &e.GuildID, &e.Schedule, &e.Kind, &e.From, &e.To, &e.ProblemID, &e.Note); err != nil {
+			return nil, fmt.Errorf("failed to list exceptions: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestExceptionsForDay(t *testing.T) {
+	r := InitExceptionRepository(openTestDB(t))
+
+	for _, e := range []Exception{
+		{GuildID: "g1", Kind: ExceptionPause, From: "2025-03-10", To: "2025-03-12", Note: "tournament"},
+		{GuildID: "g1", Schedule: "advanced", Kind: ExceptionSkip, From: "2025-03-20", To: "2025-03-20"},
+		{GuildID: "g1", Kind: ExceptionOverride, From: "2025-12-25", To: "2025-12-25", ProblemID: "cho-easy-1"},
+	} {
+		if _, err := r.AddException(e); err != nil {
+			t.Fatalf("AddException returned error: %v", err)
+		}
+	}
+
+	if got, _ := r.ForDay("g1", "beginner", "2025-03-11"); len(got) != 1 || got[0].Note != "tournament" {
+		t.Errorf("expected the pause to cover every schedule, got %+v", got)
+	}
+	if got, _ := r.ForDay("g1", "beginner", "2025-03-20"); len(got) != 0 {
+		t.Errorf("expected the skip to apply only to advanced, got %+v", got)
+	}
+	if got, _ := r.ForDay("g1", "advanced", "2025-03-20"); len(got) != 1 {
+		t.Errorf("expected the skip for advanced, got %+v", got)
+	}
+
+	upcoming, err := r.ListExceptions("g1", "2025-03-13")
+	if err != nil || len(upcoming) != 2 {
+		t.Fatalf("expected two upcoming exceptions, got %+v (%v)", upcoming, err)
+	}
+	if err := r.DeleteException("g1", upcoming[0].ID); err != nil {
+		t.Fatalf("DeleteException returned error: %v", err)
+	}
+	if err := r.DeleteException("g2", upcoming[1].ID); err != sql.ErrNoRows {
+		t.Errorf("expected another guild's exception to be left alone, got %v", err)
+	}
+}
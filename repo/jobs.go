@@ -0,0 +1,244 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Job kinds
+const (
+	JobPost     = "post"     // Key is "schedule/date", Payload the schedule name
+	JobReveal   = "reveal"   // Key is the thread, Payload JSON describing the reveal
+	JobReminder = "reminder" // Key is the thread, Payload as for JobReveal
+	JobDM       = "dm"       // Key is "schedule/date" and a part number, Payload JSON describing the daily
+)
+
+// Job statuses
+const (
+	JobPending = "pending"
+	JobRunning = "running" // claimed by whoever is running it
+	JobDone    = "done"
+	JobFailed  = "failed"  // gave up after too many attempts
+	JobSkipped = "skipped" // missed while the bot was down, per the guild's policy
+)
+
+// Catch-up policies for jobs missed while the bot was down
+const (
+	CatchUpRun  = "run"
+	CatchUpSkip = "skip"
+)
+
+// Job is a scheduled post, reveal or reminder. A guild has at most one job
+// per kind and key, so re-scheduling the same thing updates it.
+type Job struct {
+	ID        int64
+	GuildID   string
+	Kind      string
+	Key       string
+	Payload   string
+	DueAt     time.Time // when it was meant to run
+	RunAt     time.Time // when it will next be tried
+	Status    string
+	Attempts  int
+	LastError string
+	UpdatedAt time.Time
+}
+
+// JobRepository stores scheduled jobs and each guild's catch-up policy
+type JobRepository struct {
+	db *sql.DB
+}
+
+// InitJobRepository returns a new repository bound to db
+func InitJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Schedule adds a pending job, or moves an existing one that hasn't run yet
+// to the new time. Jobs that already ran are left alone.
+func (r *JobRepository) Schedule(job Job) error {
+	_, err := r.db.Exec(
+		`INSERT INTO jobs(guild_id, kind, key, payload, due_at, run_at, updated_at) VALUES(?, ?, ?, ?, ?, ?, ?)
+         ON CONFLICT(guild_id, kind, key) DO UPDATE SET
+             payload=excluded.payload, due_at=excluded.due_at, run_at=excluded.run_at, updated_at=excluded.updated_at
+         WHERE status = 'pending' AND attempts = 0;`,
+		job.GuildID, job.Kind, job.Key, job.Payload, job.DueAt.Unix(), job.DueAt.Unix(), time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to schedule job: %w", err)
+	}
+	return nil
+}
+
+// CancelFuture drops the guild's untried jobs of a kind whose key starts
+// with prefix and that are due after t, except the one with key keep
+func (r *JobRepository) CancelFuture(guildID, kind, prefix, keep string, t time.Time) error {
+	_, err := r.db.Exec(
+		`DELETE FROM jobs WHERE guild_id = ? AND kind = ? AND substr(key, 1, ?) = ? AND key != ?
+         AND status = 'pending' AND attempts = 0 AND due_at > ?`,
+		guildID, kind, len(prefix), prefix, keep, t.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel jobs: %w", err)
+	}
+	return nil
+}
+
+// CancelPending drops all of the guild's pending jobs of a kind whose key
+// starts with prefix, including ones waiting to be retried
+func (r *JobRepository) CancelPending(guildID, kind, prefix string) error {
+	_, err := r.db.Exec(
+		`DELETE FROM jobs WHERE guild_id = ? AND kind = ? AND substr(key, 1, ?) = ? AND status = 'pending'`,
+		guildID, kind, len(prefix), prefix,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel jobs: %w", err)
+	}
+	return nil
+}
+
+// Due lists the pending jobs whose run time has come
+func (r *JobRepository) Due(now time.Time) ([]Job, error) {
+	return r.query(
+		`WHERE status = 'pending' AND run_at <= ? ORDER BY run_at, id`, now.Unix(),
+	)
+}
+
+// Recent lists the guild's most recently scheduled or updated jobs
+func (r *JobRepository) Recent(guildID string, limit int) ([]Job, error) {
+	return r.query(
+		`WHERE guild_id = ? ORDER BY MAX(due_at, updated_at) DESC, id DESC LIMIT ?`, guildID, limit,
+	)
+}
+
+// Pending returns the guild's pending job of a kind and key, or
+// sql.ErrNoRows if there isn't one
+func (r *JobRepository) Pending(guildID, kind, key string) (*Job, error) {
+	jobs, err := r.query(`WHERE guild_id = ? AND kind = ? AND key = ? AND status = 'pending'`, guildID, kind, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if len(jobs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &jobs[0], nil
+}
+
+// Claim marks a pending job as running, so nothing else runs it at the
+// same time, and reports whether this caller got it
+func (r *JobRepository) Claim(id int64) (bool, error) {
+	res, err := r.db.Exec(
+		`UPDATE jobs SET status = ?, updated_at = ? WHERE id = ? AND status = 'pending'`,
+		JobRunning, time.Now().Unix(), id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// ReleaseRunning returns jobs that were claimed but never finished, as when
+// the bot stopped while running them, to pending
+func (r *JobRepository) ReleaseRunning() error {
+	_, err := r.db.Exec(`UPDATE jobs SET status = 'pending', updated_at = ? WHERE status = 'running'`, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to release jobs: %w", err)
+	}
+	return nil
+}
+
+// Finish marks a job as done
+func (r *JobRepository) Finish(id int64) error {
+	return r.setStatus(id, JobDone, "")
+}
+
+// Skip marks a job as skipped, recording why
+func (r *JobRepository) Skip(id int64, reason string) error {
+	return r.setStatus(id, JobSkipped, reason)
+}
+
+// Fail records a failed attempt. The job is tried again at retryAt, or
+// given up on when retryAt is zero.
+func (r *JobRepository) Fail(id int64, jobErr error, retryAt time.Time) error {
+	status, runAt := JobPending, retryAt.Unix()
+	if retryAt.IsZero() {
+		status, runAt = JobFailed, time.Now().Unix()
+	}
+	_, err := r.db.Exec(
+		`UPDATE jobs SET status = ?, attempts = attempts + 1, last_error = ?, run_at = ?, updated_at = ?
+         WHERE id = ?`,
+		status, jobErr.Error(), runAt, time.Now().Unix(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record job failure: %w", err)
+	}
+	return nil
+}
+
+// CatchUpPolicy returns whether the guild's missed jobs run late or are
+// skipped, defaulting to running them
+func (r *JobRepository) CatchUpPolicy(guildID string) (string, error) {
+	policy := CatchUpRun
+	err := r.db.QueryRow(
+		`SELECT policy FROM catch_up_policy WHERE guild_id = ?`, guildID,
+	).Scan(&policy)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get catch-up policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetCatchUpPolicy stores the guild's catch-up policy
+func (r *JobRepository) SetCatchUpPolicy(guildID, policy string) error {
+	if policy != CatchUpRun && policy != CatchUpSkip {
+		return fmt.Errorf("unknown catch-up policy %q", policy)
+	}
+	_, err := r.db.Exec(
+		`INSERT INTO catch_up_policy(guild_id, policy) VALUES(?, ?)
+         ON CONFLICT(guild_id) DO UPDATE SET policy=excluded.policy;`,
+		guildID, policy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set catch-up policy: %w", err)
+	}
+	return nil
+}
+
+func (r *JobRepository) setStatus(id int64, status, lastError string) error {
+	_, err := r.db.Exec(
+		`UPDATE jobs SET status = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+		status, lastError, time.Now().Unix(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	return nil
+}
+
+// query runs a SELECT over jobs with the given clause
+func (r *JobRepository) query(clause string, args ...any) ([]Job, error) {
+	rows, err := r.db.Query(
+		`SELECT id, guild_id, kind, key, payload, due_at, run_at, status, attempts, last_error, updated_at
+         FROM jobs `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var j Job
+		var due, run, updated int64
+		if err := rows.Scan(&j.ID, &j.GuildID, &j.Kind, &j.Key, &j.Payload, &due, &run,
+			&j.Status, &j.Attempts, &j.LastError, &updated); err != nil {
+			return nil, fmt.Errorf("failed to list jobs: %w", err)
+		}
+		j.DueAt, j.RunAt, j.UpdatedAt = time.Unix(due, 0), time.Unix(run, 0), time.Unix(updated, 0)
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
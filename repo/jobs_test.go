@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleAndRunJobs(t *testing.T) {
+	r := InitJobRepository(openTestDB(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	post := Job{GuildID: "g1", Kind: JobPost, Key: "default/2025-01-01", DueAt: now.Add(-time.Hour)}
+	if err := r.Schedule(post); err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if err := r.Schedule(Job{GuildID: "g1", Kind: JobReveal, Key: "t1", Payload: "cho-easy-1", DueAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+
+	due, err := r.Due(now)
+	if err != nil {
+		t.Fatalf("Due returned error: %v", err)
+	}
+	if len(due) != 1 || due[0].Kind != JobPost || !due[0].DueAt.Equal(post.DueAt) {
+		t.Fatalf("expected only the post to be due, got %+v", due)
+	}
+	if err := r.Finish(due[0].ID); err != nil {
+		t.Fatalf("Finish returned error: %v", err)
+	}
+
+	// scheduling a finished job again doesn't revive it
+	if err := r.Schedule(post); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ := r.Due(now); len(due) != 0 {
+		t.Errorf("expected the finished post to stay done, got %+v", due)
+	}
+}
+
+func TestPendingFindsUnrunJob(t *testing.T) {
+	r := InitJobRepository(openTestDB(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := r.Schedule(Job{GuildID: "g1", Kind: JobReveal, Key: "t1", Payload: `{"problem":"p1"}`, DueAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := r.Pending("g1", JobReveal, "t1")
+	if err != nil {
+		t.Fatalf("Pending returned error: %v", err)
+	}
+	if job.Payload != `{"problem":"p1"}` {
+		t.Errorf("expected the reveal's payload, got %+v", job)
+	}
+	if _, err := r.Pending("g1", JobReminder, "t1"); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows for another kind, got %v", err)
+	}
+
+	r.Finish(job.ID)
+	if _, err := r.Pending("g1", JobReveal, "t1"); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows once the job ran, got %v", err)
+	}
+}
+
+func TestFailRetriesThenGivesUp(t *testing.T) {
+	r := InitJobRepository(openTestDB(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := r.Schedule(Job{GuildID: "g1", Kind: JobReveal, Key: "t1", DueAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	due, _ := r.Due(now)
+
+	if err := r.Fail(due[0].ID, errors.New("boom"), now.Add(5*time.Minute)); err != nil {
+		t.Fatalf("Fail returned error: %v", err)
+	}
+	if due, _ := r.Due(now); len(due) != 0 {
+		t.Errorf("expected the retry to wait, got %+v", due)
+	}
+	due, _ = r.Due(now.Add(5 * time.Minute))
+	if len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "boom" {
+		t.Fatalf("expected one retry with the error recorded, got %+v", due)
+	}
+
+	if err := r.Fail(due[0].ID, errors.New("boom again"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	jobs, _ := r.Recent("g1", 10)
+	if len(jobs) != 1 || jobs[0].Status != JobFailed || jobs[0].Attempts != 2 {
+		t.Errorf("expected the job to be given up on, got %+v", jobs)
+	}
+}
+
+func TestCancelFutureKeepsCurrentJob(t *testing.T) {
+	r := InitJobRepository(openTestDB(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.Schedule(Job{GuildID: "g1", Kind: JobPost, Key: "a/2025-01-01", DueAt: now.Add(time.Hour)})
+	r.Schedule(Job{GuildID: "g1", Kind: JobPost, Key: "a/2025-01-02", DueAt: now.Add(20 * time.Hour)})
+	r.Schedule(Job{GuildID: "g1", Kind: JobPost, Key: "b/2025-01-01", DueAt: now.Add(time.Hour)})
+
+	if err := r.CancelFuture("g1", JobPost, "a/", "a/2025-01-02", now); err != nil {
+		t.Fatalf("CancelFuture returned error: %v", err)
+	}
+	jobs, _ := r.Recent("g1", 10)
+	keys := map[string]bool{}
+	for _, j := range jobs {
+		keys[j.Key] = true
+	}
+	if len(keys) != 2 || !keys["a/2025-01-02"] || !keys["b/2025-01-01"] {
+		t.Errorf("expected only other schedules and the kept job to remain, got %v", keys)
+	}
+}
+
+func TestCancelPending(t *testing.T) {
+	r := InitJobRepository(openTestDB(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.Schedule(Job{GuildID: "g1", Kind: JobPost, Key: "a/2025-01-01", DueAt: now.Add(-time.Hour)})
+	r.Schedule(Job{GuildID: "g1", Kind: JobPost, Key: "a/2025-01-02", DueAt: now.Add(20 * time.Hour)})
+	r.Schedule(Job{GuildID: "g1", Kind: JobPost, Key: "ab/2025-01-01", DueAt: now.Add(time.Hour)})
+	due, _ := r.Due(now)
+	if len(due) != 1 {
+		t.Fatalf("expected one due job, got %+v", due)
+	}
+	// a failed try leaves the job pending for a retry
+	if err := r.Fail(due[0].ID, errors.New("boom"), now.Add(5*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.CancelPending("g1", JobPost, "a/"); err != nil {
+		t.Fatalf("CancelPending returned error: %v", err)
+	}
+	jobs, _ := r.Recent("g1", 10)
+	if len(jobs) != 1 || jobs[0].Key != "ab/2025-01-01" {
+		t.Errorf("expected only the other schedule's job to remain, got %+v", jobs)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	r := InitJobRepository(openTestDB(t))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.Schedule(Job{GuildID: "g1", Kind: JobReveal, Key: "t1", DueAt: now})
+	due, _ := r.Due(now)
+	if len(due) != 1 {
+		t.Fatalf("expected one due job, got %+v", due)
+	}
+
+	if ok, err := r.Claim(due[0].ID); err != nil || !ok {
+		t.Fatalf("expected the first claim to succeed, got %v, %v", ok, err)
+	}
+	if ok, _ := r.Claim(due[0].ID); ok {
+		t.Error("expected a second claim to fail")
+	}
+	if due, _ := r.Due(now); len(due) != 0 {
+		t.Errorf("expected a running job not to be due, got %+v", due)
+	}
+
+	if err := r.ReleaseRunning(); err != nil {
+		t.Fatalf("ReleaseRunning returned error: %v", err)
+	}
+	if ok, _ := r.Claim(due[0].ID); !ok {
+		t.Error("expected a released job to be claimable again")
+	}
+	if err := r.Finish(due[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := r.Claim(due[0].ID); ok {
+		t.Error("expected a finished job not to be claimable")
+	}
+}
+
+func TestCatchUpPolicy(t *testing.T) {
+	r := InitJobRepository(openTestDB(t))
+	if p, err := r.CatchUpPolicy("g1"); err != nil || p != CatchUpRun {
+		t.Errorf("expected %q by default, got %q (%v)", CatchUpRun, p, err)
+	}
+	if err := r.SetCatchUpPolicy("g1", CatchUpSkip); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := r.CatchUpPolicy("g1"); p != CatchUpSkip {
+		t.Errorf("expected %q, got %q", CatchUpSkip, p)
+	}
+	if err := r.SetCatchUpPolicy("g1", "later"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
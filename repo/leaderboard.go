@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+)
+
+// Leaderboard metrics
+const (
+	MetricSolves   = "solves"   // distinct problems solved
+	MetricAccuracy = "accuracy" // share of graded attempts that were correct
+	MetricSpeed    = "speed"    // average time from a daily being posted to the first correct answer
+	MetricStreak   = "streak"   // current daily streak
+)
+
+// LeaderboardMinimum is how many graded attempts, or timed solves for
+// speed, a member needs before they're ranked by accuracy or speed, so one
+// lucky answer doesn't top the board
+const LeaderboardMinimum = 3
+
+// LeaderboardQuery picks a guild's board
+type LeaderboardQuery struct {
+	GuildID string
+	Metric  string
+	Since   time.Time // only attempts from then on; zero for all time
+	Today   string    // YYYY-MM-DD in the guild's zone, to tell which streaks are alive
+}
+
+// LeaderboardEntry is one member's row on a board. Streak boards fill in
+// only the streak fields, and the others only the attempt fields.
+type LeaderboardEntry struct {
+	Rank     int // members who tie share a rank
+	UserID   string
+	Solves   int
+	Attempts int
+	Correct  int
+	Speed    time.Duration // 0 without timed solves
+	Streak   int
+	Best     int
+}
+
+// Leaderboard returns one page of a board and how many members are on it
+func (r *AttemptRepository) Leaderboard(q LeaderboardQuery, offset, limit int) ([]LeaderboardEntry, int, error) {
+	board, args, err := leaderboardQuery(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(
+		`SELECT user_id, solves, attempts, correct, speed, streak, best, rank, total FROM (`+board+`)
+         ORDER BY rank, user_id LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []LeaderboardEntry
+	total := 0
+	for rows.Next() {
+		e, err := scanEntry(rows.Scan, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to load leaderboard: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to load leaderboard: %w", err)
+	}
+	if total == 0 && offset > 0 {
+		// past the last page; count the board on its own
+		err := r.db.QueryRow(`SELECT COUNT(*) FROM (`+board+`)`, args...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count leaderboard: %w", err)
+		}
+	}
+	return entries, total, nil
+}
+
+// LeaderboardRank returns a member's row on a board, or sql.ErrNoRows if
+// they aren't on it
+func (r *AttemptRepository) LeaderboardRank(q LeaderboardQuery, userID string) (*LeaderboardEntry, error) {
+	board, args, err := leaderboardQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	var total int
+	e, err := scanEntry(r.db.QueryRow(
+		`SELECT user_id, solves, attempts, correct, speed, streak, best, rank, total FROM (`+board+`) WHERE user_id = ?`,
+		append(args, userID)...,
+	).Scan, &total)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// leaderboardQuery builds the SELECT ranking every member on a board. The
+// attempt boards aggregate once per member over the guild's attempts in the
+// period, which the attempts_guild index narrows down.
+func leaderboardQuery(q LeaderboardQuery) (string, []any, error) {
+	if q.Metric == MetricStreak {
+		return `SELECT user_id, 0 AS solves, 0 AS attempts, 0 AS correct, 0 AS speed, current AS streak, best,
+                RANK() OVER (ORDER BY current DESC) AS rank, COUNT(*) OVER () AS total
+            FROM streaks
+            WHERE guild_id = ? AND current > 0 AND julianday(?) - julianday(last_day) - 1 <= freezes`,
+			[]any{q.GuildID, q.Today}, nil
+	}
+
+	var filter, order string
+	switch q.Metric {
+	case MetricSolves:
+		filter, order = "solves > 0", "solves DESC, correct * 1.0 / attempts DESC"
+	case MetricAccuracy:
+		filter, order = fmt.Sprintf("attempts >= %d", LeaderboardMinimum), "correct * 1.0 / attempts DESC, attempts DESC"
+	case MetricSpeed:
+		filter, order = fmt.Sprintf("timed >= %d", LeaderboardMinimum), "speed"
+	default:
+		return "", nil, fmt.Errorf("unknown leaderboard metric %q", q.Metric)
+	}
+	var since int64
+	if !q.Since.IsZero() {
+		since = q.Since.Unix()
+	}
+	return `WITH period AS (
+                SELECT user_id, problem_id, source, result, took_ms FROM attempts
+                WHERE guild_id = ? AND created_at >= ? AND result != 'ungraded'
+            ), firsts AS (
+                SELECT user_id, MIN(took_ms) AS took FROM period
+                WHERE result = 'correct' AND source = 'daily' AND took_ms > 0
+                GROUP BY user_id, problem_id
+            ), timing AS (
+                SELECT user_id, CAST(AVG(took) AS INTEGER) AS speed, COUNT(*) AS timed FROM firsts GROUP BY user_id
+            ), stats AS (
+                SELECT p.user_id, COUNT(DISTINCT CASE WHEN p.result = 'correct' THEN p.problem_id END) AS solves,
+                    COUNT(*) AS attempts, SUM(p.result = 'correct') AS correct,
+                    COALESCE(t.speed, 0) AS speed, COALESCE(t.timed, 0) AS timed
+                FROM period p LEFT JOIN timing t ON t.user_id = p.user_id
+                GROUP BY p.user_id
+            )
+            SELECT user_id, solves, attempts, correct, speed, 0 AS streak, 0 AS best,
+                RANK() OVER (ORDER BY ` + order + `) AS rank, COUNT(*) OVER () AS total
+            FROM stats WHERE ` + filter,
+		[]any{q.GuildID, since}, nil
+}
+
+// scanEntry reads one board row, storing the board's size in total
+func scanEntry(scan func(...any) error, total *int) (LeaderboardEntry, error) {
+	var e LeaderboardEntry
+	var speedMs int64
+	err := scan(&e.UserID, &e.Solves, &e.Attempts, &e.Correct, &speedMs, &e.Streak, &e.Best, &e.Rank, total)
+	e.Speed = time.Duration(speedMs) * time.Millisecond
+	return e, err
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestLeaderboard(t *testing.T) {
+	db := openTestDB(t)
+	r := InitAttemptRepository(db)
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	record := func(user, problem, result string, took time.Duration, at time.Time) {
+		t.Helper()
+		a := Attempt{GuildID: "g1", UserID: user, ProblemID: problem, Source: SourceDaily, Result: result, Took: took, CreatedAt: at}
+		if _, err := r.Record(a); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+	// u1: three quick solves, one miss; u2: four slower solves, one of them before the week
+	for n, p := range []string{"p1", "p2", "p3"} {
+		at := start.Add(time.Duration(n) * 24 * time.Hour)
+		record("u1", p, ResultWrong, time.Minute, at)
+		record("u1", p, ResultCorrect, 2*time.Minute, at)
+		record("u1", p, ResultCorrect, 5*time.Minute, at) // later answers don't change the speed
+		record("u2", p, ResultCorrect, 10*time.Minute, at)
+	}
+	record("u2", "p0", ResultCorrect, 10*time.Minute, start.Add(-30*24*time.Hour))
+	record("u3", "p1", ResultWrong, time.Minute, start)
+	record("u3", "p9", ResultUngraded, time.Minute, start)
+
+	week := LeaderboardQuery{GuildID: "g1", Metric: MetricSolves, Since: start}
+	entries, total, err := r.Leaderboard(week, 0, 10)
+	if err != nil {
+		t.Fatalf("Leaderboard returned error: %v", err)
+	}
+	if total != 2 || len(entries) != 2 || entries[0].UserID != "u2" || entries[1].Rank != 2 {
+		t.Fatalf("expected u2 ahead of u1 on accuracy with 3 solves each and u3 left off, got %d %+v", total, entries)
+	}
+
+	allTime := week
+	allTime.Since = time.Time{}
+	if entries, _, _ := r.Leaderboard(allTime, 0, 10); entries[0].UserID != "u2" || entries[0].Solves != 4 || entries[1].Rank != 2 {
+		t.Errorf("expected u2 to lead all time, got %+v", entries)
+	}
+
+	week.Metric = MetricAccuracy
+	if entries, _, _ := r.Leaderboard(week, 0, 10); entries[0].UserID != "u2" || entries[1].Correct != 6 || entries[1].Attempts != 9 {
+		t.Errorf("expected u2 to lead on accuracy, got %+v", entries)
+	}
+
+	week.Metric = MetricSpeed
+	entries, _, _ = r.Leaderboard(week, 0, 1)
+	if len(entries) != 1 || entries[0].UserID != "u1" || entries[0].Speed != 2*time.Minute {
+		t.Errorf("expected u1 fastest at 2m, got %+v", entries)
+	}
+	me, err := r.LeaderboardRank(week, "u2")
+	if err != nil || me.Rank != 2 || me.Speed != 10*time.Minute {
+		t.Errorf("expected u2 second on speed, got %+v, %v", me, err)
+	}
+	if _, err := r.LeaderboardRank(week, "u3"); err != sql.ErrNoRows {
+		t.Errorf("expected ErrNoRows for a member off the board, got %v", err)
+	}
+	if entries, total, _ := r.Leaderboard(week, 10, 10); len(entries) != 0 || total != 2 {
+		t.Errorf("expected an empty page past the end with the total, got %d %+v", total, entries)
+	}
+
+	streaks := InitStreakRepository(db)
+	streaks.SaveStreak(Streak{GuildID: "g1", UserID: "u1", Current: 4, Best: 4, LastDay: "2025-06-09"})
+	streaks.SaveStreak(Streak{GuildID: "g1", UserID: "u2", Current: 9, Best: 9, LastDay: "2025-06-07", Freezes: 1})
+	streaks.SaveStreak(Streak{GuildID: "g1", UserID: "u3", Current: 20, Best: 20, LastDay: "2025-06-01"})
+	board := LeaderboardQuery{GuildID: "g1", Metric: MetricStreak, Today: "2025-06-09"}
+	entries, total, _ = r.Leaderboard(board, 0, 10)
+	if total != 2 || entries[0].UserID != "u2" || entries[0].Streak != 9 || entries[1].UserID != "u1" {
+		t.Errorf("expected live streaks only, frozen ones included, got %d %+v", total, entries)
+	}
+
+	if _, _, err := r.Leaderboard(LeaderboardQuery{GuildID: "g1", Metric: "karma"}, 0, 10); err == nil {
+		t.Errorf("expected an error for an unknown metric")
+	}
+}
@@ -0,0 +1,204 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Thread modes: whether /daily opens a thread per member or everyone shares
+// the day's thread
+const (
+	ThreadsPersonal = "personal"
+	ThreadsShared   = "shared"
+)
+
+// DailyPost is a daily posted in a thread (or forum post). Shared posts are
+// the one canonical thread for their guild, schedule and day.
+type DailyPost struct {
+	ThreadID   string
+	MessageID  string // the daily's message in the thread, if it was sent
+	GuildID    string
+	Schedule   string
+	Day        string // YYYY-MM-DD in the schedule's zone
+	ProblemID  string
+	Shared     bool
+	PostedAt   time.Time
+	RevealedAt time.Time // zero until the solution is posted
+	Players    int       // people who answered, counted at the reveal
+	Solved     int       // of those, the ones who got it right
+}
+
+// CountsForStreak reports whether a correct answer to the post, given on
+// today (YYYY-MM-DD in the schedule's zone), counts towards a streak: only
+// the day's own daily does, and only until its solution is posted
+func (p DailyPost) CountsForStreak(today string) bool {
+	return p.Day == today && p.RevealedAt.IsZero()
+}
+
+// PostRepository records daily posts, so answers and reveals can find
+// their problem and past dailies can be listed, and each guild's thread mode
+type PostRepository struct {
+	db *sql.DB
+}
+
+// InitPostRepository returns a new repository bound to db
+func InitPostRepository(db *sql.DB) *PostRepository {
+	return &PostRepository{db: db}
+}
+
+// RecordPost stores a new daily post. Recording a second shared post for
+// the same guild, schedule and day fails.
+func (r *PostRepository) RecordPost(p DailyPost) error {
+	_, err := r.db.Exec(
+		`INSERT INTO daily_posts(thread_id, message_id, guild_id, schedule, day, problem_id, shared, posted_at)
+         VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ThreadID, p.MessageID, p.GuildID, p.Schedule, p.Day, p.ProblemID, p.Shared, p.PostedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record post: %w", err)
+	}
+	return nil
+}
+
+// Post returns the daily posted in a thread, or sql.ErrNoRows if it isn't
+// a daily thread
+func (r *PostRepository) Post(threadID string) (*DailyPost, error) {
+	posts, err := r.query(`WHERE thread_id = ?`, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if len(posts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &posts[0], nil
+}
+
+// SharedPost returns the shared post of a guild's schedule on a day, or
+// sql.ErrNoRows if there isn't one yet
+func (r *PostRepository) SharedPost(guildID, schedule, day string) (*DailyPost, error) {
+	posts, err := r.query(`WHERE guild_id = ? AND schedule = ? AND day = ? AND shared`, guildID, schedule, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared post: %w", err)
+	}
+	if len(posts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &posts[0], nil
+}
+
+// MarkRevealed records that a thread's solution was posted and how the
+// thread did
+func (r *PostRepository) MarkRevealed(threadID string, at time.Time, players, solved int) error {
+	_, err := r.db.Exec(
+		`UPDATE daily_posts SET revealed_at = ?, players = ?, solved = ? WHERE thread_id = ?`,
+		at.Unix(), players, solved, threadID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark post revealed: %w", err)
+	}
+	return nil
+}
+
+// HistoryFilter narrows a guild's history to a schedule or problem; empty
+// fields match everything
+type HistoryFilter struct {
+	Schedule  string
+	ProblemID string
+}
+
+// History lists a guild's dailies newest first, one per schedule and day,
+// along with how many there are in total. Days with a thread per member
+// show the first thread, with players and solves summed over all of them.
+func (r *PostRepository) History(guildID string, filter HistoryFilter, offset, limit int) ([]DailyPost, int, error) {
+	where := `WHERE guild_id = ? AND (? = '' OR schedule = ?) AND (? = '' OR problem_id = ?)`
+	args := []any{guildID, filter.Schedule, filter.Schedule, filter.ProblemID, filter.ProblemID}
+
+	var total int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM (SELECT 1 FROM daily_posts `+where+` GROUP BY schedule, day)`, args...,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count history: %w", err)
+	}
+
+	rows, err := r.db.Query(
+		`WITH days AS (
+             SELECT MIN(rowid) AS first, MAX(revealed_at) AS revealed_at, SUM(players) AS players, SUM(solved) AS solved
+             FROM daily_posts `+where+` GROUP BY schedule, day
+         )
+         SELECT p.thread_id, p.message_id, p.guild_id, p.schedule, p.day, p.problem_id, p.shared, p.posted_at,
+             days.revealed_at, days.players, days.solved
+         FROM days JOIN daily_posts p ON p.rowid = days.first
+         ORDER BY p.day DESC, p.schedule LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list history: %w", err)
+	}
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list history: %w", err)
+	}
+	return posts, total, nil
+}
+
+// ThreadMode returns the guild's thread mode, defaulting to personal threads
+func (r *PostRepository) ThreadMode(guildID string) (string, error) {
+	mode := ThreadsPersonal
+	err := r.db.QueryRow(`SELECT mode FROM thread_mode WHERE guild_id = ?`, guildID).Scan(&mode)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get thread mode: %w", err)
+	}
+	return mode, nil
+}
+
+// SetThreadMode stores the guild's thread mode
+func (r *PostRepository) SetThreadMode(guildID, mode string) error {
+	if mode != ThreadsPersonal && mode != ThreadsShared {
+		return fmt.Errorf("unknown thread mode %q", mode)
+	}
+	_, err := r.db.Exec(
+		`INSERT INTO thread_mode(guild_id, mode) VALUES(?, ?)
+         ON CONFLICT(guild_id) DO UPDATE SET mode=excluded.mode;`,
+		guildID, mode,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set thread mode: %w", err)
+	}
+	return nil
+}
+
+// query runs a SELECT over daily_posts with the given clause
+func (r *PostRepository) query(clause string, args ...any) ([]DailyPost, error) {
+	rows, err := r.db.Query(
+		`SELECT thread_id, message_id, guild_id, schedule, day, problem_id, shared, posted_at,
+             revealed_at, players, solved
+         FROM daily_posts `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanPosts(rows)
+}
+
+// scanPosts reads rows of the columns selected by query, closing them
+func scanPosts(rows *sql.Rows) ([]DailyPost, error) {
+	defer rows.Close()
+	var posts []DailyPost
+	for rows.Next() {
+		var p DailyPost
+		var postedAt, revealedAt int64
+		if err := rows.Scan(&p.ThreadID, &p.MessageID, &p.GuildID, &p.Schedule, &p.Day, &p.ProblemID, &p.Shared,
+			&postedAt, &revealedAt, &p.Players, &p.Solved); err != nil {
+			return nil, err
+		}
+		p.PostedAt = time.Unix(postedAt, 0)
+		if revealedAt > 0 {
+			p.RevealedAt = time.Unix(revealedAt, 0)
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestSharedPostIsUniquePerDay(t *testing.T) {
+	r := InitPostRepository(openTestDB(t))
+
+	postedAt := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	shared := DailyPost{ThreadID: "t1", MessageID: "m1", GuildID: "g1", Schedule: "default", Day: "2025-06-01", ProblemID: "p1", Shared: true, PostedAt: postedAt}
+	if err := r.RecordPost(shared); err != nil {
+		t.Fatalf("RecordPost returned error: %v", err)
+	}
+	got, err := r.SharedPost("g1", "default", "2025-06-01")
+	if err != nil {
+		t.Fatalf("SharedPost returned error: %v", err)
+	}
+	if got.ThreadID != "t1" || got.MessageID != "m1" || got.ProblemID != "p1" || !got.PostedAt.Equal(postedAt) || !got.RevealedAt.IsZero() {
+		t.Errorf("expected %+v, got %+v", shared, got)
+	}
+
+	again := shared
+	again.ThreadID = "t2"
+	if err := r.RecordPost(again); err == nil {
+		t.Errorf("expected a second shared thread for the day to be rejected")
+	}
+
+	// personal threads and other schedules don't clash
+	for _, th := range []DailyPost{
+		{ThreadID: "t3", GuildID: "g1", Schedule: "default", Day: "2025-06-01", ProblemID: "p1"},
+		{ThreadID: "t4", GuildID: "g1", Schedule: "default", Day: "2025-06-01", ProblemID: "p1"},
+		{ThreadID: "t5", GuildID: "g1", Schedule: "hard", Day: "2025-06-01", ProblemID: "p2", Shared: true},
+	} {
+		if err := r.RecordPost(th); err != nil {
+			t.Errorf("RecordPost(%s) returned error: %v", th.ThreadID, err)
+		}
+	}
+
+	if th, err := r.Post("t4"); err != nil || th.ProblemID != "p1" || th.Shared {
+		t.Errorf("expected personal thread t4, got %+v (%v)", th, err)
+	}
+	if _, err := r.Post("nope"); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows for an unknown thread, got %v", err)
+	}
+	if _, err := r.SharedPost("g1", "default", "2025-06-02"); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows for a day without a thread, got %v", err)
+	}
+}
+
+func TestCountsForStreak(t *testing.T) {
+	revealedAt := time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		post  DailyPost
+		today string
+		want  bool
+	}{
+		{"today's daily", DailyPost{Day: "2025-06-01"}, "2025-06-01", true},
+		{"yesterday's daily", DailyPost{Day: "2025-05-31"}, "2025-06-01", false},
+		{"after the reveal", DailyPost{Day: "2025-06-01", RevealedAt: revealedAt}, "2025-06-01", false},
+	}
+	for _, tt := range tests {
+		if got := tt.post.CountsForStreak(tt.today); got != tt.want {
+			t.Errorf("%s: CountsForStreak(%s) = %v, want %v", tt.name, tt.today, got, tt.want)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	r := InitPostRepository(openTestDB(t))
+	for _, p := range []DailyPost{
+		{ThreadID: "a1", GuildID: "g1", Schedule: "default", Day: "2025-06-01", ProblemID: "p1"},
+		{ThreadID: "a2", GuildID: "g1", Schedule: "default", Day: "2025-06-01", ProblemID: "p1"},
+		{ThreadID: "b1", GuildID: "g1", Schedule: "default", Day: "2025-06-02", ProblemID: "p2", Shared: true},
+		{ThreadID: "c1", GuildID: "g1", Schedule: "hard", Day: "2025-06-02", ProblemID: "p1", Shared: true},
+		{ThreadID: "d1", GuildID: "g2", Schedule: "default", Day: "2025-06-03", ProblemID: "p3", Shared: true},
+	} {
+		if err := r.RecordPost(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	revealedAt := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	r.MarkRevealed("a1", revealedAt, 3, 1)
+	r.MarkRevealed("a2", revealedAt, 2, 2)
+
+	posts, total, err := r.History("g1", HistoryFilter{}, 0, 2)
+	if err != nil {
+		t.Fatalf("History returned error: %v", err)
+	}
+	if total != 3 || len(posts) != 2 || posts[0].ThreadID != "b1" || posts[1].ThreadID != "c1" {
+		t.Fatalf("expected the first page of 3 days, newest first, got %d: %+v", total, posts)
+	}
+
+	posts, _, _ = r.History("g1", HistoryFilter{}, 2, 2)
+	if len(posts) != 1 || posts[0].ThreadID != "a1" {
+		t.Fatalf("expected 1 June's first thread on the second page, got %+v", posts)
+	}
+	if p := posts[0]; p.Players != 5 || p.Solved != 3 || !p.RevealedAt.Equal(revealedAt) {
+		t.Errorf("expected both threads' answers summed, got %+v", p)
+	}
+
+	// looking for repeats of a problem
+	posts, total, _ = r.History("g1", HistoryFilter{ProblemID: "p1"}, 0, 10)
+	if total != 2 || len(posts) != 2 {
+		t.Errorf("expected p1 on two days, got %d: %+v", total, posts)
+	}
+	if _, total, _ = r.History("g1", HistoryFilter{Schedule: "hard"}, 0, 10); total != 1 {
+		t.Errorf("expected one day for the hard schedule, got %d", total)
+	}
+}
+
+func TestThreadMode(t *testing.T) {
+	r := InitPostRepository(openTestDB(t))
+	if mode, err := r.ThreadMode("g1"); err != nil || mode != ThreadsPersonal {
+		t.Errorf("expected personal threads by default, got %q (%v)", mode, err)
+	}
+	if err := r.SetThreadMode("g1", ThreadsShared); err != nil {
+		t.Fatalf("SetThreadMode returned error: %v", err)
+	}
+	if mode, _ := r.ThreadMode("g1"); mode != ThreadsShared {
+		t.Errorf("expected shared threads, got %q", mode)
+	}
+	if err := r.SetThreadMode("g1", "sometimes"); err == nil {
+		t.Errorf("expected an unknown mode to be rejected")
+	}
+}
@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// Progress is a guild's place in its shuffled problem order
+type Progress struct {
+	GuildID       string
+	Seed          int64
+	Cycle         int      // first cycle that counts; picks before it are forgotten
+	NextProblemID string   // staff override for the next pick
+	Collections   []string // enabled collection IDs; empty means all
+	Rotation      string   // difficulty rotation; empty means the default
+}
+
+// DailyPick records which problem one of a guild's schedules got on a day
+type DailyPick struct {
+	Schedule  string
+	Cycle     int
+	ProblemID string
+	Day       string // YYYY-MM-DD in the schedule's zone
+}
+
+// ProgressRepository stores each guild's problem progression
+type ProgressRepository struct {
+	db *sql.DB
+}
+
+// InitProgressRepository returns a new repository bound to db
+func InitProgressRepository(db *sql.DB) *ProgressRepository {
+	return &ProgressRepository{db: db}
+}
+
+// GetProgress returns the guild's progression, starting one with a random
+// seed the first time the guild is seen
+func (r *ProgressRepository) GetProgress(guildID string) (*Progress, error) {
+	_, err := r.db.Exec(
+		`INSERT INTO guild_progress(guild_id, seed) VALUES(?, ?)
+         ON CONFLICT(guild_id) DO NOTHING;`,
+		guildID, rand.Int64(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create progress: %w", err)
+	}
+
+	row := r.db.QueryRow(
+		`SELECT guild_id, seed, cycle, next_problem_id, collections, rotation FROM guild_progress WHERE guild_id = ?`,
+		guildID,
+	)
+	var p Progress
+	var collections string
+	if err := row.Scan(&p.GuildID, &p.Seed, &p.Cycle, &p.NextProblemID, &collections, &p.Rotation); err != nil {
+		return nil, fmt.Errorf("failed to get progress: %w", err)
+	}
+	if collections != "" {
+		p.Collections = strings.Split(collections, ",")
+	}
+	return &p, nil
+}
+
+// PickForDay returns the problem already picked for the guild's schedule
+// on day, or sql.ErrNoRows if there isn't one
+func (r *ProgressRepository) PickForDay(guildID, schedule, day string) (string, error) {
+	var id string
+	err := r.db.QueryRow(
+		`SELECT problem_id FROM daily_picks WHERE guild_id = ? AND schedule = ? AND day = ?`,
+		guildID, schedule, day,
+	).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to get pick: %w", err)
+	}
+	return id, nil
+}
+
+// LastCycles maps each problem the guild has been given to the latest
+// cycle it was picked in
+func (r *ProgressRepository) LastCycles(guildID string) (map[string]int, error) {
+	rows, err := r.db.Query(
+		`SELECT problem_id, MAX(cycle) FROM daily_picks WHERE guild_id = ? GROUP BY problem_id`,
+		guildID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list picked problems: %w", err)
+	}
+	defer rows.Close()
+
+	last := map[string]int{}
+	for rows.Next() {
+		var id string
+		var cycle int
+		if err := rows.Scan(&id, &cycle); err != nil {
+			return nil, fmt.Errorf("failed to list picked problems: %w", err)
+		}
+		last[id] = cycle
+	}
+	return last, rows.Err()
+}
+
+// RecordPick stores the day's pick for its schedule, replacing only an
+// earlier pick for the same schedule and day, and clears the staff jump if
+// this was it
+func (r *ProgressRepository) RecordPick(guildID string, pick DailyPick) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to record pick: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`INSERT INTO daily_picks(guild_id, schedule, cycle, problem_id, day) VALUES(?, ?, ?, ?, ?)
+         ON CONFLICT(guild_id, schedule, day) DO UPDATE SET cycle=excluded.cycle, problem_id=excluded.problem_id;`,
+		guildID, pick.Schedule, pick.Cycle, pick.ProblemID, pick.Day,
+	); err != nil {
+		return fmt.Errorf("failed to record pick: %w", err)
+	}
+	if _, err := tx.Exec(
+		`UPDATE guild_progress SET next_problem_id = '' WHERE guild_id = ? AND next_problem_id = ?`,
+		guildID, pick.ProblemID,
+	); err != nil {
+		return fmt.Errorf("failed to record pick: %w", err)
+	}
+	return tx.Commit()
+}
+
+// PicksBetween lists the guild's picks on every schedule from one day to
+// another, inclusive
+func (r *ProgressRepository) PicksBetween(guildID, from, to string) ([]DailyPick, error) {
+	rows, err := r.db.Query(
+		`SELECT schedule, cycle, problem_id, day FROM daily_picks
+         WHERE guild_id = ? AND day BETWEEN ? AND ? ORDER BY day, rowid`,
+		guildID, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list picks: %w", err)
+	}
+	defer rows.Close()
+
+	var picks []DailyPick
+	for rows.Next() {
+		var p DailyPick
+		if err := rows.Scan(&p.Schedule, &p.Cycle, &p.ProblemID, &p.Day); err != nil {
+			return nil, fmt.Errorf("failed to list picks: %w", err)
+		}
+		picks = append(picks, p)
+	}
+	return picks, rows.Err()
+}
+
+// Reseed replaces the guild's seed and starts every pool on a new cycle in
+// the new order, past any cycle already picked in
+func (r *ProgressRepository) Reseed(guildID string, seed int64) error {
+	if _, err := r.GetProgress(guildID); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(
+		`UPDATE guild_progress SET seed = ?, cycle = MAX(cycle,
+             (SELECT COALESCE(MAX(cycle), 0) FROM daily_picks WHERE guild_id = ?)) + 1
+         WHERE guild_id = ?`,
+		seed, guildID, guildID,
+	); err != nil {
+		return fmt.Errorf("failed to reseed: %w", err)
+	}
+	return nil
+}
+
+// SetNextProblem makes problemID the guild's next pick
+func (r *ProgressRepository) SetNextProblem(guildID, problemID string) error {
+	if _, err := r.GetProgress(guildID); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(
+		`UPDATE guild_progress SET next_problem_id = ? WHERE guild_id = ?`, problemID, guildID,
+	); err != nil {
+		return fmt.Errorf("failed to set next problem: %w", err)
+	}
+	return nil
+}
+
+// SetCollections limits the guild's picks to the given collections; nil enables all
+func (r *ProgressRepository) SetCollections(guildID string, collections []string) error {
+	if _, err := r.GetProgress(guildID); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(
+		`UPDATE guild_progress SET collections = ? WHERE guild_id = ?`,
+		strings.Join(collections, ","), guildID,
+	); err != nil {
+		return fmt.Errorf("failed to set collections: %w", err)
+	}
+	return nil
+}
+
+// SetRotation stores the guild's difficulty rotation; "" restores the default
+func (r *ProgressRepository) SetRotation(guildID, rotation string) error {
+	if _, err := r.GetProgress(guildID); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(
+		`UPDATE guild_progress SET rotation = ? WHERE guild_id = ?`, rotation, guildID,
+	); err != nil {
+		return fmt.Errorf("failed to set rotation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package repo
+
+import "testing"
+
+func TestRecordPickKeepsEarlierDays(t *testing.T) {
+	r := InitProgressRepository(openTestDB(t))
+
+	for _, p := range []DailyPick{
+		{Schedule: "default", Cycle: 0, ProblemID: "p1", Day: "2025-06-01"},
+		// a staff jump back to a problem already used this cycle
+		{Schedule: "default", Cycle: 0, ProblemID: "p1", Day: "2025-06-03"},
+		// another schedule drawing from the same collections
+		{Schedule: "advanced", Cycle: 0, ProblemID: "p1", Day: "2025-06-02"},
+	} {
+		if err := r.RecordPick("g1", p); err != nil {
+			t.Fatalf("RecordPick returned error: %v", err)
+		}
+	}
+
+	for _, want := range []struct{ schedule, day string }{
+		{"default", "2025-06-01"}, {"default", "2025-06-03"}, {"advanced", "2025-06-02"},
+	} {
+		if id, err := r.PickForDay("g1", want.schedule, want.day); err != nil || id != "p1" {
+			t.Errorf("expected p1 for %s on %s, got %q, %v", want.schedule, want.day, id, err)
+		}
+	}
+	if picks, _ := r.PicksBetween("g1", "2025-06-01", "2025-06-03"); len(picks) != 3 {
+		t.Errorf("expected all three picks kept, got %+v", picks)
+	}
+
+	// picking the same day again replaces that day's pick only
+	if err := r.RecordPick("g1", DailyPick{Schedule: "default", Cycle: 1, ProblemID: "p2", Day: "2025-06-03"}); err != nil {
+		t.Fatalf("RecordPick returned error: %v", err)
+	}
+	if id, _ := r.PickForDay("g1", "default", "2025-06-03"); id != "p2" {
+		t.Errorf("expected the new pick p2, got %q", id)
+	}
+	if id, _ := r.PickForDay("g1", "default", "2025-06-01"); id != "p1" {
+		t.Errorf("expected the first day's pick kept, got %q", id)
+	}
+	if last, _ := r.LastCycles("g1"); last["p1"] != 0 || last["p2"] != 1 {
+		t.Errorf("expected p1 in cycle 0 and p2 in cycle 1, got %v", last)
+	}
+}
@@ -0,0 +1,158 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/novnod/barista-bot/rating"
+)
+
+// What a rating belongs to
+const (
+	SubjectUser    = "user"
+	SubjectProblem = "problem"
+)
+
+// SubjectRating is a user's or a problem's Glicko-2 rating in a guild
+type SubjectRating struct {
+	GuildID   string
+	Subject   string
+	SubjectID string
+	Glicko    rating.Rating
+	Matches   int // graded attempts counted
+	UpdatedAt time.Time
+}
+
+// RatingPoint is a user's rating right after one of their attempts
+type RatingPoint struct {
+	GuildID   string
+	UserID    string
+	AttemptID int64
+	Rating    float64
+	Deviation float64
+	CreatedAt time.Time
+}
+
+// RatingRepository stores ratings and each user's rating history
+type RatingRepository struct {
+	db *sql.DB
+}
+
+// InitRatingRepository returns a new repository bound to db
+func InitRatingRepository(db *sql.DB) *RatingRepository {
+	return &RatingRepository{db: db}
+}
+
+// GetRating returns a rating, or sql.ErrNoRows if the subject hasn't been
+// in a match yet
+func (r *RatingRepository) GetRating(guildID, subject, subjectID string) (*SubjectRating, error) {
+	sr := SubjectRating{GuildID: guildID, Subject: subject, SubjectID: subjectID}
+	var updatedAt int64
+	err := r.db.QueryRow(
+		`SELECT rating, deviation, volatility, matches, updated_at FROM ratings
+         WHERE guild_id = ? AND subject = ? AND subject_id = ?`,
+		guildID, subject, subjectID,
+	).Scan(&sr.Glicko.Rating, &sr.Glicko.Deviation, &sr.Glicko.Volatility, &sr.Matches, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get rating: %w", err)
+	}
+	sr.UpdatedAt = time.Unix(updatedAt, 0)
+	return &sr, nil
+}
+
+// SaveMatch stores the user's and the problem's ratings after an attempt
+// and adds the user's new rating to their history
+func (r *RatingRepository) SaveMatch(user, problem SubjectRating, attemptID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to save ratings: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, sr := range []SubjectRating{user, problem} {
+		if err := saveRating(tx, sr); err != nil {
+			return fmt.Errorf("failed to save ratings: %w", err)
+		}
+	}
+	if err := addPoint(tx, RatingPoint{
+		GuildID: user.GuildID, UserID: user.SubjectID, AttemptID: attemptID,
+		Rating: user.Glicko.Rating, Deviation: user.Glicko.Deviation, CreatedAt: user.UpdatedAt,
+	}); err != nil {
+		return fmt.Errorf("failed to save ratings: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ReplaceRatings swaps all of a guild's ratings and history for the result
+// of a replay, in one transaction
+func (r *RatingRepository) ReplaceRatings(guildID string, ratings []SubjectRating, history []RatingPoint) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to replace ratings: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM ratings WHERE guild_id = ?; DELETE FROM rating_history WHERE guild_id = ?`, guildID, guildID); err != nil {
+		return fmt.Errorf("failed to clear ratings: %w", err)
+	}
+	for _, sr := range ratings {
+		if err := saveRating(tx, sr); err != nil {
+			return fmt.Errorf("failed to replace ratings: %w", err)
+		}
+	}
+	for _, p := range history {
+		if err := addPoint(tx, p); err != nil {
+			return fmt.Errorf("failed to replace ratings: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// History lists a user's ratings after each of their attempts since the
+// given time, oldest first
+func (r *RatingRepository) History(guildID, userID string, since time.Time) ([]RatingPoint, error) {
+	rows, err := r.db.Query(
+		`SELECT attempt_id, rating, deviation, created_at FROM rating_history
+         WHERE guild_id = ? AND user_id = ? AND created_at >= ? ORDER BY created_at, id`,
+		guildID, userID, since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating history: %w", err)
+	}
+	defer rows.Close()
+
+	var points []RatingPoint
+	for rows.Next() {
+		p := RatingPoint{GuildID: guildID, UserID: userID}
+		var createdAt int64
+		if err := rows.Scan(&p.AttemptID, &p.Rating, &p.Deviation, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to get rating history: %w", err)
+		}
+		p.CreatedAt = time.Unix(createdAt, 0)
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+func saveRating(tx *sql.Tx, sr SubjectRating) error {
+	_, err := tx.Exec(
+		`INSERT INTO ratings(guild_id, subject, subject_id, rating, deviation, volatility, matches, updated_at)
+         VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+         ON CONFLICT(guild_id, subject, subject_id) DO UPDATE SET rating=excluded.rating, deviation=excluded.deviation,
+             volatility=excluded.volatility, matches=excluded.matches, updated_at=excluded.updated_at;`,
+		sr.GuildID, sr.Subject, sr.SubjectID, sr.Glicko.Rating, sr.Glicko.Deviation, sr.Glicko.Volatility, sr.Matches, sr.UpdatedAt.Unix(),
+	)
+	return err
+}
+
+func addPoint(tx *sql.Tx, p RatingPoint) error {
+	_, err := tx.Exec(
+		`INSERT INTO rating_history(guild_id, user_id, attempt_id, rating, deviation, created_at) VALUES(?, ?, ?, ?, ?, ?)`,
+		p.GuildID, p.UserID, p.AttemptID, p.Rating, p.Deviation, p.CreatedAt.Unix(),
+	)
+	return err
+}
@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/novnod/barista-bot/rating"
+)
+
+func TestRatings(t *testing.T) {
+	r := InitRatingRepository(openTestDB(t))
+	at := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	if _, err := r.GetRating("g1", SubjectUser, "u1"); err != sql.ErrNoRows {
+		t.Fatalf("expected ErrNoRows before any match, got %v", err)
+	}
+
+	user := SubjectRating{GuildID: "g1", Subject: SubjectUser, SubjectID: "u1",
+		Glicko: rating.Rating{Rating: 1600, Deviation: 300, Volatility: 0.06}, Matches: 1, UpdatedAt: at}
+	problem := SubjectRating{GuildID: "g1", Subject: SubjectProblem, SubjectID: "p1",
+		Glicko: rating.Rating{Rating: 1400, Deviation: 300, Volatility: 0.06}, Matches: 1, UpdatedAt: at}
+	if err := r.SaveMatch(user, problem, 7); err != nil {
+		t.Fatalf("SaveMatch returned error: %v", err)
+	}
+	user.Glicko.Rating, user.Matches, user.UpdatedAt = 1650, 2, at.Add(time.Hour)
+	if err := r.SaveMatch(user, problem, 8); err != nil {
+		t.Fatalf("SaveMatch returned error: %v", err)
+	}
+
+	got, err := r.GetRating("g1", SubjectUser, "u1")
+	if err != nil {
+		t.Fatalf("GetRating returned error: %v", err)
+	}
+	if got.Glicko != user.Glicko || got.Matches != 2 || !got.UpdatedAt.Equal(user.UpdatedAt) {
+		t.Errorf("expected %+v, got %+v", user, got)
+	}
+	if _, err := r.GetRating("g1", SubjectProblem, "u1"); err != sql.ErrNoRows {
+		t.Errorf("expected users and problems to be kept apart, got %v", err)
+	}
+
+	history, err := r.History("g1", "u1", at)
+	if err != nil {
+		t.Fatalf("History returned error: %v", err)
+	}
+	if len(history) != 2 || history[0].Rating != 1600 || history[1].AttemptID != 8 {
+		t.Errorf("expected two points oldest first, got %+v", history)
+	}
+
+	replay := user
+	replay.Glicko.Rating = 1550
+	err = r.ReplaceRatings("g1", []SubjectRating{replay}, []RatingPoint{{GuildID: "g1", UserID: "u1", AttemptID: 8, Rating: 1550, CreatedAt: at}})
+	if err != nil {
+		t.Fatalf("ReplaceRatings returned error: %v", err)
+	}
+	if _, err := r.GetRating("g1", SubjectProblem, "p1"); err != sql.ErrNoRows {
+		t.Errorf("expected the problem's old rating to be cleared, got %v", err)
+	}
+	if history, _ = r.History("g1", "u1", time.Time{}); len(history) != 1 || history[0].Rating != 1550 {
+		t.Errorf("expected the replayed history only, got %+v", history)
+	}
+}
@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DefaultRevealDelay is used by guilds that haven't configured reveals
+const DefaultRevealDelay = 24
+
+// RevealConfig controls when a guild's daily threads get their solution
+type RevealConfig struct {
+	GuildID    string
+	DelayHours int  // 0 disables reveals
+	LockThread bool // lock the thread once the solution is posted
+}
+
+// RevealRepository stores each guild's reveal settings; the reveals
+// themselves are jobs
+type RevealRepository struct {
+	db *sql.DB
+}
+
+// InitRevealRepository returns a new repository bound to db
+func InitRevealRepository(db *sql.DB) *RevealRepository {
+	return &RevealRepository{db: db}
+}
+
+// GetRevealConfig returns the guild's reveal settings, or the defaults if
+// it hasn't set any
+func (r *RevealRepository) GetRevealConfig(guildID string) (*RevealConfig, error) {
+	cfg := RevealConfig{GuildID: guildID, DelayHours: DefaultRevealDelay}
+	err := r.db.QueryRow(
+		`SELECT delay_hours, lock_thread FROM reveal_config WHERE guild_id = ?`, guildID,
+	).Scan(&cfg.DelayHours, &cfg.LockThread)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get reveal config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// SetRevealConfig inserts or updates the guild's reveal settings
+func (r *RevealRepository) SetRevealConfig(cfg RevealConfig) error {
+	_, err := r.db.Exec(
+		`INSERT INTO reveal_config(guild_id, delay_hours, lock_thread) VALUES(?, ?, ?)
+         ON CONFLICT(guild_id) DO UPDATE SET delay_hours=excluded.delay_hours, lock_thread=excluded.lock_thread;`,
+		cfg.GuildID, cfg.DelayHours, cfg.LockThread,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set reveal config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package repo
+
+import "testing"
+
+func TestRevealConfigDefaults(t *testing.T) {
+	r := InitRevealRepository(openTestDB(t))
+
+	cfg, err := r.GetRevealConfig("g1")
+	if err != nil {
+		t.Fatalf("GetRevealConfig returned error: %v", err)
+	}
+	if cfg.DelayHours != DefaultRevealDelay || cfg.LockThread {
+		t.Errorf("expected default reveal config, got %+v", cfg)
+	}
+
+	if err := r.SetRevealConfig(RevealConfig{GuildID: "g1", DelayHours: 6, LockThread: true}); err != nil {
+		t.Fatalf("SetRevealConfig returned error: %v", err)
+	}
+	cfg, _ = r.GetRevealConfig("g1")
+	if cfg.DelayHours != 6 || !cfg.LockThread {
+		t.Errorf("expected updated reveal config, got %+v", cfg)
+	}
+}
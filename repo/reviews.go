@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/novnod/barista-bot/review"
+)
+
+// ReviewCard is a problem in a user's review queue
+type ReviewCard struct {
+	UserID    string
+	ProblemID string
+	GuildID   string // where the problem was missed; review answers count there
+	review.Card
+	DueAt     time.Time
+	UpdatedAt time.Time
+}
+
+// ReviewRepository stores users' review queues
+type ReviewRepository struct {
+	db *sql.DB
+}
+
+// InitReviewRepository returns a new repository bound to db
+func InitReviewRepository(db *sql.DB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// GetCard returns a problem's card in a user's queue, or sql.ErrNoRows if
+// it isn't queued
+func (r *ReviewRepository) GetCard(userID, problemID string) (*ReviewCard, error) {
+	cards, err := r.query(`WHERE user_id = ? AND problem_id = ?`, userID, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review card: %w", err)
+	}
+	if len(cards) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &cards[0], nil
+}
+
+// SaveCard inserts or updates a card
+func (r *ReviewRepository) SaveCard(c ReviewCard) error {
+	_, err := r.db.Exec(
+		`INSERT INTO reviews(user_id, problem_id, guild_id, ease, interval, reps, due_at, updated_at)
+         VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+         ON CONFLICT(user_id, problem_id) DO UPDATE SET ease=excluded.ease, interval=excluded.interval,
+             reps=excluded.reps, due_at=excluded.due_at, updated_at=excluded.updated_at;`,
+		c.UserID, c.ProblemID, c.GuildID, c.Ease, c.Interval, c.Reps, c.DueAt.Unix(), c.UpdatedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save review card: %w", err)
+	}
+	return nil
+}
+
+// Due lists a user's cards due by the given time, most overdue first
+func (r *ReviewRepository) Due(userID string, by time.Time, limit int) ([]ReviewCard, error) {
+	cards, err := r.query(`WHERE user_id = ? AND due_at <= ? ORDER BY due_at, problem_id LIMIT ?`, userID, by.Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due reviews: %w", err)
+	}
+	return cards, nil
+}
+
+// DueCount counts a user's cards due by the given time
+func (r *ReviewRepository) DueCount(userID string, by time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM reviews WHERE user_id = ? AND due_at <= ?`, userID, by.Unix()).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count due reviews: %w", err)
+	}
+	return n, nil
+}
+
+// query runs a SELECT over reviews with the given clause
+func (r *ReviewRepository) query(clause string, args ...any) ([]ReviewCard, error) {
+	rows, err := r.db.Query(
+		`SELECT user_id, problem_id, guild_id, ease, interval, reps, due_at, updated_at FROM reviews `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []ReviewCard
+	for rows.Next() {
+		var c ReviewCard
+		var dueAt, updatedAt int64
+		if err := rows.Scan(&c.UserID, &c.ProblemID, &c.GuildID, &c.Ease, &c.Interval, &c.Reps, &dueAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		c.DueAt = time.Unix(dueAt, 0)
+		c.UpdatedAt = time.Unix(updatedAt, 0)
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
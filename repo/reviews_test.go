@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/novnod/barista-bot/review"
+)
+
+func TestReviewQueue(t *testing.T) {
+	r := InitReviewRepository(openTestDB(t))
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	if _, err := r.GetCard("u1", "p1"); err != sql.ErrNoRows {
+		t.Fatalf("expected ErrNoRows for an unqueued problem, got %v", err)
+	}
+	for n, p := range []string{"p1", "p2", "p3"} {
+		c := ReviewCard{UserID: "u1", ProblemID: p, GuildID: "g1", Card: review.New,
+			DueAt: now.Add(time.Duration(n-1) * 24 * time.Hour), UpdatedAt: now}
+		if err := r.SaveCard(c); err != nil {
+			t.Fatalf("SaveCard returned error: %v", err)
+		}
+	}
+	r.SaveCard(ReviewCard{UserID: "u2", ProblemID: "p1", GuildID: "g1", Card: review.New, DueAt: now, UpdatedAt: now})
+
+	due, err := r.Due("u1", now, 10)
+	if err != nil {
+		t.Fatalf("Due returned error: %v", err)
+	}
+	if len(due) != 2 || due[0].ProblemID != "p1" || due[1].ProblemID != "p2" {
+		t.Errorf("expected p1 then p2 due, got %+v", due)
+	}
+	if n, _ := r.DueCount("u1", now.Add(24*time.Hour)); n != 3 {
+		t.Errorf("expected 3 due by tomorrow, got %d", n)
+	}
+
+	card := due[0]
+	card.Card = review.Next(card.Card, review.QualityCorrect)
+	card.DueAt = now.Add(6 * 24 * time.Hour)
+	if err := r.SaveCard(card); err != nil {
+		t.Fatalf("SaveCard returned error on update: %v", err)
+	}
+	got, err := r.GetCard("u1", "p1")
+	if err != nil {
+		t.Fatalf("GetCard returned error: %v", err)
+	}
+	if got.Card != card.Card || !got.DueAt.Equal(card.DueAt) || got.GuildID != "g1" {
+		t.Errorf("expected %+v, got %+v", card, got)
+	}
+	if n, _ := r.DueCount("u1", now); n != 1 {
+		t.Errorf("expected only p2 still due, got %d", n)
+	}
+}
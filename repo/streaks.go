@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DefaultFreezeEvery is how many streak days earn a freeze in guilds that
+// haven't set their own
+const DefaultFreezeEvery = 7
+
+// Streak is a member's run of consecutive days with a solved daily
+type Streak struct {
+	GuildID string
+	UserID  string
+	Current int // as of LastDay
+	Best    int
+	LastDay string // YYYY-MM-DD in the guild's zone, "" before the first solve
+	Freezes int    // banked freezes, each covering one missed day
+}
+
+// StreakRepository stores members' streaks and each guild's freeze setting
+type StreakRepository struct {
+	db *sql.DB
+}
+
+// InitStreakRepository returns a new repository bound to db
+func InitStreakRepository(db *sql.DB) *StreakRepository {
+	return &StreakRepository{db: db}
+}
+
+// GetStreak returns a member's streak, or an empty one if they haven't
+// solved a daily yet
+func (r *StreakRepository) GetStreak(guildID, userID string) (*Streak, error) {
+	st := Streak{GuildID: guildID, UserID: userID}
+	err := r.db.QueryRow(
+		`SELECT current, best, last_day, freezes FROM streaks WHERE guild_id = ? AND user_id = ?`, guildID, userID,
+	).Scan(&st.Current, &st.Best, &st.LastDay, &st.Freezes)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get streak: %w", err)
+	}
+	return &st, nil
+}
+
+// SaveStreak inserts or updates a member's streak
+func (r *StreakRepository) SaveStreak(st Streak) error {
+	_, err := r.db.Exec(
+		`INSERT INTO streaks(guild_id, user_id, current, best, last_day, freezes) VALUES(?, ?, ?, ?, ?, ?)
+         ON CONFLICT(guild_id, user_id) DO UPDATE SET current=excluded.current, best=excluded.best,
+             last_day=excluded.last_day, freezes=excluded.freezes;`,
+		st.GuildID, st.UserID, st.Current, st.Best, st.LastDay, st.Freezes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save streak: %w", err)
+	}
+	return nil
+}
+
+// FreezeEvery returns how many streak days earn a freeze in the guild; 0
+// means freezes are off
+func (r *StreakRepository) FreezeEvery(guildID string) (int, error) {
+	every := DefaultFreezeEvery
+	err := r.db.QueryRow(`SELECT freeze_every FROM streak_config WHERE guild_id = ?`, guildID).Scan(&every)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get streak config: %w", err)
+	}
+	return every, nil
+}
+
+// SetFreezeEvery stores how many streak days earn a freeze in the guild
+func (r *StreakRepository) SetFreezeEvery(guildID string, every int) error {
+	_, err := r.db.Exec(
+		`INSERT INTO streak_config(guild_id, freeze_every) VALUES(?, ?)
+         ON CONFLICT(guild_id) DO UPDATE SET freeze_every=excluded.freeze_every;`,
+		guildID, every,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set streak config: %w", err)
+	}
+	return nil
+}
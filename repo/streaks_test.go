@@ -0,0 +1,40 @@
+package repo
+
+import "testing"
+
+func TestStreaks(t *testing.T) {
+	r := InitStreakRepository(openTestDB(t))
+
+	st, err := r.GetStreak("g1", "u1")
+	if err != nil {
+		t.Fatalf("GetStreak returned error: %v", err)
+	}
+	if st.Current != 0 || st.LastDay != "" || st.UserID != "u1" {
+		t.Errorf("expected an empty streak, got %+v", st)
+	}
+
+	saved := Streak{GuildID: "g1", UserID: "u1", Current: 3, Best: 9, LastDay: "2025-06-03", Freezes: 1}
+	if err := r.SaveStreak(saved); err != nil {
+		t.Fatalf("SaveStreak returned error: %v", err)
+	}
+	saved.Current = 4
+	if err := r.SaveStreak(saved); err != nil {
+		t.Fatalf("SaveStreak returned error on update: %v", err)
+	}
+	if st, _ = r.GetStreak("g1", "u1"); *st != saved {
+		t.Errorf("expected %+v, got %+v", saved, st)
+	}
+	if st, _ = r.GetStreak("g2", "u1"); st.Current != 0 {
+		t.Errorf("expected streaks to be per guild, got %+v", st)
+	}
+
+	if every, _ := r.FreezeEvery("g1"); every != DefaultFreezeEvery {
+		t.Errorf("expected the default freeze interval, got %d", every)
+	}
+	if err := r.SetFreezeEvery("g1", 0); err != nil {
+		t.Fatalf("SetFreezeEvery returned error: %v", err)
+	}
+	if every, _ := r.FreezeEvery("g1"); every != 0 {
+		t.Errorf("expected freezes to be off, got %d", every)
+	}
+}
@@ -0,0 +1,181 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Subscription is a member's opt-in to receive a schedule's daily by DM
+type Subscription struct {
+	GuildID   string
+	UserID    string
+	Schedule  string
+	Disabled  string // why delivery stopped, such as closed DMs; empty while active
+	CreatedAt time.Time
+}
+
+// DMDaily is a daily delivered to a subscriber's DMs
+type DMDaily struct {
+	MessageID string
+	ChannelID string // the DM channel
+	GuildID   string
+	UserID    string
+	Schedule  string
+	Day       string // YYYY-MM-DD in the schedule's zone
+	ProblemID string
+	PostedAt  time.Time
+}
+
+// SubscriptionRepository stores DM subscriptions and the dailies delivered
+// through them, so answers in a DM can find their guild and problem
+type SubscriptionRepository struct {
+	db *sql.DB
+}
+
+// InitSubscriptionRepository returns a new repository bound to db
+func InitSubscriptionRepository(db *sql.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+// Subscribe opts a member in to a schedule's DMs, re-enabling a
+// subscription that was disabled
+func (r *SubscriptionRepository) Subscribe(guildID, userID, schedule string, at time.Time) error {
+	_, err := r.db.Exec(
+		`INSERT INTO dm_subscriptions(guild_id, user_id, schedule, created_at) VALUES(?, ?, ?, ?)
+         ON CONFLICT(guild_id, user_id, schedule) DO UPDATE SET disabled = '';`,
+		guildID, userID, schedule, at.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+	return nil
+}
+
+// Unsubscribe removes a member's subscription to a schedule, or to all of
+// the guild's schedules if schedule is empty, and returns how many there were
+func (r *SubscriptionRepository) Unsubscribe(guildID, userID, schedule string) (int, error) {
+	res, err := r.db.Exec(
+		`DELETE FROM dm_subscriptions WHERE guild_id = ? AND user_id = ? AND (? = '' OR schedule = ?)`,
+		guildID, userID, schedule, schedule,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// Disable stops deliveries to all of a member's subscriptions in a guild,
+// keeping them so /subscribe can turn them back on
+func (r *SubscriptionRepository) Disable(guildID, userID, reason string) error {
+	_, err := r.db.Exec(
+		`UPDATE dm_subscriptions SET disabled = ? WHERE guild_id = ? AND user_id = ?`,
+		reason, guildID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to disable subscriptions: %w", err)
+	}
+	return nil
+}
+
+// Subscriptions lists a member's subscriptions in a guild by schedule
+func (r *SubscriptionRepository) Subscriptions(guildID, userID string) ([]Subscription, error) {
+	subs, err := r.query(`WHERE guild_id = ? AND user_id = ? ORDER BY schedule`, guildID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// Subscribers lists the active subscriptions to a schedule, oldest first
+func (r *SubscriptionRepository) Subscribers(guildID, schedule string) ([]Subscription, error) {
+	subs, err := r.query(`WHERE guild_id = ? AND schedule = ? AND disabled = '' ORDER BY created_at, user_id`,
+		guildID, schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscribers: %w", err)
+	}
+	return subs, nil
+}
+
+// query runs a SELECT over dm_subscriptions with the given clause
+func (r *SubscriptionRepository) query(clause string, args ...any) ([]Subscription, error) {
+	rows, err := r.db.Query(
+		`SELECT guild_id, user_id, schedule, disabled, created_at FROM dm_subscriptions `+clause, args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []Subscription
+	for rows.Next() {
+		var sub Subscription
+		var created int64
+		if err := rows.Scan(&sub.GuildID, &sub.UserID, &sub.Schedule, &sub.Disabled, &created); err != nil {
+			return nil, err
+		}
+		sub.CreatedAt = time.Unix(created, 0)
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// RecordDM stores a delivered daily. Each subscriber gets a schedule's
+// daily at most once a day, so recording a second one fails.
+func (r *SubscriptionRepository) RecordDM(d DMDaily) error {
+	_, err := r.db.Exec(
+		`INSERT INTO dm_dailies(message_id, channel_id, guild_id, user_id, schedule, day, problem_id, posted_at)
+         VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.MessageID, d.ChannelID, d.GuildID, d.UserID, d.Schedule, d.Day, d.ProblemID, d.PostedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record DM: %w", err)
+	}
+	return nil
+}
+
+// Delivered returns the members a schedule's daily was already delivered
+// to on day
+func (r *SubscriptionRepository) Delivered(guildID, schedule, day string) (map[string]bool, error) {
+	rows, err := r.db.Query(
+		`SELECT user_id FROM dm_dailies WHERE guild_id = ? AND schedule = ? AND day = ?`, guildID, schedule, day,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	delivered := map[string]bool{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to list deliveries: %w", err)
+		}
+		delivered[userID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	return delivered, nil
+}
+
+// LatestDM returns the daily most recently delivered in a DM channel, of
+// problemID if it isn't empty, or sql.ErrNoRows if there isn't one
+func (r *SubscriptionRepository) LatestDM(channelID, problemID string) (*DMDaily, error) {
+	var d DMDaily
+	var posted int64
+	err := r.db.QueryRow(
+		`SELECT message_id, channel_id, guild_id, user_id, schedule, day, problem_id, posted_at FROM dm_dailies
+         WHERE channel_id = ? AND (? = '' OR problem_id = ?) ORDER BY posted_at DESC, rowid DESC LIMIT 1`,
+		channelID, problemID, problemID,
+	).Scan(&d.MessageID, &d.ChannelID, &d.GuildID, &d.UserID, &d.Schedule, &d.Day, &d.ProblemID, &posted)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DM: %w", err)
+	}
+	d.PostedAt = time.Unix(posted, 0)
+	return &d, nil
+}
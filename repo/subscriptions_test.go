@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestSubscriptions(t *testing.T) {
+	r := InitSubscriptionRepository(openTestDB(t))
+	at := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	for i, userID := range []string{"u1", "u2", "u3"} {
+		if err := r.Subscribe("g1", userID, DefaultSchedule, at.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("Subscribe returned error: %v", err)
+		}
+	}
+	if err := r.Subscribe("g1", "u1", "advanced", at); err != nil {
+		t.Fatalf("Subscribe returned error: %v", err)
+	}
+
+	if err := r.Disable("g1", "u2", "DMs closed"); err != nil {
+		t.Fatalf("Disable returned error: %v", err)
+	}
+	subs, err := r.Subscribers("g1", DefaultSchedule)
+	if err != nil {
+		t.Fatalf("Subscribers returned error: %v", err)
+	}
+	if len(subs) != 2 || subs[0].UserID != "u1" || subs[1].UserID != "u3" {
+		t.Errorf("expected u1 and u3 to be active, got %+v", subs)
+	}
+	if subs, _ := r.Subscriptions("g1", "u2"); len(subs) != 1 || subs[0].Disabled != "DMs closed" {
+		t.Errorf("expected u2's subscription to be kept disabled, got %+v", subs)
+	}
+
+	// subscribing again turns delivery back on
+	if err := r.Subscribe("g1", "u2", DefaultSchedule, at.Add(time.Hour)); err != nil {
+		t.Fatalf("Subscribe returned error on re-enable: %v", err)
+	}
+	if subs, _ := r.Subscribers("g1", DefaultSchedule); len(subs) != 3 || subs[1].UserID != "u2" {
+		t.Errorf("expected u2 back in their original place, got %+v", subs)
+	}
+
+	if n, err := r.Unsubscribe("g1", "u1", "advanced"); err != nil || n != 1 {
+		t.Errorf("expected one subscription removed, got %d, %v", n, err)
+	}
+	if n, _ := r.Unsubscribe("g1", "u1", ""); n != 1 {
+		t.Errorf("expected the remaining subscription removed, got %d", n)
+	}
+	if subs, _ := r.Subscriptions("g1", "u1"); len(subs) != 0 {
+		t.Errorf("expected no subscriptions left, got %+v", subs)
+	}
+}
+
+func TestDMDailies(t *testing.T) {
+	r := InitSubscriptionRepository(openTestDB(t))
+	at := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	if _, err := r.LatestDM("dm1", ""); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows for an empty channel, got %v", err)
+	}
+
+	first := DMDaily{MessageID: "m1", ChannelID: "dm1", GuildID: "g1", UserID: "u1", Schedule: DefaultSchedule,
+		Day: "2025-06-01", ProblemID: "p1", PostedAt: at}
+	second := DMDaily{MessageID: "m2", ChannelID: "dm1", GuildID: "g2", UserID: "u1", Schedule: DefaultSchedule,
+		Day: "2025-06-01", ProblemID: "p2", PostedAt: at.Add(time.Hour)}
+	for _, d := range []DMDaily{first, second} {
+		if err := r.RecordDM(d); err != nil {
+			t.Fatalf("RecordDM returned error: %v", err)
+		}
+	}
+	again := first
+	again.MessageID = "m3"
+	if err := r.RecordDM(again); err == nil {
+		t.Error("expected a second delivery of the same daily to fail")
+	}
+
+	if d, err := r.LatestDM("dm1", ""); err != nil || d.MessageID != "m2" || d.GuildID != "g2" || !d.PostedAt.Equal(second.PostedAt) {
+		t.Errorf("expected the latest daily %+v, got %+v, %v", second, d, err)
+	}
+	if d, err := r.LatestDM("dm1", "p1"); err != nil || d.MessageID != "m1" || d.GuildID != "g1" {
+		t.Errorf("expected %+v for p1, got %+v, %v", first, d, err)
+	}
+
+	delivered, err := r.Delivered("g1", DefaultSchedule, "2025-06-01")
+	if err != nil {
+		t.Fatalf("Delivered returned error: %v", err)
+	}
+	if len(delivered) != 1 || !delivered["u1"] {
+		t.Errorf("expected only u1 delivered, got %v", delivered)
+	}
+}
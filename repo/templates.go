@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// MessageTemplate lays out the embed a guild's dailies are posted with.
+// The text fields can use the placeholders listed in daily.TemplateVars.
+type MessageTemplate struct {
+	GuildID     string
+	Title       string
+	Description string
+	Footer      string
+	Color       int
+}
+
+// DefaultTemplate is used by guilds that haven't set their own
+var DefaultTemplate = MessageTemplate{
+	Title:       "Daily problem · {date}",
+	Description: "**{prompt}**\nReply in this thread with your move, e.g. `C17`, or press Answer.",
+	Footer:      "Solve one every day to keep your streak going 🔥",
+	Color:       0xC8A165,
+}
+
+// TemplateRepository stores each guild's daily message template
+type TemplateRepository struct {
+	db *sql.DB
+}
+
+// InitTemplateRepository returns a new repository bound to db
+func InitTemplateRepository(db *sql.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// GetTemplate returns the guild's template, or the default if it hasn't
+// set one
+func (r *TemplateRepository) GetTemplate(guildID string) (*MessageTemplate, error) {
+	tmpl := DefaultTemplate
+	tmpl.GuildID = guildID
+	err := r.db.QueryRow(
+		`SELECT title, description, footer, color FROM message_templates WHERE guild_id = ?`, guildID,
+	).Scan(&tmpl.Title, &tmpl.Description, &tmpl.Footer, &tmpl.Color)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get message template: %w", err)
+	}
+	return &tmpl, nil
+}
+
+// SetTemplate inserts or updates the guild's template
+func (r *TemplateRepository) SetTemplate(tmpl MessageTemplate) error {
+	_, err := r.db.Exec(
+		`INSERT INTO message_templates(guild_id, title, description, footer, color) VALUES(?, ?, ?, ?, ?)
+         ON CONFLICT(guild_id) DO UPDATE SET title=excluded.title, description=excluded.description,
+             footer=excluded.footer, color=excluded.color;`,
+		tmpl.GuildID, tmpl.Title, tmpl.Description, tmpl.Footer, tmpl.Color,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set message template: %w", err)
+	}
+	return nil
+}
+
+// ResetTemplate drops the guild's template so the default applies again
+func (r *TemplateRepository) ResetTemplate(guildID string) error {
+	if _, err := r.db.Exec(`DELETE FROM message_templates WHERE guild_id = ?`, guildID); err != nil {
+		return fmt.Errorf("failed to reset message template: %w", err)
+	}
+	return nil
+}
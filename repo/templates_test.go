@@ -0,0 +1,33 @@
+package repo
+
+import "testing"
+
+func TestTemplateDefaultsAndReset(t *testing.T) {
+	r := InitTemplateRepository(openTestDB(t))
+
+	tmpl, err := r.GetTemplate("g1")
+	if err != nil {
+		t.Fatalf("GetTemplate returned error: %v", err)
+	}
+	if tmpl.Title != DefaultTemplate.Title || tmpl.GuildID != "g1" {
+		t.Errorf("expected the default template, got %+v", tmpl)
+	}
+
+	custom := MessageTemplate{GuildID: "g1", Title: "Tsumego {number}", Description: "{prompt}", Footer: "", Color: 0x336699}
+	if err := r.SetTemplate(custom); err != nil {
+		t.Fatalf("SetTemplate returned error: %v", err)
+	}
+	if tmpl, _ = r.GetTemplate("g1"); *tmpl != custom {
+		t.Errorf("expected %+v, got %+v", custom, tmpl)
+	}
+	if tmpl, _ = r.GetTemplate("g2"); tmpl.Title != DefaultTemplate.Title {
+		t.Errorf("expected other guilds to keep the default, got %+v", tmpl)
+	}
+
+	if err := r.ResetTemplate("g1"); err != nil {
+		t.Fatalf("ResetTemplate returned error: %v", err)
+	}
+	if tmpl, _ = r.GetTemplate("g1"); tmpl.Title != DefaultTemplate.Title {
+		t.Errorf("expected the default after a reset, got %+v", tmpl)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/scheduler"
+)
+
+// reminderLead is how long before a reveal the thread is reminded
+const reminderLead = time.Hour
+
+// revealPayload is the payload of reveal and reminder jobs
+type revealPayload struct {
+	Problem string `json:"problem"`
+	Lock    bool   `json:"lock,omitempty"`
+}
+
+// revealSettings returns how long after posting the schedule's solution is
+// revealed and whether the thread is then locked. Schedules that inherit,
+// and threads started with /daily (cfg is nil), use the guild's settings.
+func revealSettings(guildID string, cfg *repo.DailyConfig) (hours int, lock bool, err error) {
+	if cfg != nil && cfg.RevealHours != repo.InheritReveal {
+		return cfg.RevealHours, cfg.LockThread, nil
+	}
+	guild, err := revealRepo.GetRevealConfig(guildID)
+	if err != nil {
+		return 0, false, err
+	}
+	return guild.DelayHours, guild.LockThread, nil
+}
+
+// scheduleReveal queues the solution for a new daily thread according to
+// its reveal settings, with a reminder an hour before when the delay is
+// long enough for one to be useful
+func scheduleReveal(thread *discordgo.Channel, prob *parser.GoProblem, cfg *repo.DailyConfig) error {
+	hours, lock, err := revealSettings(thread.GuildID, cfg)
+	if err != nil {
+		return err
+	}
+	if hours <= 0 {
+		return nil
+	}
+	payload, err := json.Marshal(revealPayload{Problem: prob.ID, Lock: lock})
+	if err != nil {
+		return err
+	}
+	revealAt := time.Now().Add(time.Duration(hours) * time.Hour)
+	reveal := repo.Job{GuildID: thread.GuildID, Kind: repo.JobReveal, Key: thread.ID, Payload: string(payload), DueAt: revealAt}
+	if err := jobRepo.Schedule(reveal); err != nil {
+		return err
+	}
+	if time.Duration(hours)*time.Hour < 2*reminderLead {
+		return nil
+	}
+	reminder := reveal
+	reminder.Kind = repo.JobReminder
+	reminder.DueAt = revealAt.Add(-reminderLead)
+	return jobRepo.Schedule(reminder)
+}
+
+// remindDaily nudges a daily thread shortly before its solution is posted
+func remindDaily(s *discordgo.Session) scheduler.JobFunc {
+	return func(job repo.Job) error {
+		_, err := s.ChannelMessageSend(job.Key, "⏰ One hour left to answer before the solution is revealed!")
+		return err
+	}
+}
+
+// revealDaily posts the solution in the daily's thread, then locks it if
+// its settings asked for that
+func revealDaily(s *discordgo.Session, pg *parser.GoParser) scheduler.JobFunc {
+	return func(job repo.Job) error {
+		var payload revealPayload
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return fmt.Errorf("bad reveal payload: %w", err)
+		}
+		prob := pg.Problem(payload.Problem)
+		if prob == nil {
+			return fmt.Errorf("unknown problem %q", payload.Problem)
+		}
+
+		if err := postSolution(s, job.Key, prob); err != nil {
+			return err
+		}
+
+		if !payload.Lock {
+			return nil
+		}
+		locked := true
+		if _, err := s.ChannelEdit(job.Key, &discordgo.ChannelEdit{Locked: &locked}); err != nil {
+			// the solution is already up, so don't fail the job and post it twice
+			log.Printf("failed to lock thread %s: %v", job.Key, err)
+		}
+		return nil
+	}
+}
+
+// postSolution posts the solution diagram and participation stats in a
+// thread, and records them with the thread's post
+func postSolution(s *discordgo.Session, threadID string, prob *parser.GoProblem) error {
+	stats, err := threadStats(threadID, prob)
+	if err != nil {
+		log.Printf("failed to count answers in thread %s: %v", threadID, err)
+	}
+
+	send := &discordgo.MessageSend{Content: revealMessage(prob, stats)}
+	if line := prob.CorrectLine(); len(line) > 0 {
+		imgPath, err := parser.RenderOverlay(prob, parser.SolutionOverlay(line), imageDir, 600, 30)
+		if err != nil {
+			log.Printf("failed to render solution for %s: %v", prob.ID, err)
+		} else if file, err := os.Open(imgPath); err != nil {
+			log.Printf("failed to open image: %v", err)
+		} else {
+			defer file.Close()
+			send.Files = []*discordgo.File{{
+				Name:        filepath.Base(imgPath),
+				ContentType: "image/png",
+				Reader:      file,
+			}}
+		}
+	}
+	if _, err := s.ChannelMessageSendComplex(threadID, send); err != nil {
+		return fmt.Errorf("failed to post solution: %w", err)
+	}
+	if err := postRepo.MarkRevealed(threadID, time.Now(), stats.Players, stats.Solved); err != nil {
+		log.Printf("failed to record reveal of thread %s: %v", threadID, err)
+	}
+	return nil
+}
+
+// revealNow posts a daily thread's solution straight away. A pending reveal
+// runs early with its own settings and its reminder is dropped; threads
+// without one get the solution without being locked. It does nothing if the
+// solution is already up or the scheduler is revealing it right now.
+func revealNow(s *discordgo.Session, pg *parser.GoParser, guildID, threadID string, prob *parser.GoProblem) error {
+	if post, err := postRepo.Post(threadID); err == nil && !post.RevealedAt.IsZero() {
+		return nil
+	}
+	job, err := jobRepo.Pending(guildID, repo.JobReveal, threadID)
+	if err == sql.ErrNoRows {
+		payload, err := json.Marshal(revealPayload{Problem: prob.ID})
+		if err != nil {
+			return err
+		}
+		job = &repo.Job{GuildID: guildID, Kind: repo.JobReveal, Key: threadID, Payload: string(payload)}
+	} else if err != nil {
+		return err
+	} else if claimed, err := jobRepo.Claim(job.ID); err != nil || !claimed {
+		return err
+	}
+
+	if err := revealDaily(s, pg)(*job); err != nil {
+		if job.ID != 0 {
+			// hand the job back to the scheduler to retry
+			if err := jobRepo.Fail(job.ID, err, time.Now()); err != nil {
+				log.Printf("failed to release reveal job %d: %v", job.ID, err)
+			}
+		}
+		return err
+	}
+	if job.ID != 0 {
+		if err := jobRepo.Finish(job.ID); err != nil {
+			log.Printf("failed to finish reveal job %d: %v", job.ID, err)
+		}
+	}
+	return jobRepo.CancelFuture(guildID, repo.JobReminder, threadID, "", time.Now())
+}
+
+// threadStats summarizes the answers recorded for a thread's daily,
+// including those sent with the Answer button or by DM
+func threadStats(threadID string, prob *parser.GoProblem) (repo.PostStats, error) {
+	post, err := postRepo.Post(threadID)
+	if err == sql.ErrNoRows {
+		post = &repo.DailyPost{ThreadID: threadID, ProblemID: prob.ID}
+	} else if err != nil {
+		return repo.PostStats{}, err
+	}
+	return attemptRepo.PostStats(*post)
+}
+
+// revealMessage describes the solution and how the thread did
+func revealMessage(prob *parser.GoProblem, stats repo.PostStats) string {
+	var b strings.Builder
+	b.WriteString("**Solution**")
+	line := prob.CorrectLine()
+	if len(line) == 0 {
+		b.WriteString("\nThere's no solution on file for this problem.")
+	} else {
+		names := make([]string, len(line))
+		for i, m := range line {
+			names[i] = fmt.Sprintf("%d. %s", i+1, parser.CoordName(m.Coord))
+		}
+		b.WriteString("\n" + strings.Join(names, "  "))
+	}
+
+	switch {
+	case stats.Players == 0:
+		b.WriteString("\nNobody answered this one.")
+	case len(prob.Solution) == 0:
+		fmt.Fprintf(&b, "\n%d players posted %d answers.", stats.Players, stats.Attempts)
+	default:
+		fmt.Fprintf(&b, "\n%d players posted %d answers: %d solved it, %d on the first try.",
+			stats.Players, stats.Attempts, stats.Solved, stats.FirstTry)
+	}
+	return b.String()
+}
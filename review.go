@@ -0,0 +1,299 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/review"
+)
+
+// Custom IDs of the buttons under a review problem
+const (
+	reviewAnswerButton = "review_answer:" // followed by the problem ID
+	reviewNextButton   = "review_next"
+)
+
+// Where /review serves problems
+const (
+	reviewInDM     = "dm"
+	reviewInThread = "thread"
+)
+
+// reviewCommand describes /review
+func reviewCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "review",
+		Description: "Go over problems you missed that are due again",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "where",
+				Description: "Where to serve them (your DMs by default)",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Direct messages", Value: reviewInDM},
+					{Name: "A private thread here", Value: reviewInThread},
+				},
+			},
+		},
+	}
+}
+
+// handleReview tells the member how many reviews are due today and serves
+// the first one in their DMs or a new private thread
+func handleReview(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	userID := interactionUserID(i)
+	where := reviewInDM
+	if opts := i.ApplicationCommandData().Options; len(opts) > 0 {
+		where = opts[0].StringValue()
+	}
+	loc := guildLocation(i.GuildID)
+	due, err := reviewRepo.DueCount(userID, endOfDay(time.Now().In(loc)))
+	if err != nil {
+		respondError(s, i, "could not load reviews: "+err.Error())
+		return
+	}
+	if due == 0 {
+		respondEphemeral(s, i, "Nothing to review today. Problems you miss come back here, spaced out further each time you get them right.")
+		return
+	}
+	msg, err := reviewMessage(userID, loc, pg)
+	if err != nil {
+		respondError(s, i, "could not load reviews: "+err.Error())
+		return
+	}
+	if msg == nil {
+		respondEphemeral(s, i, "The problems due for review aren't loaded any more.")
+		return
+	}
+
+	var channelID string
+	if where == reviewInThread {
+		if i.GuildID == "" {
+			respondEphemeral(s, i, "Private threads need a server; here your reviews come straight to this DM.")
+			return
+		}
+		name := "Review"
+		if i.Member != nil {
+			name += " · " + i.Member.User.Username
+		}
+		thread, err := s.ThreadStartComplex(i.ChannelID, &discordgo.ThreadStart{
+			Name:                name,
+			AutoArchiveDuration: 60,
+			Type:                discordgo.ChannelTypeGuildPrivateThread,
+			Invitable:           false,
+		})
+		if err != nil {
+			respondEphemeral(s, i, "Couldn't open a private thread here; try /review in your DMs.")
+			return
+		}
+		if err := s.ThreadMemberAdd(thread.ID, userID); err != nil {
+			log.Printf("failed to add %s to review thread %s: %v", userID, thread.ID, err)
+		}
+		channelID = thread.ID
+	} else {
+		dm, err := s.UserChannelCreate(userID)
+		if err != nil {
+			respondError(s, i, "could not open a DM: "+err.Error())
+			return
+		}
+		channelID = dm.ID
+	}
+
+	if _, err := s.ChannelMessageSendComplex(channelID, msg); err != nil {
+		log.Printf("failed to send review to %s: %v", channelID, err)
+		respondEphemeral(s, i, "Couldn't send you the problem; if your DMs are closed, try where: thread.")
+		return
+	}
+	if channelID == i.ChannelID {
+		respondEphemeral(s, i, fmt.Sprintf("%s due today.", reviewCount(due)))
+		return
+	}
+	respondEphemeral(s, i, fmt.Sprintf("%s due today; the first is in <#%s>.", reviewCount(due), channelID))
+}
+
+// handleReviewButton answers a press of a button under a review problem
+func handleReviewButton(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	id := i.MessageComponentData().CustomID
+	if id == reviewNextButton {
+		msg, err := reviewMessage(interactionUserID(i), guildLocation(i.GuildID), pg)
+		if err != nil {
+			respondError(s, i, "could not load reviews: "+err.Error())
+			return
+		}
+		if msg == nil {
+			respondEphemeral(s, i, "That's every review due today. 🎉")
+			return
+		}
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Embeds: msg.Embeds, Files: msg.Files, Components: msg.Components},
+		})
+		if err != nil {
+			log.Printf("failed to send review: %v", err)
+		}
+		return
+	}
+
+	prob := pg.Problem(strings.TrimPrefix(id, reviewAnswerButton))
+	if prob == nil {
+		respondEphemeral(s, i, "This problem isn't loaded any more.")
+		return
+	}
+	openAnswerForm(s, i, reviewAnswerButton, prob)
+}
+
+// handleReviewSubmit grades a review answer and reschedules the problem
+func handleReviewSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	prob, moves, ok := submittedAnswer(s, i, reviewAnswerButton, pg)
+	if !ok {
+		return
+	}
+	if !deferVerdict(s, i) {
+		return
+	}
+	userID := interactionUserID(i)
+	verdict, msg, imgPath, err := gradeAnswer(prob, moves)
+	if err != nil {
+		followupVerdict(s, i, msg, imgPath, nil)
+		return
+	}
+
+	// review answers count in the guild the problem was missed in
+	guildID := i.GuildID
+	if card, err := reviewRepo.GetCard(userID, prob.ID); err == nil {
+		guildID = card.GuildID
+	}
+	attempt := repo.Attempt{
+		GuildID:   guildID,
+		UserID:    userID,
+		ProblemID: prob.ID,
+		Source:    repo.SourceReview,
+		Moves:     coordNames(moves),
+		Result:    attemptResult(verdict),
+		ThreadID:  i.ChannelID,
+		CreatedAt: time.Now(),
+	}
+	recordAttempt(s, pg, attempt, prob, false)
+	if card, err := reviewRepo.GetCard(userID, prob.ID); err == nil {
+		msg += fmt.Sprintf("\nThis problem comes back %s.", reviewDue(card.DueAt))
+	}
+
+	loc := guildLocation(i.GuildID)
+	due, err := reviewRepo.DueCount(userID, endOfDay(time.Now().In(loc)))
+	if err != nil {
+		log.Printf("failed to count reviews: %v", err)
+	}
+	var components []discordgo.MessageComponent
+	if due > 0 {
+		components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: fmt.Sprintf("Next problem (%d due)", due), Style: discordgo.PrimaryButton, CustomID: reviewNextButton},
+			}},
+		}
+	} else {
+		msg += "\nThat's every review due today. 🎉"
+	}
+	followupVerdict(s, i, msg, imgPath, components)
+}
+
+// reviewMessage builds the message serving the member's most overdue
+// review that's still loaded, or returns nil if nothing is due today
+func reviewMessage(userID string, loc *time.Location, pg *parser.GoParser) (*discordgo.MessageSend, error) {
+	by := endOfDay(time.Now().In(loc))
+	cards, err := reviewRepo.Due(userID, by, 25)
+	if err != nil {
+		return nil, err
+	}
+	due, err := reviewRepo.DueCount(userID, by)
+	if err != nil {
+		return nil, err
+	}
+	for n, card := range cards {
+		prob := pg.Problem(card.ProblemID)
+		if prob == nil {
+			continue
+		}
+		img, err := problemImage(prob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render problem: %w", err)
+		}
+		embed := &discordgo.MessageEmbed{
+			Title:       "Review · " + prob.Prompt(),
+			Description: fmt.Sprintf("%s\nLast seen <t:%d:R> · %s left today", prob.Details(), card.UpdatedAt.Unix(), reviewCount(due-n)),
+			Color:       repo.DefaultTemplate.Color,
+			Image:       &discordgo.MessageEmbedImage{URL: "attachment://" + img.Name},
+		}
+		return &discordgo.MessageSend{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Files:  []*discordgo.File{img},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "Answer", Style: discordgo.PrimaryButton, CustomID: reviewAnswerButton + prob.ID},
+					discordgo.Button{Label: "Hint", Style: discordgo.SecondaryButton, CustomID: hintButton + prob.ID},
+				}},
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+// scheduleReview moves the problem's card in the user's review queue after
+// a graded attempt. A miss queues the problem, or starts its card over. A
+// correct answer only advances a card that is due, so a quick retry right
+// after a miss doesn't count as a review.
+func scheduleReview(a repo.Attempt) {
+	if a.Result == repo.ResultUngraded {
+		return
+	}
+	card, err := reviewRepo.GetCard(a.UserID, a.ProblemID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if a.Result == repo.ResultCorrect {
+			return
+		}
+		card = &repo.ReviewCard{UserID: a.UserID, ProblemID: a.ProblemID, GuildID: a.GuildID, Card: review.New}
+	case err != nil:
+		log.Printf("failed to schedule review: %v", err)
+		return
+	case a.Result == repo.ResultCorrect && card.DueAt.After(endOfDay(a.CreatedAt.In(guildLocation(card.GuildID)))):
+		return
+	case a.Result == repo.ResultCorrect:
+		card.Card = review.Next(card.Card, review.QualityCorrect)
+	default:
+		card.Card = review.Next(card.Card, review.QualityWrong)
+	}
+	card.DueAt = a.CreatedAt.AddDate(0, 0, card.Interval)
+	card.UpdatedAt = a.CreatedAt
+	if err := reviewRepo.SaveCard(*card); err != nil {
+		log.Printf("failed to schedule review: %v", err)
+	}
+}
+
+// reviewDue describes when a card is next due, e.g. "tomorrow" or "in 6 days"
+func reviewDue(at time.Time) string {
+	n := int(time.Until(at).Round(24*time.Hour) / (24 * time.Hour))
+	if n <= 1 {
+		return "tomorrow"
+	}
+	return fmt.Sprintf("in %d days", n)
+}
+
+// reviewCount formats a number of reviews, e.g. "1 review" or "3 reviews"
+func reviewCount(n int) string {
+	if n == 1 {
+		return "1 review"
+	}
+	return fmt.Sprintf("%d reviews", n)
+}
+
+// endOfDay returns the midnight that ends t's date in t's location
+func endOfDay(t time.Time) time.Time {
+	return startOfDay(t).AddDate(0, 0, 1)
+}
@@ -0,0 +1,47 @@
+// Package review schedules missed problems for spaced repetition with SM-2
+package review
+
+import "math"
+
+// SM-2 parameters
+const (
+	StartEase = 2.5
+	MinEase   = 1.3
+)
+
+// Answer qualities on SM-2's 0–5 scale
+const (
+	QualityCorrect = 4 // right, the usual effort
+	QualityWrong   = 1 // wrong, but the problem was familiar
+)
+
+// Card is where a problem stands in a user's review queue
+type Card struct {
+	Ease     float64
+	Interval int // days until the next review
+	Reps     int // correct reviews in a row
+}
+
+// New is the card of a problem just missed: it comes back the next day
+var New = Card{Ease: StartEase, Interval: 1}
+
+// Next grades a review of quality q and returns the updated card. A
+// quality below 3 starts the repetitions over with a one-day interval.
+func Next(c Card, q int) Card {
+	if q < 3 {
+		c.Reps, c.Interval = 0, 1
+	} else {
+		switch c.Reps {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+		c.Reps++
+	}
+	miss := float64(5 - q)
+	c.Ease = math.Max(MinEase, c.Ease+0.1-miss*(0.08+miss*0.02))
+	return c
+}
@@ -0,0 +1,35 @@
+package review
+
+import "testing"
+
+func TestNextSpacesCorrectReviews(t *testing.T) {
+	c := New
+	var intervals []int
+	for range 4 {
+		c = Next(c, QualityCorrect)
+		intervals = append(intervals, c.Interval)
+	}
+	want := []int{1, 6, 15, 38}
+	for n := range want {
+		if intervals[n] != want[n] {
+			t.Fatalf("expected intervals %v, got %v", want, intervals)
+		}
+	}
+	if c.Ease != StartEase || c.Reps != 4 {
+		t.Errorf("expected quality 4 to keep the ease, got %+v", c)
+	}
+}
+
+func TestNextResetsOnMiss(t *testing.T) {
+	c := Card{Ease: 2.5, Interval: 15, Reps: 3}
+	c = Next(c, QualityWrong)
+	if c.Interval != 1 || c.Reps != 0 || c.Ease >= 2.5 {
+		t.Errorf("expected a miss to start over and lower the ease, got %+v", c)
+	}
+	for range 20 {
+		c = Next(c, 0)
+	}
+	if c.Ease != MinEase {
+		t.Errorf("expected the ease to bottom out at %v, got %v", MinEase, c.Ease)
+	}
+}
@@ -0,0 +1,232 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronTrigger fires whenever any of its cron expressions match, in its zone.
+// Each expression has the usual five fields: minute, hour, day of month,
+// month and day of week, with lists, ranges, steps and three-letter names.
+type CronTrigger struct {
+	Exprs    []CronExpr
+	Location *time.Location
+}
+
+// CronExpr is one parsed cron expression
+type CronExpr struct {
+	spec   string
+	minute []int // sorted
+	hour   []int // sorted
+	dom    map[int]bool
+	month  map[int]bool
+	dow    map[int]bool
+	anyDom bool
+	anyDow bool
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// cronSearchYears bounds the search for the next fire time, long enough
+// for expressions that only match on 29 February
+const cronSearchYears = 5
+
+// ParseCron reads ";"-separated cron expressions, optionally followed by
+// a zone as in ParseDailyTime
+func ParseCron(s string) (CronTrigger, error) {
+	parts := strings.Split(s, ";")
+	last := strings.Fields(parts[len(parts)-1])
+	loc := time.UTC
+	if len(last) == 6 {
+		var err error
+		if loc, err = LoadZone(last[5]); err != nil {
+			return CronTrigger{}, err
+		}
+		parts[len(parts)-1] = strings.Join(last[:5], " ")
+	}
+
+	ct := CronTrigger{Location: loc}
+	for _, part := range parts {
+		expr, err := parseCronExpr(part)
+		if err != nil {
+			return CronTrigger{}, err
+		}
+		ct.Exprs = append(ct.Exprs, expr)
+	}
+	if ct.Next(time.Now()).IsZero() {
+		return CronTrigger{}, fmt.Errorf("%q never fires", ct.Spec())
+	}
+	return ct, nil
+}
+
+func parseCronExpr(s string) (CronExpr, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return CronExpr{}, fmt.Errorf("cron expression %q needs 5 fields: minute hour day month weekday", strings.TrimSpace(s))
+	}
+	e := CronExpr{spec: strings.ToLower(strings.Join(fields, " ")), anyDom: fields[2] == "*", anyDow: fields[4] == "*"}
+	minutes, err := parseCronField(fields[0], 0, 59, nil)
+	if err != nil {
+		return CronExpr{}, fmt.Errorf("minute: %w", err)
+	}
+	hours, err := parseCronField(fields[1], 0, 23, nil)
+	if err != nil {
+		return CronExpr{}, fmt.Errorf("hour: %w", err)
+	}
+	if e.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return CronExpr{}, fmt.Errorf("day of month: %w", err)
+	}
+	if e.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return CronExpr{}, fmt.Errorf("month: %w", err)
+	}
+	if e.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return CronExpr{}, fmt.Errorf("day of week: %w", err)
+	}
+	if e.dow[7] {
+		e.dow[0] = true // 7 is Sunday too
+	}
+	for m := range 60 {
+		if minutes[m] {
+			e.minute = append(e.minute, m)
+		}
+	}
+	for h := range 24 {
+		if hours[h] {
+			e.hour = append(e.hour, h)
+		}
+	}
+	return e, nil
+}
+
+// parseCronField reads a comma-separated list of "*", "N", "N-M" or names,
+// each optionally with a "/step"
+func parseCronField(s string, lo, hi int, names []string) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, item := range strings.Split(strings.ToLower(s), ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return nil, fmt.Errorf("bad step in %q", item)
+			}
+		}
+
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = cronValue(a, lo, names); err != nil {
+				return nil, err
+			}
+			to = from
+			if isRange {
+				if to, err = cronValue(b, lo, names); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("%q is outside %d-%d", item, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// cronValue reads a number or a three-letter name; names count from lo
+func cronValue(s string, lo int, names []string) (int, error) {
+	for i, name := range names {
+		if s == name {
+			return lo + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return v, nil
+}
+
+// matchesDay follows cron's rule that when both day fields are restricted
+// a day matching either one counts
+func (e CronExpr) matchesDay(y int, m time.Month, d int, wd time.Weekday) bool {
+	if !e.month[int(m)] {
+		return false
+	}
+	switch {
+	case e.anyDom && e.anyDow:
+		return true
+	case e.anyDom:
+		return e.dow[int(wd)]
+	case e.anyDow:
+		return e.dom[d]
+	default:
+		return e.dom[d] || e.dow[int(wd)]
+	}
+}
+
+// Next returns the first fire time strictly after t, or the zero time if
+// none comes within cronSearchYears
+func (ct CronTrigger) Next(t time.Time) time.Time {
+	local := t.In(ct.Location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 12, 0, 0, 0, ct.Location)
+	for i := range cronSearchYears * 366 {
+		day := start.AddDate(0, 0, i)
+		y, m, d := day.Date()
+
+		// fire times within a day can come out of order across a DST change
+		var best time.Time
+		for _, e := range ct.Exprs {
+			if !e.matchesDay(y, m, d, day.Weekday()) {
+				continue
+			}
+			for _, h := range e.hour {
+				for _, min := range e.minute {
+					at := time.Date(y, m, d, h, min, 0, 0, ct.Location)
+					if at.After(t) && (best.IsZero() || at.Before(best)) {
+						best = at
+					}
+				}
+			}
+		}
+		if !best.IsZero() {
+			return best
+		}
+	}
+	return time.Time{}
+}
+
+// Spec returns the normalized expressions, "; "-separated, as stored in
+// time_hhmm
+func (ct CronTrigger) Spec() string {
+	specs := make([]string, len(ct.Exprs))
+	for i, e := range ct.Exprs {
+		specs[i] = e.spec
+	}
+	return strings.Join(specs, "; ")
+}
+
+// Zone returns the normalized zone name
+func (ct CronTrigger) Zone() string {
+	return ct.Location.String()
+}
+
+// Loc returns the zone the expressions are read in
+func (ct CronTrigger) Loc() *time.Location {
+	return ct.Location
+}
+
+// String returns the expressions followed by the zone
+func (ct CronTrigger) String() string {
+	return ct.Spec() + " " + ct.Zone()
+}
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // zone data for hosts without /usr/share/zoneinfo
+)
+
+// DailyTime is a wall-clock time of day in a particular zone
+type DailyTime struct {
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+var (
+	clockRe  = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	offsetRe = regexp.MustCompile(`^(?i:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+)
+
+// ParseDailyTime reads "HH:MM" optionally followed by an IANA zone
+// ("Europe/Berlin") or a UTC offset ("+02:00", "UTC-5", "GMT+5:30").
+// A missing zone means UTC.
+func ParseDailyTime(s string) (DailyTime, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return DailyTime{}, fmt.Errorf("expected a time like \"08:00 Europe/Berlin\"")
+	}
+	m := clockRe.FindStringSubmatch(fields[0])
+	if m == nil {
+		return DailyTime{}, fmt.Errorf("%q is not a time in HH:MM form", fields[0])
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	if hour > 23 || minute > 59 {
+		return DailyTime{}, fmt.Errorf("%q is not a valid time of day", fields[0])
+	}
+
+	loc := time.UTC
+	if len(fields) == 2 {
+		var err error
+		if loc, err = LoadZone(fields[1]); err != nil {
+			return DailyTime{}, err
+		}
+	}
+	return DailyTime{Hour: hour, Minute: minute, Location: loc}, nil
+}
+
+// LoadZone resolves an IANA zone name or a UTC offset. Offsets come back
+// as fixed zones named in the normalized "UTC+02:00" form.
+func LoadZone(name string) (*time.Location, error) {
+	switch strings.ToUpper(name) {
+	case "", "UTC", "GMT", "Z":
+		return time.UTC, nil
+	}
+	if m := offsetRe.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("%q is not a valid UTC offset", name)
+		}
+		secs := hours*3600 + minutes*60
+		if m[1] == "-" {
+			secs = -secs
+		}
+		if secs == 0 {
+			return time.UTC, nil
+		}
+		return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", m[1], hours, minutes), secs), nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q (use a name like Europe/Berlin or an offset like +02:00)", name)
+	}
+	return loc, nil
+}
+
+// Clock returns the normalized "HH:MM"
+func (d DailyTime) Clock() string {
+	return fmt.Sprintf("%02d:%02d", d.Hour, d.Minute)
+}
+
+// Spec returns the normalized "HH:MM", as stored in time_hhmm
+func (d DailyTime) Spec() string {
+	return d.Clock()
+}
+
+// Loc returns the zone the time is in
+func (d DailyTime) Loc() *time.Location {
+	return d.Location
+}
+
+// Zone returns the normalized zone name
+func (d DailyTime) Zone() string {
+	return d.Location.String()
+}
+
+// String returns the time followed by the zone
+func (d DailyTime) String() string {
+	return d.Clock() + " " + d.Zone()
+}
+
+// On returns the instant the time falls on the given calendar day. If
+// clocks spring forward over it, Go's normalization moves it later by the
+// size of the gap; if they fall back over it, it is whichever of the two
+// matching instants time.Date picks. Either way there is exactly one per day.
+func (d DailyTime) On(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, d.Hour, d.Minute, 0, 0, d.Location)
+}
+
+// Next returns the first occurrence strictly after t
+func (d DailyTime) Next(t time.Time) time.Time {
+	local := t.In(d.Location)
+	y, m, day := local.Date()
+	next := d.On(y, m, day)
+	for !next.After(t) {
+		day++
+		next = d.On(y, m, day)
+	}
+	return next
+}
+
+// NextN returns the next n occurrences after t
+func (d DailyTime) NextN(t time.Time, n int) []time.Time {
+	return NextN(d, t, n)
+}
+
+// DayNumber counts calendar days since 1970-01-01 for the date t shows in
+// its own location, so callers pick the guild's "day" with t.In(loc)
+func DayNumber(t time.Time) int64 {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
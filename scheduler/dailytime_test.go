@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/novnod/barista-bot/repo"
+)
+
+func TestParseDailyTime(t *testing.T) {
+	cases := map[string]string{
+		"08:00":                "08:00 UTC",
+		"8:05 Europe/Berlin":   "08:05 Europe/Berlin",
+		"20:30 +02:00":         "20:30 UTC+02:00",
+		"20:30 UTC-5":          "20:30 UTC-05:00",
+		"07:15 GMT+0530":       "07:15 UTC+05:30",
+		"23:59 America/Denver": "23:59 America/Denver",
+		"12:00 utc":            "12:00 UTC",
+	}
+	for in, want := range cases {
+		dt, err := ParseDailyTime(in)
+		if err != nil {
+			t.Errorf("ParseDailyTime(%q) returned error: %v", in, err)
+			continue
+		}
+		if dt.String() != want {
+			t.Errorf("ParseDailyTime(%q) = %q, want %q", in, dt, want)
+		}
+	}
+
+	for _, in := range []string{"", "8am", "24:00", "12:60", "12:00 Mars/Base", "12:00 +15:00", "12:00 UTC extra", "12:00 Local"} {
+		if _, err := ParseDailyTime(in); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestNormalizedZoneRoundTrips(t *testing.T) {
+	dt, err := ParseDailyTime("09:00 -03:30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := ConfigTrigger(repo.DailyConfig{TimeHHMM: dt.Clock(), Timezone: dt.Zone()})
+	if err != nil {
+		t.Fatalf("stored zone %q didn't parse: %v", dt.Zone(), err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !again.Next(now).Equal(dt.Next(now)) {
+		t.Errorf("expected same fire time after round trip, got %v and %v", again.Next(now), dt.Next(now))
+	}
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	// clocks go forward 02:00 → 03:00 on 2025-03-30
+	dt := DailyTime{Hour: 8, Minute: 0, Location: berlin}
+	times := dt.NextN(time.Date(2025, 3, 28, 12, 0, 0, 0, berlin), 3)
+	for i, want := range []string{"2025-03-29 08:00 CET", "2025-03-30 08:00 CEST", "2025-03-31 08:00 CEST"} {
+		if got := times[i].Format("2006-01-02 15:04 MST"); got != want {
+			t.Errorf("fire %d = %s, want %s", i, got, want)
+		}
+	}
+	if gap := times[2].Sub(times[1]); gap != 24*time.Hour {
+		t.Errorf("expected 24h between summer fires, got %v", gap)
+	}
+	if gap := times[1].Sub(times[0]); gap != 23*time.Hour {
+		t.Errorf("expected 23h across spring forward, got %v", gap)
+	}
+
+	// a time inside the skipped hour still fires once that day
+	gap := DailyTime{Hour: 2, Minute: 30, Location: berlin}
+	times = gap.NextN(time.Date(2025, 3, 29, 12, 0, 0, 0, berlin), 2)
+	if times[0].Day() != 30 || times[1].Day() != 31 {
+		t.Errorf("expected one fire on each of the 30th and 31st, got %v", times)
+	}
+
+	// clocks go back 03:00 → 02:00 on 2025-10-26; 02:30 happens twice but fires once
+	times = gap.NextN(time.Date(2025, 10, 25, 12, 0, 0, 0, berlin), 3)
+	for i, day := range []int{26, 27, 28} {
+		if times[i].Day() != day {
+			t.Errorf("fire %d on day %d, want %d (%v)", i, times[i].Day(), day, times)
+		}
+	}
+}
+
+func TestDayNumberUsesLocalDate(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	utc := time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC) // already 2 June in Tokyo
+	if DayNumber(utc.In(tokyo)) != DayNumber(utc)+1 {
+		t.Errorf("expected Tokyo to be a day ahead at 20:00 UTC")
+	}
+	if DayNumber(time.Date(1970, 1, 2, 23, 0, 0, 0, tokyo)) != 1 {
+		t.Errorf("expected 1970-01-02 to be day 1")
+	}
+}
@@ -0,0 +1,249 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/novnod/barista-bot/repo"
+)
+
+// Clock abstracts time so the scheduler can be driven by tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RealClock returns a Clock backed by the system time
+func RealClock() Clock {
+	return realClock{}
+}
+
+// ConfigSource lists every guild's daily schedules
+type ConfigSource interface {
+	ListConfigs() ([]repo.DailyConfig, error)
+}
+
+// PostFunc posts the daily problem for one of a guild's schedules; at is
+// the scheduled time in the schedule's zone
+type PostFunc func(cfg repo.DailyConfig, at time.Time) error
+
+// JobStore holds the scheduled jobs
+type JobStore interface {
+	Schedule(job repo.Job) error
+	CancelFuture(guildID, kind, prefix, keep string, t time.Time) error
+	Due(now time.Time) ([]repo.Job, error)
+	Claim(id int64) (bool, error)
+	Finish(id int64) error
+	Skip(id int64, reason string) error
+	Fail(id int64, err error, retryAt time.Time) error
+	CatchUpPolicy(guildID string) (string, error)
+}
+
+// SkipError is returned by a PostFunc or JobFunc that deliberately didn't
+// run, such as a post on a paused day; the job is marked skipped, not failed
+type SkipError struct {
+	Reason string
+}
+
+func (e *SkipError) Error() string {
+	return e.Reason
+}
+
+// JobFunc runs a reveal, reminder or other non-post job
+type JobFunc func(job repo.Job) error
+
+const (
+	// MissedAfter is how late a job can start before it counts as missed,
+	// which is when the guild's catch-up policy decides whether it runs
+	MissedAfter = 10 * time.Minute
+	// MaxAttempts is how many times a failing job is tried
+	MaxAttempts = 3
+	// retryDelay is multiplied by the attempt number between retries
+	retryDelay = 5 * time.Minute
+)
+
+// Scheduler queues each schedule's daily as a job ahead of its configured
+// time, then runs jobs as they come due: posts, and any kinds registered
+// with Handle. Jobs live in the database, so ones missed while the bot was
+// down are found on the next tick. Configs are re-read on every tick, so
+// edits take effect without a restart.
+type Scheduler struct {
+	source   ConfigSource
+	jobs     JobStore
+	post     PostFunc
+	handlers map[string]JobFunc
+	clock    Clock
+	interval time.Duration
+
+	last    time.Time
+	configs map[scheduleKey]repo.DailyConfig // as of the last tick
+}
+
+// scheduleKey identifies one of a guild's schedules
+type scheduleKey struct {
+	guildID, name string
+}
+
+// New returns a Scheduler that checks for due jobs every minute
+func New(source ConfigSource, jobs JobStore, post PostFunc, clock Clock) *Scheduler {
+	return &Scheduler{
+		source:   source,
+		jobs:     jobs,
+		post:     post,
+		handlers: map[string]JobFunc{},
+		clock:    clock,
+		interval: time.Minute,
+		last:     clock.Now(),
+		configs:  map[scheduleKey]repo.DailyConfig{},
+	}
+}
+
+// Handle registers the function that runs jobs of the given kind
+func (s *Scheduler) Handle(kind string, fn JobFunc) *Scheduler {
+	s.handlers[kind] = fn
+	return s
+}
+
+// Run ticks straight away, to catch up on jobs missed while the bot was
+// down, then every interval until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	s.Tick()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(s.interval):
+			s.Tick()
+		}
+	}
+}
+
+// Tick queues each schedule's next daily and runs every job that is due.
+// Each schedule gets at most one post per day, even if its time is moved
+// later.
+func (s *Scheduler) Tick() {
+	now := s.clock.Now()
+	since := s.last
+	s.last = now
+	s.queuePosts(since, now)
+	s.runDue(now)
+}
+
+// queuePosts schedules each schedule's first post after since, replacing
+// any untried post left over from an earlier time setting
+func (s *Scheduler) queuePosts(since, now time.Time) {
+	configs, err := s.source.ListConfigs()
+	if err != nil {
+		log.Printf("scheduler: failed to list configs: %v", err)
+		return
+	}
+
+	s.configs = map[scheduleKey]repo.DailyConfig{}
+	for _, cfg := range configs {
+		s.configs[scheduleKey{cfg.GuildID, cfg.Name}] = cfg
+		tr, err := ConfigTrigger(cfg)
+		if err != nil {
+			log.Printf("scheduler: schedule %s of guild %s has an invalid time %q: %v", cfg.Name, cfg.GuildID, cfg.TimeHHMM, err)
+			continue
+		}
+		at := tr.Next(since)
+		if at.IsZero() {
+			continue
+		}
+		prefix := cfg.Name + "/"
+		key := prefix + at.Format(time.DateOnly)
+		if err := s.jobs.CancelFuture(cfg.GuildID, repo.JobPost, prefix, key, now); err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+		job := repo.Job{GuildID: cfg.GuildID, Kind: repo.JobPost, Key: key, Payload: cfg.Name, DueAt: at}
+		if err := s.jobs.Schedule(job); err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+	}
+}
+
+// runDue runs the jobs due by now, applying the guild's catch-up policy to
+// missed ones and scheduling retries for failures
+func (s *Scheduler) runDue(now time.Time) {
+	due, err := s.jobs.Due(now)
+	if err != nil {
+		log.Printf("scheduler: failed to list jobs: %v", err)
+		return
+	}
+
+	for _, job := range due {
+		if ok, err := s.jobs.Claim(job.ID); err != nil || !ok {
+			// already being run elsewhere, such as a reveal staff asked for
+			if err != nil {
+				log.Printf("scheduler: %v", err)
+			}
+			continue
+		}
+		if job.Attempts == 0 && now.Sub(job.DueAt) > MissedAfter {
+			policy, err := s.jobs.CatchUpPolicy(job.GuildID)
+			if err != nil {
+				log.Printf("scheduler: %v", err)
+			}
+			if policy == repo.CatchUpSkip {
+				log.Printf("scheduler: skipping missed %s job %s for guild %s", job.Kind, job.Key, job.GuildID)
+				if err := s.jobs.Skip(job.ID, "missed while the bot was offline"); err != nil {
+					log.Printf("scheduler: %v", err)
+				}
+				continue
+			}
+			log.Printf("scheduler: running missed %s job %s for guild %s late", job.Kind, job.Key, job.GuildID)
+		}
+
+		err := s.run(job)
+		var skip *SkipError
+		if errors.As(err, &skip) {
+			if err := s.jobs.Skip(job.ID, skip.Reason); err != nil {
+				log.Printf("scheduler: %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("scheduler: %s job %s for guild %s failed: %v", job.Kind, job.Key, job.GuildID, err)
+			var retryAt time.Time
+			if job.Attempts+1 < MaxAttempts {
+				retryAt = now.Add(time.Duration(job.Attempts+1) * retryDelay)
+			}
+			if err := s.jobs.Fail(job.ID, err, retryAt); err != nil {
+				log.Printf("scheduler: %v", err)
+			}
+			continue
+		}
+		if err := s.jobs.Finish(job.ID); err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+	}
+}
+
+// run dispatches a job to the post function or its kind's handler
+func (s *Scheduler) run(job repo.Job) error {
+	if job.Kind != repo.JobPost {
+		fn, ok := s.handlers[job.Kind]
+		if !ok {
+			return fmt.Errorf("no handler for %s jobs", job.Kind)
+		}
+		return fn(job)
+	}
+
+	cfg, ok := s.configs[scheduleKey{job.GuildID, job.Payload}]
+	if !ok {
+		return fmt.Errorf("guild has no schedule named %q", job.Payload)
+	}
+	tr, err := ConfigTrigger(cfg)
+	if err != nil {
+		return err
+	}
+	return s.post(cfg, job.DueAt.In(tr.Loc()))
+}
@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/novnod/barista-bot/repo"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time                         { return c.now }
+func (c *fakeClock) After(d time.Duration) <-chan time.Time { return make(chan time.Time) }
+func (c *fakeClock) advance(d time.Duration)                { c.now = c.now.Add(d) }
+
+type fakeSource struct {
+	configs []repo.DailyConfig
+}
+
+func (f *fakeSource) ListConfigs() ([]repo.DailyConfig, error) {
+	return f.configs, nil
+}
+
+type recorder struct {
+	posts []string
+}
+
+func (r *recorder) post(cfg repo.DailyConfig, at time.Time) error {
+	post := cfg.GuildID
+	if cfg.Name != "" {
+		post += "/" + cfg.Name
+	}
+	r.posts = append(r.posts, post+"@"+at.Format("2006-01-02 15:04"))
+	return nil
+}
+
+func newTestScheduler(t *testing.T, start string, configs ...repo.DailyConfig) (*Scheduler, *fakeClock, *fakeSource, *recorder) {
+	t.Helper()
+	db, err := repo.InitDBConnection(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	now, _ := time.Parse("2006-01-02 15:04", start)
+	clock := &fakeClock{now: now}
+	source := &fakeSource{configs: configs}
+	rec := &recorder{}
+	return New(source, repo.InitJobRepository(db), rec.post, clock), clock, source, rec
+}
+
+func TestTickPostsAtConfiguredTime(t *testing.T) {
+	s, clock, _, rec := newTestScheduler(t, "2025-03-01 07:58",
+		repo.DailyConfig{GuildID: "g1", ChannelID: "c1", TimeHHMM: "08:00"},
+		repo.DailyConfig{GuildID: "g2", ChannelID: "c2", TimeHHMM: "09:30"},
+	)
+
+	clock.advance(time.Minute)
+	s.Tick()
+	if len(rec.posts) != 0 {
+		t.Fatalf("expected no posts before 08:00, got %v", rec.posts)
+	}
+
+	clock.advance(time.Minute)
+	s.Tick()
+	if len(rec.posts) != 1 || rec.posts[0] != "g1@2025-03-01 08:00" {
+		t.Fatalf("expected g1 to post at 08:00, got %v", rec.posts)
+	}
+
+	// a late tick still catches 09:30, and nothing posts twice
+	clock.advance(2 * time.Hour)
+	s.Tick()
+	s.Tick()
+	if len(rec.posts) != 2 || rec.posts[1] != "g2@2025-03-01 09:30" {
+		t.Fatalf("expected g2 to post at 09:30, got %v", rec.posts)
+	}
+}
+
+func TestTickSkipsTimesBeforeStart(t *testing.T) {
+	s, clock, _, rec := newTestScheduler(t, "2025-03-01 12:00",
+		repo.DailyConfig{GuildID: "g1", TimeHHMM: "08:00"},
+	)
+	clock.advance(time.Minute)
+	s.Tick()
+	if len(rec.posts) != 0 {
+		t.Fatalf("expected no post for a time before the scheduler started, got %v", rec.posts)
+	}
+
+	clock.advance(20 * time.Hour)
+	s.Tick()
+	if len(rec.posts) != 1 || rec.posts[0] != "g1@2025-03-02 08:00" {
+		t.Fatalf("expected post the next morning, got %v", rec.posts)
+	}
+}
+
+func TestTickPicksUpConfigChanges(t *testing.T) {
+	s, clock, source, rec := newTestScheduler(t, "2025-03-01 07:00",
+		repo.DailyConfig{GuildID: "g1", TimeHHMM: "10:00"},
+	)
+	clock.advance(time.Minute)
+	s.Tick()
+
+	// staff move the daily earlier without a restart
+	source.configs[0].TimeHHMM = "07:30"
+	clock.advance(30 * time.Minute)
+	s.Tick()
+	if len(rec.posts) != 1 || rec.posts[0] != "g1@2025-03-01 07:30" {
+		t.Fatalf("expected post at the new time, got %v", rec.posts)
+	}
+
+	// moving it later the same day doesn't post a second daily
+	source.configs[0].TimeHHMM = "08:00"
+	clock.advance(time.Hour)
+	s.Tick()
+	if len(rec.posts) != 1 {
+		t.Fatalf("expected one post per day, got %v", rec.posts)
+	}
+}
+
+func TestTickRunsMissedJobsPerPolicy(t *testing.T) {
+	s, clock, _, rec := newTestScheduler(t, "2025-03-02 12:00",
+		repo.DailyConfig{GuildID: "g1", Name: "default", TimeHHMM: "08:00"},
+		repo.DailyConfig{GuildID: "g2", Name: "default", TimeHHMM: "08:00"},
+	)
+	jobs := s.jobs.(*repo.JobRepository)
+
+	// both posts were queued yesterday, before the bot went down
+	for _, g := range []string{"g1", "g2"} {
+		due := time.Date(2025, 3, 2, 8, 0, 0, 0, time.UTC)
+		job := repo.Job{GuildID: g, Kind: repo.JobPost, Key: "default/2025-03-02", Payload: "default", DueAt: due}
+		if err := jobs.Schedule(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := jobs.SetCatchUpPolicy("g2", repo.CatchUpSkip); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.advance(time.Minute)
+	s.Tick()
+	if len(rec.posts) != 1 || rec.posts[0] != "g1/default@2025-03-02 08:00" {
+		t.Fatalf("expected only g1 to catch up, got %v", rec.posts)
+	}
+	recent, _ := jobs.Recent("g2", 10)
+	skipped := false
+	for _, j := range recent {
+		skipped = skipped || (j.Key == "default/2025-03-02" && j.Status == repo.JobSkipped)
+	}
+	if !skipped {
+		t.Errorf("expected g2's missed post to be skipped, got %+v", recent)
+	}
+}
+
+func TestTickRetriesFailedJobs(t *testing.T) {
+	s, clock, _, _ := newTestScheduler(t, "2025-03-01 08:00")
+	jobs := s.jobs.(*repo.JobRepository)
+	if err := jobs.Schedule(repo.Job{GuildID: "g1", Kind: repo.JobReveal, Key: "t1", DueAt: clock.now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	s.Handle(repo.JobReveal, func(job repo.Job) error {
+		calls++
+		return errors.New("discord is down")
+	})
+
+	clock.advance(59 * time.Minute)
+	s.Tick()
+	if calls != 0 {
+		t.Fatalf("expected no reveal before it is due, got %d calls", calls)
+	}
+	for range 30 {
+		clock.advance(time.Minute)
+		s.Tick()
+	}
+	if calls != MaxAttempts {
+		t.Errorf("expected %d attempts, got %d", MaxAttempts, calls)
+	}
+	recent, _ := jobs.Recent("g1", 1)
+	if len(recent) != 1 || recent[0].Status != repo.JobFailed || recent[0].LastError != "discord is down" {
+		t.Errorf("expected the failure to be recorded, got %+v", recent)
+	}
+}
+
+func TestTickPostsEachScheduleOfAGuild(t *testing.T) {
+	s, clock, _, rec := newTestScheduler(t, "2025-03-01 07:59",
+		repo.DailyConfig{GuildID: "g1", Name: "beginner", TimeHHMM: "08:00"},
+		repo.DailyConfig{GuildID: "g1", Name: "advanced", TimeHHMM: "20:00"},
+	)
+	for range 13 * 60 {
+		clock.advance(time.Minute)
+		s.Tick()
+	}
+	want := []string{"g1/beginner@2025-03-01 08:00", "g1/advanced@2025-03-01 20:00"}
+	if len(rec.posts) != 2 || rec.posts[0] != want[0] || rec.posts[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, rec.posts)
+	}
+}
+
+func TestTickMarksSkippedPosts(t *testing.T) {
+	s, clock, _, _ := newTestScheduler(t, "2025-03-01 07:59",
+		repo.DailyConfig{GuildID: "g1", Name: "default", TimeHHMM: "08:00"},
+	)
+	s.post = func(cfg repo.DailyConfig, at time.Time) error {
+		return &SkipError{Reason: "dailies are paused"}
+	}
+	clock.advance(time.Minute)
+	s.Tick()
+
+	recent, _ := s.jobs.(*repo.JobRepository).Recent("g1", 10)
+	skipped := false
+	for _, j := range recent {
+		skipped = skipped || (j.Key == "default/2025-03-01" && j.Status == repo.JobSkipped && j.LastError == "dailies are paused")
+	}
+	if !skipped {
+		t.Errorf("expected the post to be skipped with its reason, got %+v", recent)
+	}
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/novnod/barista-bot/repo"
+)
+
+// Trigger decides when a schedule fires: a DailyTime, a CronTrigger or an
+// IntervalTrigger
+type Trigger interface {
+	// Next returns the first fire time strictly after t, or the zero time
+	// if there is none
+	Next(t time.Time) time.Time
+	// Spec is the normalized trigger without its zone, as stored in time_hhmm
+	Spec() string
+	// Zone is the normalized zone name, as stored in timezone
+	Zone() string
+	// Loc is the zone fire times are in
+	Loc() *time.Location
+	// String is the spec followed by the zone, as shown to staff
+	String() string
+}
+
+var legacyRe = regexp.MustCompile(`^\d{1,2}:\d{2} \S+$`)
+
+// ParseTrigger reads a schedule's trigger, optionally followed by a zone
+// as in ParseDailyTime:
+//
+//	08:00 Europe/Berlin                      every day
+//	30 7 * * 1-5; 0 10 * * sat Europe/Berlin  cron expressions, ";"-separated
+//	@every 2d 08:00 Europe/Berlin            every other day
+//
+// A schedule still posts at most once a day, at the first fire time.
+func ParseTrigger(s string) (Trigger, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "@every"):
+		return ParseInterval(s)
+	case len(strings.Fields(s)) > 2 || strings.Contains(s, ";"):
+		return ParseCron(s)
+	default:
+		return ParseDailyTime(s)
+	}
+}
+
+// ConfigTrigger reads a schedule's stored trigger and zone. Configs saved
+// before zones were stored separately keep "HH:MM Zone" in time_hhmm.
+func ConfigTrigger(cfg repo.DailyConfig) (Trigger, error) {
+	spec := strings.TrimSpace(cfg.TimeHHMM)
+	if legacyRe.MatchString(spec) {
+		return ParseTrigger(spec)
+	}
+	return ParseTrigger(spec + " " + cfg.Timezone)
+}
+
+// NextN returns the next n days' posting times after t, fewer if the
+// trigger stops. A schedule posts once a day, so only the first fire time
+// of each day counts.
+func NextN(tr Trigger, t time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for range n {
+		t = tr.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+
+		// skip to the last instant of the day
+		y, m, d := t.In(tr.Loc()).Date()
+		t = time.Date(y, m, d+1, 0, 0, 0, 0, tr.Loc()).Add(-time.Nanosecond)
+	}
+	return times
+}
+
+// IntervalTrigger fires at a time of day every Days days, counted from
+// 1970-01-01 so the rhythm doesn't depend on when it was set up
+type IntervalTrigger struct {
+	Days int
+	DailyTime
+}
+
+var intervalRe = regexp.MustCompile(`^@every\s+(\d+)d\s+(.+)$`)
+
+// ParseInterval reads "@every Nd HH:MM [zone]"
+func ParseInterval(s string) (IntervalTrigger, error) {
+	m := intervalRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return IntervalTrigger{}, fmt.Errorf("expected an interval like \"@every 2d 08:00\"")
+	}
+	var days int
+	fmt.Sscan(m[1], &days)
+	if days < 1 || days > 365 {
+		return IntervalTrigger{}, fmt.Errorf("interval must be between 1 and 365 days")
+	}
+	dt, err := ParseDailyTime(m[2])
+	if err != nil {
+		return IntervalTrigger{}, err
+	}
+	return IntervalTrigger{Days: days, DailyTime: dt}, nil
+}
+
+// Next returns the first occurrence strictly after t on a day in the rhythm
+func (it IntervalTrigger) Next(t time.Time) time.Time {
+	next := it.DailyTime.Next(t)
+	for DayNumber(next.In(it.Location))%int64(it.Days) != 0 {
+		next = it.DailyTime.Next(next)
+	}
+	return next
+}
+
+// Spec returns the normalized "@every Nd HH:MM", as stored in time_hhmm
+func (it IntervalTrigger) Spec() string {
+	return fmt.Sprintf("@every %dd %s", it.Days, it.Clock())
+}
+
+// String returns the interval and time followed by the zone; Zone and Loc
+// come from the DailyTime
+func (it IntervalTrigger) String() string {
+	return it.Spec() + " " + it.Zone()
+}
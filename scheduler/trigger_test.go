@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/novnod/barista-bot/repo"
+)
+
+func TestParseTrigger(t *testing.T) {
+	cases := map[string]string{
+		"08:00 Europe/Berlin":                  "08:00 Europe/Berlin",
+		"30 7 * * MON-FRI;0 10 * * sat +02:00": "30 7 * * mon-fri; 0 10 * * sat UTC+02:00",
+		"*/15 9-17 1,15 * *":                   "*/15 9-17 1,15 * * UTC",
+		"@every 2d 8:00 Asia/Tokyo":            "@every 2d 08:00 Asia/Tokyo",
+	}
+	for in, want := range cases {
+		tr, err := ParseTrigger(in)
+		if err != nil {
+			t.Errorf("ParseTrigger(%q) returned error: %v", in, err)
+			continue
+		}
+		if tr.String() != want {
+			t.Errorf("ParseTrigger(%q) = %q, want %q", in, tr, want)
+		}
+	}
+	for _, in := range []string{"60 7 * * *", "0 7 * *", "0 7 * * 1-9", "0 7 31 2 *", "0 7 * * 1 Mars/Base", "@every 0d 08:00", "0 7 * * */0"} {
+		if _, err := ParseTrigger(in); err == nil {
+			t.Errorf("expected ParseTrigger(%q) to fail", in)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tr, err := ParseTrigger("30 7 * * 1-5; 0 10 * * 6 Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Thursday 2025-06-05
+	times := NextN(tr, time.Date(2025, 6, 5, 7, 30, 0, 0, berlin), 5)
+	want := []string{"Fri 06-06 07:30", "Sat 06-07 10:00", "Mon 06-09 07:30", "Tue 06-10 07:30", "Wed 06-11 07:30"}
+	for i, w := range want {
+		if got := times[i].In(berlin).Format("Mon 01-02 15:04"); got != w {
+			t.Errorf("fire %d = %s, want %s", i, got, w)
+		}
+	}
+
+	// with both day fields restricted either one matches
+	tr, _ = ParseTrigger("0 12 1 * sun")
+	times = NextN(tr, time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), 3)
+	for i, day := range []int{8, 15, 22} {
+		if times[i].Day() != day {
+			t.Errorf("fire %d on day %d, want %d", i, times[i].Day(), day)
+		}
+	}
+	if next := tr.Next(time.Date(2025, 6, 29, 13, 0, 0, 0, time.UTC)); next.Day() != 1 || next.Month() != time.July {
+		t.Errorf("expected 1 July, got %v", next)
+	}
+
+	// a leap day schedule looks ahead far enough
+	tr, _ = ParseTrigger("0 0 29 2 *")
+	if next := tr.Next(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)); next.Year() != 2028 {
+		t.Errorf("expected 2028-02-29, got %v", next)
+	}
+}
+
+func TestCronAcrossDST(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tr, _ := ParseTrigger("30 2 * * * Europe/Berlin")
+
+	// 02:30 doesn't exist on 2025-03-30 but still fires once that day
+	times := NextN(tr, time.Date(2025, 3, 29, 12, 0, 0, 0, berlin), 2)
+	if times[0].Day() != 30 || times[1].Day() != 31 {
+		t.Errorf("expected one fire on each of the 30th and 31st, got %v", times)
+	}
+}
+
+func TestIntervalEveryOtherDay(t *testing.T) {
+	tr, err := ParseTrigger("@every 2d 08:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// spans a month end, where "*/2" in cron would fire on the 31st and the 1st
+	times := NextN(tr, time.Date(2025, 7, 29, 12, 0, 0, 0, time.UTC), 4)
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap != 48*time.Hour {
+			t.Errorf("expected 48h between fires, got %v (%v)", gap, times)
+		}
+	}
+	for _, at := range times {
+		if DayNumber(at)%2 != 0 {
+			t.Errorf("%v is off the rhythm", at)
+		}
+	}
+}
+
+func TestConfigTriggerReadsStoredForms(t *testing.T) {
+	cases := []struct {
+		cfg  repo.DailyConfig
+		want string
+	}{
+		{repo.DailyConfig{TimeHHMM: "08:00 Europe/Berlin"}, "08:00 Europe/Berlin"}, // before zones were stored apart
+		{repo.DailyConfig{TimeHHMM: "08:00", Timezone: "Europe/Berlin"}, "08:00 Europe/Berlin"},
+		{repo.DailyConfig{TimeHHMM: "30 7 * * 1-5", Timezone: "UTC+02:00"}, "30 7 * * 1-5 UTC+02:00"},
+		{repo.DailyConfig{TimeHHMM: "30 7 * * 1-5; 0 10 * * 6", Timezone: "UTC"}, "30 7 * * 1-5; 0 10 * * 6 UTC"},
+		{repo.DailyConfig{TimeHHMM: "@every 3d 21:00", Timezone: "America/New_York"}, "@every 3d 21:00 America/New_York"},
+	}
+	for _, c := range cases {
+		tr, err := ConfigTrigger(c.cfg)
+		if err != nil {
+			t.Errorf("ConfigTrigger(%q, %q) returned error: %v", c.cfg.TimeHHMM, c.cfg.Timezone, err)
+			continue
+		}
+		if tr.String() != c.want {
+			t.Errorf("ConfigTrigger(%q, %q) = %q, want %q", c.cfg.TimeHHMM, c.cfg.Timezone, tr, c.want)
+		}
+	}
+}
+
+func TestNextNCountsDays(t *testing.T) {
+	tr, _ := ParseTrigger("0 9,18 * * *")
+	times := NextN(tr, time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC), 3)
+	want := []string{"06-01 18:00", "06-02 09:00", "06-03 09:00"}
+	for i, w := range want {
+		if got := times[i].Format("01-02 15:04"); got != w {
+			t.Errorf("fire %d = %s, want %s", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/scheduler"
+)
+
+// scheduleNamePattern keeps schedule names short and safe to use in job keys
+var scheduleNamePattern = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+// scheduleCommand describes /schedule, which manages a guild's named daily schedules
+func scheduleCommand(pg *parser.GoParser) *discordgo.ApplicationCommand {
+	minReveal := float64(repo.InheritReveal)
+	nameOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "name",
+		Description: "Schedule name, e.g. beginner",
+		Required:    true,
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        "schedule",
+		Description: "Manage the guild's daily schedules",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "Show every daily schedule",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Add a schedule, or change some of an existing one's settings",
+				Options: []*discordgo.ApplicationCommandOption{
+					nameOption,
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "Text or forum channel to post in (required for a new schedule)",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildForum},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "time",
+						Description: "HH:MM or cron, and time zone, e.g. 20:00 Europe/Berlin (required for a new schedule)",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "collections",
+						Description: fmt.Sprintf("Comma-separated, or \"all\" for the guild's (loaded: %s)", strings.Join(pg.Collections(), ", ")),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "reveal_hours",
+						Description: "Hours until the solution is posted; 0 for never, -1 for the guild setting",
+						MinValue:    &minReveal,
+						MaxValue:    24 * 7,
+					},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "lock", Description: "Lock the thread after the reveal"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Delete a schedule",
+				Options:     []*discordgo.ApplicationCommandOption{nameOption},
+			},
+		},
+	}
+}
+
+// handleSchedule runs a /schedule subcommand for staff
+func handleSchedule(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	if !requireStaff(s, i) {
+		return
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+
+	switch sub.Name {
+	case "list":
+		schedules, err := dailyRepo.ListSchedules(i.GuildID)
+		if err != nil {
+			respondError(s, i, "could not list schedules: "+err.Error())
+			return
+		}
+		if len(schedules) == 0 {
+			respondEphemeral(s, i, "No schedules yet. Add one with /schedule set.")
+			return
+		}
+		var b strings.Builder
+		for _, cfg := range schedules {
+			b.WriteString(formatSchedule(cfg) + "\n")
+		}
+		respondEphemeral(s, i, b.String())
+
+	case "set":
+		name := strings.ToLower(strings.TrimSpace(opts["name"].StringValue()))
+		if !scheduleNamePattern.MatchString(name) {
+			respondEphemeral(s, i, "Schedule names are up to 32 lowercase letters, digits or dashes.")
+			return
+		}
+		cfg, err := dailyRepo.GetSchedule(i.GuildID, name)
+		isNew := err == sql.ErrNoRows
+		if err != nil && !isNew {
+			respondError(s, i, "could not load schedule: "+err.Error())
+			return
+		}
+		if isNew {
+			if opts["channel"] == nil || opts["time"] == nil {
+				respondEphemeral(s, i, "A new schedule needs a channel and a time.")
+				return
+			}
+			cfg = &repo.DailyConfig{GuildID: i.GuildID, Name: name, RevealHours: repo.InheritReveal}
+		}
+
+		if o, ok := opts["channel"]; ok {
+			cfg.ChannelID = o.ChannelValue(s).ID
+		}
+		if o, ok := opts["time"]; ok {
+			tr, err := scheduler.ParseTrigger(o.StringValue())
+			if err != nil {
+				respondEphemeral(s, i, fmt.Sprintf("Couldn't save: %v.", err))
+				return
+			}
+			cfg.TimeHHMM, cfg.Timezone = tr.Spec(), tr.Zone()
+		}
+		if o, ok := opts["collections"]; ok {
+			ids, err := parseCollections(pg, o.StringValue())
+			if err != nil {
+				respondEphemeral(s, i, fmt.Sprintf("Couldn't save: %v.", err))
+				return
+			}
+			cfg.Collections = ids
+		}
+		if o, ok := opts["reveal_hours"]; ok {
+			cfg.RevealHours = int(o.IntValue())
+		}
+		if o, ok := opts["lock"]; ok {
+			cfg.LockThread = o.BoolValue()
+		}
+
+		if err := dailyRepo.SaveSchedule(*cfg); err != nil {
+			respondError(s, i, "could not save schedule: "+err.Error())
+			return
+		}
+		msg := "Saved: " + formatSchedule(*cfg)
+		if tr, err := scheduler.ConfigTrigger(*cfg); err == nil {
+			msg += "\nNext posts:\n" + formatFireTimes(scheduler.NextN(tr, time.Now(), 3))
+		}
+		respondEphemeral(s, i, msg)
+
+	case "remove":
+		name := strings.ToLower(strings.TrimSpace(opts["name"].StringValue()))
+		if err := dailyRepo.DeleteSchedule(i.GuildID, name); err == sql.ErrNoRows {
+			respondEphemeral(s, i, fmt.Sprintf("No schedule named %q.", name))
+			return
+		} else if err != nil {
+			respondError(s, i, "could not remove schedule: "+err.Error())
+			return
+		}
+		if err := jobRepo.CancelPending(i.GuildID, repo.JobPost, name+"/"); err != nil {
+			log.Printf("failed to cancel posts of schedule %s: %v", name, err)
+		}
+		respondEphemeral(s, i, fmt.Sprintf("Removed the %s schedule.", name))
+	}
+}
+
+// parseCollections reads a comma-separated list of loaded collection IDs;
+// "all" gives nil
+func parseCollections(pg *parser.GoParser, value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "all") {
+		return nil, nil
+	}
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		id = strings.TrimSpace(id)
+		if !slices.Contains(pg.Collections(), id) {
+			return nil, fmt.Errorf("unknown collection %q (loaded: %s)", id, strings.Join(pg.Collections(), ", "))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// formatSchedule describes a schedule on one line, e.g.
+// "**advanced** · <#123> at 20:00 Europe/Berlin · cho-hard · reveal after 12h"
+func formatSchedule(cfg repo.DailyConfig) string {
+	when := strings.TrimSpace(cfg.TimeHHMM + " " + cfg.Timezone)
+	if tr, err := scheduler.ConfigTrigger(cfg); err == nil {
+		when = tr.String()
+	}
+	collections := "guild collections"
+	if len(cfg.Collections) > 0 {
+		collections = strings.Join(cfg.Collections, ", ")
+	}
+	var reveal string
+	switch {
+	case cfg.RevealHours == repo.InheritReveal:
+		reveal = "guild reveal setting"
+	case cfg.RevealHours == 0:
+		reveal = "no reveal"
+	default:
+		reveal = fmt.Sprintf("reveal after %dh", cfg.RevealHours)
+		if cfg.LockThread {
+			reveal += ", then lock"
+		}
+	}
+	return fmt.Sprintf("**%s** · <#%s> at %s · %s · %s", cfg.Name, cfg.ChannelID, when, collections, reveal)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/card"
+	"github.com/novnod/barista-bot/daily"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/rating"
+	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/scheduler"
+)
+
+// statsCommand describes /stats
+func statsCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "stats",
+		Description: "Show your stats card, or another member's",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "member", Description: "Whose stats to show"},
+		},
+	}
+}
+
+// handleStats replies with a member's stats card
+func handleStats(s *discordgo.Session, i *discordgo.InteractionCreate, pg *parser.GoParser) {
+	if i.GuildID == "" {
+		respondEphemeral(s, i, "Stats are kept per server; use /stats in one.")
+		return
+	}
+	userID := interactionUserID(i)
+	data := i.ApplicationCommandData()
+	if len(data.Options) > 0 {
+		userID = data.Options[0].UserValue(nil).ID
+	}
+
+	st, sum, err := memberStats(i.GuildID, userID, memberName(i, userID), pg)
+	if err != nil {
+		respondError(s, i, "could not load stats: "+err.Error())
+		return
+	}
+	imgPath, err := card.RenderStats(*st, imageDir)
+	if err != nil {
+		respondError(s, i, "could not draw stats card: "+err.Error())
+		return
+	}
+	file, err := os.Open(imgPath)
+	if err != nil {
+		respondError(s, i, "could not open stats card: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	name := filepath.Base(imgPath)
+	embed := &discordgo.MessageEmbed{
+		Description: fmt.Sprintf("<@%s> · %d of %d problems solved · %d answers", userID, sum.Solved, sum.Problems, sum.Attempts),
+		Color:       repo.DefaultTemplate.Color,
+		Image:       &discordgo.MessageEmbedImage{URL: "attachment://" + name},
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Files:  []*discordgo.File{{Name: name, ContentType: "image/png", Reader: file}},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("failed to send stats: %v", err)
+	}
+}
+
+// memberStats gathers what a member's stats card shows
+func memberStats(guildID, userID, name string, pg *parser.GoParser) (*card.Stats, *repo.AttemptSummary, error) {
+	today := time.Now().In(guildLocation(guildID))
+	user, err := loadRating(guildID, repo.SubjectUser, userID, rating.Default)
+	if err != nil {
+		return nil, nil, err
+	}
+	st := &card.Stats{
+		GuildID:     guildID,
+		UserID:      userID,
+		Name:        name,
+		Rating:      user.Glicko.Rating,
+		Deviation:   user.Glicko.Deviation,
+		Provisional: user.Glicko.Deviation > provisionalDeviation,
+		Activity:    map[string]int{},
+		Today:       today,
+	}
+
+	history, err := ratingRepo.History(guildID, userID, time.Time{})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range history {
+		st.History = append(st.History, p.Rating)
+	}
+
+	streak, err := streakRepo.GetStreak(guildID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	st.Streak = daily.CurrentStreak(*streak, scheduler.DayNumber(today))
+	st.BestStreak, st.Freezes = streak.Best, streak.Freezes
+
+	tallies, err := attemptRepo.Tallies(guildID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	byTier := map[string]*card.TierAccuracy{}
+	for _, tier := range daily.Tiers {
+		st.Tiers = append(st.Tiers, card.TierAccuracy{Tier: tier})
+	}
+	for n := range st.Tiers {
+		byTier[st.Tiers[n].Tier] = &st.Tiers[n]
+	}
+	for _, t := range tallies {
+		prob := pg.Problem(t.ProblemID)
+		if prob == nil || byTier[prob.Difficulty] == nil {
+			continue
+		}
+		byTier[prob.Difficulty].Attempts += t.Attempts
+		byTier[prob.Difficulty].Correct += t.Correct
+	}
+
+	buckets, err := attemptRepo.Activity(guildID, userID, card.HeatmapStart(today))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, b := range buckets {
+		st.Activity[b.Hour.In(today.Location()).Format(time.DateOnly)] += b.Attempts
+	}
+
+	sum, err := attemptRepo.Summary(guildID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return st, sum, nil
+}
+
+// memberName returns the name to show for a member: their server nickname,
+// display name or username, from the interaction's member or resolved data
+func memberName(i *discordgo.InteractionCreate, userID string) string {
+	var member *discordgo.Member
+	var user *discordgo.User
+	if i.Member != nil && i.Member.User.ID == userID {
+		member, user = i.Member, i.Member.User
+	} else if resolved := i.ApplicationCommandData().Resolved; resolved != nil {
+		member, user = resolved.Members[userID], resolved.Users[userID]
+	}
+	switch {
+	case member != nil && member.Nick != "":
+		return member.Nick
+	case user != nil && user.GlobalName != "":
+		return user.GlobalName
+	case user != nil:
+		return user.Username
+	}
+	return "Member " + userID
+}
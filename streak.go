@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/daily"
+	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/scheduler"
+)
+
+// streakCommand describes /streak, which shows a member's daily streak
+func streakCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "streak",
+		Description: "Show your daily streak, or another member's",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "member", Description: "Whose streak to show"},
+		},
+	}
+}
+
+// handleStreak shows a member's current and best streak and their freezes
+func handleStreak(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" {
+		respondEphemeral(s, i, "Streaks are kept per server; use /streak in one.")
+		return
+	}
+	userID := interactionUserID(i)
+	if opts := i.ApplicationCommandData().Options; len(opts) > 0 {
+		userID = opts[0].UserValue(nil).ID
+	}
+	st, err := streakRepo.GetStreak(i.GuildID, userID)
+	if err != nil {
+		respondError(s, i, "could not load streak: "+err.Error())
+		return
+	}
+	every, err := streakRepo.FreezeEvery(i.GuildID)
+	if err != nil {
+		respondError(s, i, "could not load streak: "+err.Error())
+		return
+	}
+
+	today := scheduler.DayNumber(time.Now().In(guildLocation(i.GuildID)))
+	current := daily.CurrentStreak(*st, today)
+	msg := fmt.Sprintf("<@%s>'s streak: %s (best %s)", userID, days(current), days(st.Best))
+	if every > 0 {
+		msg += fmt.Sprintf("\nFreezes: %d of %d banked", st.Freezes, daily.MaxFreezes)
+		if current > 0 && st.Freezes < daily.MaxFreezes {
+			msg += fmt.Sprintf(", next in %s", days(every-current%every))
+		}
+	}
+	respondEphemeral(s, i, msg)
+}
+
+// advanceStreak counts a correct daily answer towards the member's streak,
+// on today's date in the guild's zone. It returns nil if the streak couldn't
+// be updated.
+func advanceStreak(guildID, userID string, at time.Time) *repo.Streak {
+	st, err := streakRepo.GetStreak(guildID, userID)
+	if err != nil {
+		log.Printf("failed to update streak: %v", err)
+		return nil
+	}
+	every, err := streakRepo.FreezeEvery(guildID)
+	if err != nil {
+		log.Printf("failed to update streak: %v", err)
+		return nil
+	}
+	next := daily.AdvanceStreak(*st, scheduler.DayNumber(at.In(guildLocation(guildID))), every)
+	if next != *st {
+		if err := streakRepo.SaveStreak(next); err != nil {
+			log.Printf("failed to update streak: %v", err)
+			return nil
+		}
+	}
+	return &next
+}
+
+// streakLine sums up a streak under an answer's verdict, e.g.
+// "🔥 Streak: 5 days (best 12) · 1 freeze banked"
+func streakLine(st *repo.Streak) string {
+	line := fmt.Sprintf("🔥 Streak: %s (best %s)", days(st.Current), days(st.Best))
+	switch st.Freezes {
+	case 0:
+	case 1:
+		line += " · 1 freeze banked"
+	default:
+		line += fmt.Sprintf(" · %d freezes banked", st.Freezes)
+	}
+	return line
+}
+
+// freezeRule describes a guild's freeze setting
+func freezeRule(every int) string {
+	if every == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("one every %s of streak, up to %d banked", days(every), daily.MaxFreezes)
+}
+
+// days formats a count of days, e.g. "1 day" or "5 days"
+func days(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/novnod/barista-bot/parser"
+	"github.com/novnod/barista-bot/repo"
+	"github.com/novnod/barista-bot/scheduler"
+)
+
+// DMs go out a batch at a time with a pause in between, which keeps a big
+// guild's fan-out clear of Discord's rate limits. Each job sends at most
+// dmPerRun and queues the rest, so other guilds' jobs aren't held up.
+const (
+	dmBatchSize  = 10
+	dmBatchPause = time.Second
+	dmPerRun     = 100
+)
+
+// dmsClosedReason is why a subscription is disabled when Discord won't let
+// the bot DM the member
+const dmsClosedReason = "DMs closed"
+
+// dmPayload is the payload of DM delivery jobs
+type dmPayload struct {
+	Schedule string `json:"schedule"`
+	Day      string `json:"day"` // YYYY-MM-DD in the schedule's zone
+	Problem  string `json:"problem"`
+	Part     int    `json:"part,omitempty"` // how many jobs delivered this daily before
+}
+
+// subscribeCommand describes /subscribe
+func subscribeCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "subscribe",
+		Description: "Get the daily problem delivered to you",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "dm",
+				Description: "Get the daily in your DMs and answer it there",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "schedule", Description: "Schedule to follow (the default if omitted)"},
+				},
+			},
+		},
+	}
+}
+
+// unsubscribeCommand describes /unsubscribe
+func unsubscribeCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "unsubscribe",
+		Description: "Stop getting the daily problem in your DMs",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "schedule", Description: "Only this schedule (all if omitted)"},
+		},
+	}
+}
+
+// handleSubscribe opts the member in to a schedule's DMs, first checking
+// that the bot can DM them at all
+func handleSubscribe(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	schedule := repo.DefaultSchedule
+	if opts := i.ApplicationCommandData().Options[0].Options; len(opts) > 0 {
+		schedule = strings.ToLower(strings.TrimSpace(opts[0].StringValue()))
+	}
+	cfg, err := dailyRepo.GetSchedule(i.GuildID, schedule)
+	if err == sql.ErrNoRows {
+		respondEphemeral(s, i, fmt.Sprintf("No schedule named %q.", schedule))
+		return
+	}
+	if err != nil {
+		respondError(s, i, "could not load schedule: "+err.Error())
+		return
+	}
+
+	userID := interactionUserID(i)
+	greeting := fmt.Sprintf("You're subscribed to the **%s** daily from **%s**. It will arrive here at %s (%s); "+
+		"reply with your move or press Answer. Use /unsubscribe in the server to stop.",
+		schedule, guildName(s, i.GuildID), cfg.TimeHHMM, cfg.Timezone)
+	if err := sendDM(s, userID, greeting); err != nil {
+		if dmsClosed(err) {
+			respondEphemeral(s, i, "I can't DM you. Allow direct messages from this server's members in its privacy settings, then try again.")
+			return
+		}
+		respondError(s, i, "could not DM you: "+err.Error())
+		return
+	}
+	if err := subscriptionRepo.Subscribe(i.GuildID, userID, schedule, time.Now()); err != nil {
+		respondError(s, i, "could not subscribe: "+err.Error())
+		return
+	}
+	respondEphemeral(s, i, fmt.Sprintf("Subscribed! The **%s** daily will be in your DMs from its next post.", schedule))
+}
+
+// handleUnsubscribe removes the member's subscriptions to one schedule or
+// all of them
+func handleUnsubscribe(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	schedule := ""
+	if opts := i.ApplicationCommandData().Options; len(opts) > 0 {
+		schedule = strings.ToLower(strings.TrimSpace(opts[0].StringValue()))
+	}
+	n, err := subscriptionRepo.Unsubscribe(i.GuildID, interactionUserID(i), schedule)
+	if err != nil {
+		respondError(s, i, "could not unsubscribe: "+err.Error())
+		return
+	}
+	switch {
+	case n == 0 && schedule != "":
+		respondEphemeral(s, i, fmt.Sprintf("You aren't subscribed to the **%s** daily.", schedule))
+	case n == 0:
+		respondEphemeral(s, i, "You aren't subscribed to any dailies here.")
+	case schedule != "":
+		respondEphemeral(s, i, fmt.Sprintf("Unsubscribed from the **%s** daily.", schedule))
+	default:
+		respondEphemeral(s, i, "Unsubscribed from all of this server's dailies.")
+	}
+}
+
+// queueDMs queues delivery of a schedule's daily to its subscribers, if it
+// has any
+func queueDMs(cfg repo.DailyConfig, at time.Time, problemID string) {
+	subs, err := subscriptionRepo.Subscribers(cfg.GuildID, cfg.Name)
+	if err != nil {
+		log.Printf("failed to list subscribers: %v", err)
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+	payload := dmPayload{Schedule: cfg.Name, Day: at.Format(time.DateOnly), Problem: problemID}
+	if err := scheduleDMs(cfg.GuildID, payload, time.Now()); err != nil {
+		log.Printf("failed to queue DMs for guild %s: %v", cfg.GuildID, err)
+	}
+}
+
+// scheduleDMs queues one part of a daily's delivery
+func scheduleDMs(guildID string, payload dmPayload, at time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s/%s/%d", payload.Schedule, payload.Day, payload.Part)
+	return jobRepo.Schedule(repo.Job{GuildID: guildID, Kind: repo.JobDM, Key: key, Payload: string(data), DueAt: at})
+}
+
+// deliverDMs sends a daily to the subscribers who haven't had it yet.
+// Members whose DMs are closed have their subscriptions disabled; other
+// failures fail the job, and its retry picks up whoever was missed.
+func deliverDMs(s *discordgo.Session, pg *parser.GoParser) scheduler.JobFunc {
+	return func(job repo.Job) error {
+		var payload dmPayload
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return fmt.Errorf("bad DM payload: %w", err)
+		}
+		prob := pg.Problem(payload.Problem)
+		if prob == nil {
+			return fmt.Errorf("unknown problem %q", payload.Problem)
+		}
+
+		subs, err := subscriptionRepo.Subscribers(job.GuildID, payload.Schedule)
+		if err != nil {
+			return err
+		}
+		delivered, err := subscriptionRepo.Delivered(job.GuildID, payload.Schedule, payload.Day)
+		if err != nil {
+			return err
+		}
+		var pending []string
+		for _, sub := range subs {
+			if !delivered[sub.UserID] {
+				pending = append(pending, sub.UserID)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if len(pending) > dmPerRun {
+			next := payload
+			next.Part++
+			if err := scheduleDMs(job.GuildID, next, time.Now()); err != nil {
+				return err
+			}
+			pending = pending[:dmPerRun]
+		}
+
+		day, err := time.Parse(time.DateOnly, payload.Day)
+		if err != nil {
+			return fmt.Errorf("bad DM day: %w", err)
+		}
+		msg, img := dmMessage(s, job.GuildID, prob, day, payload.Schedule)
+
+		failed := 0
+		for n, userID := range pending {
+			if n > 0 && n%dmBatchSize == 0 {
+				time.Sleep(dmBatchPause)
+			}
+			err := sendDailyDM(s, job.GuildID, userID, payload, prob, msg, img)
+			if dmsClosed(err) {
+				log.Printf("disabling DMs to %s in guild %s: %v", userID, job.GuildID, err)
+				if err := subscriptionRepo.Disable(job.GuildID, userID, dmsClosedReason); err != nil {
+					log.Printf("failed to disable subscription: %v", err)
+				}
+				continue
+			}
+			if err != nil {
+				log.Printf("failed to DM daily to %s: %v", userID, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to DM %d of %d subscribers", failed, len(pending))
+		}
+		return nil
+	}
+}
+
+// dmMessage builds the daily as delivered by DM, with the guild it's from
+// and no staff Reveal button, and returns its board image separately so
+// every subscriber's copy can be sent with its own reader. A nil message
+// means the text board should be sent instead.
+func dmMessage(s *discordgo.Session, guildID string, prob *parser.GoProblem, day time.Time, schedule string) (*discordgo.MessageSend, []byte) {
+	msg, err := dailyMessage(guildID, prob, day, schedule)
+	if err != nil {
+		log.Printf("failed to build DM daily, sending text board instead: %v", err)
+		return nil, nil
+	}
+	img, err := io.ReadAll(msg.Files[0].Reader)
+	if err != nil {
+		log.Printf("failed to read board image, sending text board instead: %v", err)
+		return nil, nil
+	}
+	msg.Content = fmt.Sprintf("Today's daily from **%s**. Reply with your move, or press Answer.", guildName(s, guildID))
+	msg.Components = dailyButtons(prob, false)
+	return msg, img
+}
+
+// sendDailyDM delivers the daily to one subscriber and records it, so
+// answers in the DM are graded
+func sendDailyDM(s *discordgo.Session, guildID, userID string, payload dmPayload, prob *parser.GoProblem, msg *discordgo.MessageSend, img []byte) error {
+	channel, err := s.UserChannelCreate(userID)
+	if err != nil {
+		return err
+	}
+	var messageID string
+	if msg != nil {
+		own := *msg
+		own.Files = []*discordgo.File{{Name: msg.Files[0].Name, ContentType: msg.Files[0].ContentType, Reader: bytes.NewReader(img)}}
+		sent, err := s.ChannelMessageSendComplex(channel.ID, &own)
+		if err == nil {
+			messageID = sent.ID
+		} else if dmsClosed(err) {
+			return err
+		} else {
+			log.Printf("failed to send DM daily, sending text board instead: %v", err)
+		}
+	}
+	if messageID == "" {
+		sent, err := sendTextBoard(s, channel.ID, prob)
+		if err != nil {
+			return err
+		}
+		messageID = sent.ID
+	}
+
+	return subscriptionRepo.RecordDM(repo.DMDaily{
+		MessageID: messageID,
+		ChannelID: channel.ID,
+		GuildID:   guildID,
+		UserID:    userID,
+		Schedule:  payload.Schedule,
+		Day:       payload.Day,
+		ProblemID: prob.ID,
+		PostedAt:  time.Now(),
+	})
+}
+
+// sendDM sends a plain message to a member's DMs
+func sendDM(s *discordgo.Session, userID, content string) error {
+	channel, err := s.UserChannelCreate(userID)
+	if err != nil {
+		return err
+	}
+	_, err = s.ChannelMessageSend(channel.ID, content)
+	return err
+}
+
+// dmsClosed reports whether err is Discord refusing to DM a member, as it
+// does when they've turned off DMs from the server or blocked the bot
+func dmsClosed(err error) bool {
+	var rest *discordgo.RESTError
+	return errors.As(err, &rest) && rest.Message != nil && rest.Message.Code == discordgo.ErrCodeCannotSendMessagesToThisUser
+}
+
+// guildName returns a guild's name from the state cache, or a stand-in
+func guildName(s *discordgo.Session, guildID string) string {
+	if guild, err := s.State.Guild(guildID); err == nil && guild.Name != "" {
+		return guild.Name
+	}
+	return "your server"
+}
@@ -0,0 +1,163 @@
+package worksheet
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/novnod/barista-bot/parser"
+)
+
+// A4 page geometry in millimetres
+const (
+	pageW   = 210.0
+	pageH   = 297.0
+	margin  = 15.0
+	headerH = 12.0
+	footerH = 8.0
+)
+
+// Options controls the worksheet layout
+type Options struct {
+	Title     string
+	PerPage   int  // problems per page, laid out in a grid
+	AnswerKey bool // append answer pages after the problems
+}
+
+// Write lays out problems as numbered, cropped diagrams with to-move captions,
+// PerPage to a page, and writes the multi-page PDF to w. With AnswerKey set,
+// later pages repeat each diagram with its solution line numbered on it.
+func Write(w io.Writer, problems []*parser.GoProblem, opts Options) error {
+	if len(problems) == 0 {
+		return fmt.Errorf("no problems to export")
+	}
+	if opts.PerPage <= 0 {
+		opts.PerPage = 6
+	}
+	cols := 2
+	switch {
+	case opts.PerPage == 1:
+		cols = 1
+	case opts.PerPage > 12:
+		cols = 3
+	}
+	rows := int(math.Ceil(float64(opts.PerPage) / float64(cols)))
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, margin)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetXY(margin, pageH-margin)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(pageW-2*margin, 5, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	l := layout{
+		pdf:   pdf,
+		tr:    tr,
+		cols:  cols,
+		cellW: (pageW - 2*margin) / float64(cols),
+		cellH: (pageH - 2*margin - headerH - footerH) / float64(rows),
+		per:   opts.PerPage,
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = "Go problems"
+	}
+	for i, p := range problems {
+		if i%opts.PerPage == 0 {
+			l.page(title)
+		}
+		img, err := parser.RenderDiagram(p, nil, 24)
+		if err != nil {
+			return fmt.Errorf("problem %s: %w", p.ID, err)
+		}
+		l.cell(i, fmt.Sprintf("%d. %s", i+1, p.Prompt()), p.Details(), img)
+	}
+
+	if opts.AnswerKey {
+		for i, p := range problems {
+			if i%opts.PerPage == 0 {
+				l.page(title + " - answer key")
+			}
+			line := p.CorrectLine()
+			if len(line) == 0 {
+				l.cell(i, fmt.Sprintf("%d. No solution on file", i+1), p.Details(), nil)
+				continue
+			}
+			ov := parser.AnswerOverlay(line, parser.VerdictCorrect)
+			img, err := parser.RenderDiagram(p, &ov, 24)
+			if err != nil {
+				return fmt.Errorf("answer %s: %w", p.ID, err)
+			}
+			l.cell(i, fmt.Sprintf("%d. %s", i+1, describeLine(line)), p.Details(), img)
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+// layout places numbered cells on the current page
+type layout struct {
+	pdf          *fpdf.Fpdf
+	tr           func(string) string
+	cols, per    int
+	cellW, cellH float64
+	images       int
+}
+
+// page starts a new page with a title header
+func (l *layout) page(title string) {
+	l.pdf.AddPage()
+	l.pdf.SetFont("Helvetica", "B", 14)
+	l.pdf.SetXY(margin, margin)
+	l.pdf.CellFormat(pageW-2*margin, headerH-4, l.tr(title), "", 0, "L", false, 0, "")
+}
+
+// cell draws the i-th problem's caption, source line and diagram in its grid slot
+func (l *layout) cell(i int, caption, source string, img image.Image) {
+	slot := i % l.per
+	x := margin + float64(slot%l.cols)*l.cellW
+	y := margin + headerH + float64(slot/l.cols)*l.cellH
+
+	l.pdf.SetXY(x, y)
+	l.pdf.SetFont("Helvetica", "B", 10)
+	l.pdf.CellFormat(l.cellW-4, 5, l.tr(caption), "", 2, "L", false, 0, "")
+	l.pdf.SetFont("Helvetica", "", 7)
+	l.pdf.CellFormat(l.cellW-4, 4, l.tr(source), "", 2, "L", false, 0, "")
+	if img == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		l.pdf.SetError(err)
+		return
+	}
+	l.images++
+	name := fmt.Sprintf("diagram-%d", l.images)
+	l.pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, &buf)
+
+	// fit the diagram in what's left of the slot, keeping its shape
+	boxW, boxH := l.cellW-6, l.cellH-13
+	b := img.Bounds()
+	scale := math.Min(boxW/float64(b.Dx()), boxH/float64(b.Dy()))
+	w, h := float64(b.Dx())*scale, float64(b.Dy())*scale
+	l.pdf.ImageOptions(name, x, y+10, w, h, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+}
+
+// describeLine writes a solution as "B18, A18, A17"
+func describeLine(line []parser.Move) string {
+	names := make([]string, len(line))
+	for i, m := range line {
+		names[i] = parser.CoordName(m.Coord)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,55 @@
+package worksheet
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/novnod/barista-bot/parser"
+)
+
+func loadProblems(t *testing.T) []*parser.GoProblem {
+	t.Helper()
+	pg := parser.GoParser{}
+	if err := pg.LoadProblems(filepath.Join("..", "files", "cho-easy.sgf")); err != nil {
+		t.Fatalf("failed to load problems: %v", err)
+	}
+	return pg.Problems
+}
+
+func TestWriteWorksheet(t *testing.T) {
+	problems := loadProblems(t)[:7]
+	var buf bytes.Buffer
+	if err := Write(&buf, problems, Options{Title: "Club night", PerPage: 6}); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatal("expected PDF output")
+	}
+	if pages := bytes.Count(buf.Bytes(), []byte("/Type /Page\n")); pages != 2 {
+		t.Errorf("expected 2 pages for 7 problems at 6 per page, got %d", pages)
+	}
+}
+
+func TestWriteWorksheetWithAnswerKey(t *testing.T) {
+	pg := parser.GoParser{}
+	solved, err := pg.ParseSGFLine("(;AB[ba][bb]AW[ca][cb]C[problem 1](;B[aa];W[ab];B[ac]C[RIGHT]))")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	problems := append([]*parser.GoProblem{solved}, loadProblems(t)[:3]...)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, problems, Options{PerPage: 4, AnswerKey: true}); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if pages := bytes.Count(buf.Bytes(), []byte("/Type /Page\n")); pages != 2 {
+		t.Errorf("expected a problem page and an answer page, got %d pages", pages)
+	}
+}
+
+func TestWriteWorksheetEmpty(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, nil, Options{}); err == nil {
+		t.Error("expected error for empty worksheet")
+	}
+}